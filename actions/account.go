@@ -0,0 +1,408 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/navionguy/quotewall/mailers"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// how long the emailed links keep working
+const resetTokenTTL = time.Hour
+const emailTokenTTL = 24 * time.Hour
+const inviteTokenTTL = 7 * 24 * time.Hour
+
+// how many sign in events the account page shows
+const loginLogSize = 20
+
+// AccountShow lets the signed in user change his password, email address
+// and digest.
+// This function is mapped to the path GET /account
+func AccountShow(c buffalo.Context) error {
+	return renderAccount(c, 200, validate.NewErrors())
+}
+
+// AccountPassword changes the password.  The current one has to be given
+// so a session left open on a shared screen can't take over the account.
+// This function is mapped to the path POST /account/password
+func AccountPassword(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	if !u.CheckPassword(c.Param("CurrentPassword")) {
+		verrs := validate.NewErrors()
+		verrs.Add("current_password", "Current password is wrong")
+
+		return renderAccount(c, http.StatusUnprocessableEntity, verrs)
+	}
+
+	verrs, err := u.ChangePassword(tx, c.Param("Password"), c.Param("PasswordConfirmation"))
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return renderAccount(c, http.StatusUnprocessableEntity, verrs)
+	}
+
+	// whoever knew the old password gets signed out everywhere else
+	if err := u.RevokeSessions(tx, currentSession(c).ID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logLogin(c, models.LoginPasswordChanged, u, u.Email)
+	c.Flash().Add("success", "Your password was changed")
+
+	return c.Redirect(302, "/account")
+}
+
+// AccountEmail starts an email change.  Nothing changes until the link
+// mailed to the new address gets followed.
+// This function is mapped to the path POST /account/email
+func AccountEmail(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	if !u.CheckPassword(c.Param("CurrentPassword")) {
+		verrs := validate.NewErrors()
+		verrs.Add("current_password", "Current password is wrong")
+
+		return renderAccount(c, http.StatusUnprocessableEntity, verrs)
+	}
+
+	// check the address before mailing anything to it
+	email := strings.ToLower(strings.TrimSpace(c.Param("Email")))
+	verrs, err := u.CheckEmail(tx, email)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return renderAccount(c, http.StatusUnprocessableEntity, verrs)
+	}
+
+	raw, _, err := models.NewUserToken(tx, u, models.TokenEmailChange, emailTokenTTL, email)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	link := fmt.Sprintf("%s/account/email/%s", siteURL(c), raw)
+
+	if err := mailers.SendEmailConfirmation(email, link); err != nil {
+		c.Logger().Errorf("email confirmation to %s: %s", email, err.Error())
+		c.Flash().Add("danger", "The confirmation email couldn't be sent, try again later")
+		return c.Redirect(302, "/account")
+	}
+
+	c.Flash().Add("success", "Check "+email+" for a link to confirm the change")
+
+	return c.Redirect(302, "/account")
+}
+
+// AccountEmailConfirm finishes an email change.  Following the link
+// proves the user can read mail at the new address.
+// This function is mapped to the path GET /account/email/{token}
+func AccountEmailConfirm(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	t := &models.UserToken{}
+
+	if err := t.Redeem(tx, models.TokenEmailChange, c.Param("token"), time.Now()); err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(302, "/account")
+	}
+
+	u := &models.User{}
+
+	if err := tx.Find(u, t.UserID); err != nil {
+		return c.Error(404, err)
+	}
+
+	verrs, err := u.ChangeEmail(tx, t.Email)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(302, "/account")
+	}
+
+	c.Flash().Add("success", "Your email address is now "+u.Email)
+
+	return c.Redirect(302, "/account")
+}
+
+// AccountDigest changes how often the digest comes
+// This function is mapped to the path POST /account/digest
+func AccountDigest(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	var err error
+	if every := c.Param("Digest"); every == models.DigestOff {
+		err = u.UnsubscribeDigest(tx)
+	} else {
+		err = u.SubscribeDigest(tx, every)
+	}
+
+	if err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(302, "/account")
+	}
+
+	c.Flash().Add("success", "Your digest setting was saved")
+
+	return c.Redirect(302, "/account")
+}
+
+// AccountSessionRevoke signs out one of the user's other browsers
+// This function is mapped to the path DELETE /account/sessions/{session_id}
+func AccountSessionRevoke(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	if _, err := uuid.FromString(c.Param("session_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := u.RevokeSession(tx, c.Param("session_id")); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logLogin(c, models.LoginRevoked, u, u.Email)
+	c.Flash().Add("success", "That session was signed out")
+
+	return c.Redirect(302, "/account")
+}
+
+// AccountSessionsRevoke signs out every browser but this one
+// This function is mapped to the path DELETE /account/sessions
+func AccountSessionsRevoke(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	if err := u.RevokeSessions(tx, currentSession(c).ID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logLogin(c, models.LoginRevoked, u, u.Email)
+	c.Flash().Add("success", "Your other sessions were signed out")
+
+	return c.Redirect(302, "/account")
+}
+
+// renderAccount shows the account page with any errors
+func renderAccount(c buffalo.Context, status int, verrs *validate.Errors) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	sessions := models.UserSessions{}
+
+	if err := sessions.ForUser(tx, u.ID, time.Now()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	events := models.LoginEvents{}
+
+	if err := events.ForUser(tx, u.ID, loginLogSize); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", u)
+	c.Set("sessions", sessions)
+	c.Set("thisSession", currentSession(c).ID.String())
+	c.Set("events", events)
+	c.Set("errors", verrs)
+	c.Set("digestChoices", []string{models.DigestOff, models.DigestDaily, models.DigestWeekly})
+	c.Set("digest", digestChoice(u))
+
+	return c.Render(status, r.HTML("account/show.html"))
+}
+
+// digestChoice is what the digest menu should show as picked
+func digestChoice(u *models.User) string {
+	if u.Digest == models.DigestDaily || u.Digest == models.DigestWeekly {
+		return u.Digest
+	}
+	return models.DigestOff
+}
+
+// PasswordForgot asks for the email address to send a reset link to
+// This function is mapped to the path GET /password/forgot
+func PasswordForgot(c buffalo.Context) error {
+	c.Set("user", models.User{})
+	return c.Render(200, r.HTML("password/forgot.html"))
+}
+
+// PasswordForgotCreate mails a reset link.  The answer is the same
+// whether or not the address has an account, so this can't be used to
+// find out who has one.
+// This function is mapped to the path POST /password/forgot
+func PasswordForgotCreate(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	// every request counts, so the form can't be used to flood a mailbox
+	now := time.Now()
+	_, limited, err := models.LockedOut(models.DB, models.ScopeReset, clientIP(c), now)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ip := clientIP(c)
+	afterTransaction(c, func() {
+		if _, err := models.RecordLoginFailure(models.DB, models.ScopeReset, ip, resetLockoutAfter, now); err != nil {
+			c.Logger().Errorf("password reset request from %s: %s", ip, err.Error())
+		}
+	})
+
+	u := &models.User{}
+
+	if err := u.FindByEmail(tx, c.Param("Email")); err == nil && !limited {
+		raw, _, err := models.NewUserToken(tx, u, models.TokenPasswordReset, resetTokenTTL, "")
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		link := fmt.Sprintf("%s/password/reset/%s", siteURL(c), raw)
+
+		if err := mailers.SendPasswordReset(u.Email, link); err != nil {
+			c.Logger().Errorf("password reset to %s: %s", u.Email, err.Error())
+		}
+	}
+
+	c.Flash().Add("success", "If that address has an account, a reset link is on its way")
+
+	return c.Redirect(302, "/signin")
+}
+
+// PasswordReset shows the form for picking a new password
+// This function is mapped to the path GET /password/reset/{token}
+func PasswordReset(c buffalo.Context) error {
+	return tokenForm(c, models.TokenPasswordReset, "password/reset.html")
+}
+
+// PasswordResetUpdate sets the new password and uses up the link
+// This function is mapped to the path POST /password/reset/{token}
+func PasswordResetUpdate(c buffalo.Context) error {
+	return tokenPassword(c, models.TokenPasswordReset, "password/reset.html", "Your password was reset")
+}
+
+// tokenForm shows a form reached from an emailed link, as long as the
+// link is still good
+func tokenForm(c buffalo.Context, purpose, page string) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	t := &models.UserToken{}
+
+	if err := t.Lookup(tx, purpose, c.Param("token"), time.Now()); err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(302, "/signin")
+	}
+
+	c.Set("user", models.User{})
+	c.Set("token", c.Param("token"))
+	c.Set("errors", validate.NewErrors())
+
+	return c.Render(200, r.HTML(page))
+}
+
+// tokenPassword sets a password from an emailed link and signs the
+// user in
+func tokenPassword(c buffalo.Context, purpose, page, welcome string) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	t := &models.UserToken{}
+
+	if err := t.Lookup(tx, purpose, c.Param("token"), time.Now()); err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(302, "/signin")
+	}
+
+	u := &models.User{}
+
+	if err := tx.Find(u, t.UserID); err != nil {
+		return c.Error(404, err)
+	}
+
+	verrs, err := u.ChangePassword(tx, c.Param("Password"), c.Param("PasswordConfirmation"))
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("user", models.User{})
+		c.Set("token", c.Param("token"))
+		c.Set("errors", verrs)
+
+		return c.Render(http.StatusUnprocessableEntity, r.HTML(page))
+	}
+
+	if err := t.Redeem(tx, purpose, c.Param("token"), time.Now()); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// a reset usually means someone else might know the old password
+	if err := u.RevokeSessions(tx, uuid.Nil); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if purpose == models.TokenPasswordReset {
+		logLogin(c, models.LoginPasswordReset, u, u.Email)
+	}
+
+	clearFailures(c, u.Email)
+
+	return signIn(c, u, welcome)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gobuffalo/packr/v2"
 	"github.com/gobuffalo/suite"
+	"github.com/navionguy/quotewall/models"
 )
 
 type ActionSuite struct {
@@ -22,3 +23,18 @@ func Test_ActionSuite(t *testing.T) {
 	}
 	suite.Run(t, as)
 }
+
+// SetupTest empties the tables with models.TruncateAll, pop's own doesn't
+// work on SQLite.  It's the same database the suite's connection is to.
+// The shuffle went with them, so the wall has to forget it.  The last
+// test's metrics have to be written first, or they land in this one.
+func (as *ActionSuite) SetupTest() {
+	metricLog.wait()
+
+	db := as.DB
+	as.DB = nil
+	as.Action.SetupTest()
+	as.DB = db
+	as.NoError(models.TruncateAll(models.DB))
+	curShuffle = nil
+}
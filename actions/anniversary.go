@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"strconv"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// Anniversary lists all the published conversations that occurred on
+// today's month and day in previous years.  If there aren't any, he
+// widens the search to "window" days either side of today.
+// This function is mapped to the path GET /anniversary
+func (v ConversationsResource) Anniversary(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	day := today()
+	conversations := &models.Conversations{}
+
+	if err := conversations.OccurredOnThisDay(tx, day, 0); err != nil {
+		return errors.WithStack(err)
+	}
+
+	used := 0
+	if len(*conversations) == 0 {
+		used = defaultWindow
+		if w, err := strconv.Atoi(c.Param(window)); err == nil && w >= 0 {
+			used = w
+		}
+		if used > maxWindow {
+			used = maxWindow
+		}
+
+		if err := conversations.OccurredOnThisDay(tx, day, used); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.Set("day", day)
+	c.Set("window", used)
+	c.Set("conversations", conversations)
+
+	return c.Render(200, r.HTML("conversations/anniversary.html"))
+}
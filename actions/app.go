@@ -1,11 +1,10 @@
 package actions
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/envy"
-	"github.com/gobuffalo/logger"
 	forcessl "github.com/gobuffalo/mw-forcessl"
 	paramlogger "github.com/gobuffalo/mw-paramlogger"
 	"github.com/gorilla/sessions"
@@ -15,6 +14,7 @@ import (
 	csrf "github.com/gobuffalo/mw-csrf"
 	i18n "github.com/gobuffalo/mw-i18n"
 	"github.com/gobuffalo/packr/v2"
+	"github.com/navionguy/quotewall/logging"
 	"github.com/navionguy/quotewall/models"
 )
 
@@ -43,15 +43,45 @@ func App() *buffalo.App {
 			Host:        envy.Get("FORUM_HOST", envy.Get("HOST", "")),
 			Env:         ENV,
 			SessionName: "_quotewall_session",
-			LogLvl:      logger.InfoLevel,
+			Logger:      logging.Buffalo("actions"),
 		}
+		// a SQLite database is the app's own, so it keeps it migrated
+		if models.IsSQLite(models.DB) {
+			if err := models.Migrate(models.DB); err != nil {
+				log.Fatal(err)
+			}
+		}
+
 		cookieStore := defaultCookieStore(buffaloOptions)
 		buffaloOptions.SessionStore = cookieStore
+
+		// every request gets logged with its id, route and user
+		buffalo.RequestLogger = logRequests
 		app = buffalo.New(buffaloOptions)
 
+		// webhooks go out in the background so a slow receiver never
+		// holds up the admin pages
+		if err := app.Worker.Register(deliverWebhookJob, deliverWebhook); err != nil {
+			app.Stop(err)
+		}
+
+		// the maintenance jobs run on their schedules, SCHEDULER=off
+		// leaves them to another instance
+		if ENV != "test" && envy.Get("SCHEDULER", "on") != "off" {
+			if err := jobs.start(); err != nil {
+				app.Stop(err)
+			}
+		}
+
+		cv := &ConversationsResource{}
+
 		// Automatically redirect to SSL
 		//app.Use(forceSSL())
 
+		// Time every request for /metrics
+		app.Use(RecordMetrics)
+		app.Middleware.Skip(RecordMetrics, cv.WallStream) // streams stay open until the display goes away
+
 		// Log request parameters (filters apply).
 		app.Use(paramlogger.ParameterLogger)
 
@@ -62,19 +92,120 @@ func App() *buffalo.App {
 		// Wraps each request in a transaction.
 		//  c.Value("tx").(*pop.Connection)
 		// Remove to disable this.
-		app.Use(popmw.Transaction(models.DB))
+		txm := transaction()
+		app.Use(txm)
 
 		// Setup and use translations:
 		app.Use(translations())
 
-		cv := &ConversationsResource{}
+		// Loads the signed in user, if there is one
+		app.Use(SetCurrentUser)
+
+		app.GET("/metrics", Metrics)
+		app.Middleware.Skip(txm, Metrics)
+		app.Middleware.Skip(SetCurrentUser, Metrics)
+
+		// probes for container orchestration, they don't need a
+		// transaction or a user
+		app.GET("/healthz", Healthz)
+		app.GET("/readyz", Readyz)
+		app.GET("/version", VersionShow)
+		app.Middleware.Skip(txm, Healthz, Readyz, VersionShow)
+		app.Middleware.Skip(SetCurrentUser, Healthz, Readyz, VersionShow)
+
 		app.GET("/quickie", cv.QuickieQuote)
+		app.GET("/quickie.png", cv.QuickiePNG)
+		app.GET(streamPath, cv.WallStream)
+		app.Middleware.Skip(txm, cv.WallStream) // streams stay open, don't hold a transaction
+		app.Middleware.Skip(SetCurrentUser, cv.WallStream)
+		app.GET("/today", cv.Today)
+		app.GET("/today.json", cv.TodayJSON)
+		app.GET("/anniversary", cv.Anniversary)
+		app.GET("/conversations/{conversation_id}.png", cv.ConversationPNG)
+		app.GET("/conversations/{conversation_id}.svg", cv.ConversationSVG)
+		app.GET("/conversations/{conversation_id}/share", cv.Share)
+		app.GET("/oembed", OEmbed)
+		app.GET("/embed.js", cv.EmbedScript)
+		app.GET("/embed/{embed_key}.json", cv.EmbedJSON)
+		app.OPTIONS("/embed/{embed_key}.json", cv.EmbedPreflight)
+		app.GET("/embed/{embed_key}", cv.Embed)
+
+		// slack checks the raw request body, so nothing can read it first
+		app.POST("/slack/command", SlackCommand)
+		app.POST("/slack/webhook", SlackWebhook)
+		app.Middleware.Skip(csrf.New, SlackCommand, SlackWebhook)
+		app.Middleware.Skip(paramlogger.ParameterLogger, SlackCommand, SlackWebhook)
+
+		// mail programs post the one click unsubscribe without a csrf token
+		app.GET("/digest/unsubscribe/{token}", DigestUnsubscribe)
+		app.POST("/digest/unsubscribe/{token}", DigestUnsubscribeConfirm)
+		app.Middleware.Skip(csrf.New, DigestUnsubscribeConfirm)
+
+		// signing in and the links mailed to users
+		app.GET("/signin", AuthNew)
+		app.POST("/signin", AuthCreate)
+		app.DELETE("/signout", AuthDestroy)
+		app.GET("/signin/two-factor", TwoFactorNew)
+		app.POST("/signin/two-factor", TwoFactorCreate)
+		app.GET("/auth/oidc", OIDCStart)
+		app.GET("/auth/oidc/callback", OIDCCallback)
+		app.GET("/password/forgot", PasswordForgot)
+		app.POST("/password/forgot", PasswordForgotCreate)
+		app.GET("/password/reset/{token}", PasswordReset)
+		app.POST("/password/reset/{token}", PasswordResetUpdate)
+		app.GET("/account/email/{token}", AccountEmailConfirm)
+		app.GET("/invite/{token}", InviteAccept)
+		app.POST("/invite/{token}", InviteAcceptCreate)
+
+		account := app.Group("/account")
+		account.Use(Authorize)
+		account.GET("/", AccountShow)
+		account.POST("/password", AccountPassword)
+		account.POST("/email", AccountEmail)
+		account.POST("/digest", AccountDigest)
+		account.DELETE("/sessions", AccountSessionsRevoke)
+		account.DELETE("/sessions/{session_id}", AccountSessionRevoke)
+		account.GET("/two-factor", AccountTwoFactor)
+		account.POST("/two-factor", AccountTwoFactorEnable)
+		account.DELETE("/two-factor", AccountTwoFactorDisable)
+		account.POST("/two-factor/recovery-codes", AccountRecoveryCodes)
+
+		users := app.Group("/users")
+		users.Use(Authorize, AdminRequired)
+		users.GET("/", UsersList)
+		users.GET("/events", UsersEvents)
+		users.POST("/invite", UsersInvite)
+		users.POST("/{user_id}/admin", UsersAdmin)
+		users.POST("/{user_id}/signout", UsersSignOut)
+		users.DELETE("/{user_id}", UsersDestroy)
+
+		admin := app.Group("/admin")
+		admin.Use(Authorize, AdminRequired)
+		admin.GET("/stats", StatsShow)
+		admin.GET("/stats.json", StatsJSON)
+		admin.GET("/jobs", JobsList)
+		admin.POST("/jobs/{job}/run", JobsRun)
+		admin.GET("/logging", LoggingShow)
+		admin.POST("/logging", LoggingUpdate)
+
 		if envy.Get("ADDR", "127.0.0.1") == "127.0.0.1" {
-			fmt.Println("adding routes")
+			log.Debug("adding the local only routes")
 			app.GET("/", HomeHandler)
 			app.Resource("/authors", &AuthorsResource{})
 			app.GET("/conversations/export/", cv.Export) // this is becoming useless and should probably go away
 			app.Resource("/conversations", cv)
+
+			dr := &DisplaysResource{}
+			app.GET("/displays", dr.List)
+			app.POST("/displays/advance", dr.Advance)
+			app.POST("/displays/pin", dr.Pin)
+			app.POST("/displays/unpin", dr.Unpin)
+			app.Resource("/display_profiles", &DisplayProfilesResource{})
+			app.Resource("/embed_keys", &EmbedKeysResource{})
+
+			wh := &WebhooksResource{}
+			app.POST("/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", wh.Redeliver)
+			app.Resource("/webhooks", wh)
 		}
 
 		app.ServeFiles("/", assetsBox) // serve files from the public directory
@@ -95,6 +226,102 @@ func translations() buffalo.MiddlewareFunc {
 	return T.Middleware()
 }
 
+// transaction returns the middleware that wraps each request in a
+// transaction, kept in "tx".  What has to be written whatever happens to
+// the request, like the login failures, waits until the transaction is
+// over.  On SQLite that keeps it from waiting on the request's own write
+// lock, and on Postgres it keeps it out of the way of a rollback.  Work
+// that depends on the change sticking, like the webhooks, only runs once
+// the transaction commits.
+func transaction() buffalo.MiddlewareFunc {
+	txm := popmw.Transaction(models.DB)
+
+	return func(next buffalo.Handler) buffalo.Handler {
+		h := txm(next)
+
+		return func(c buffalo.Context) error {
+			hooks := &txHooks{}
+			c.Set(txHooksKey, hooks)
+
+			err := h(c)
+			hooks.run(err == nil && succeeded(c))
+
+			return err
+		}
+	}
+}
+
+const txHooksKey = "tx_hooks"
+
+// txHooks is the work waiting on the request's transaction to be over
+type txHooks struct {
+	finished   []func() // runs whether it committed or not
+	committed  []func() // runs only once it commits
+	rolledBack []func() // runs only if it got rolled back
+}
+
+func (h *txHooks) run(committed bool) {
+	for _, fn := range h.finished {
+		fn()
+	}
+
+	then := h.rolledBack
+	if committed {
+		then = h.committed
+	}
+
+	for _, fn := range then {
+		fn()
+	}
+}
+
+// afterTransaction runs fn once the request's transaction is over, however
+// it ended.  Outside a transaction it runs right away.
+func afterTransaction(c buffalo.Context, fn func()) {
+	hooks, ok := c.Value(txHooksKey).(*txHooks)
+
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.finished = append(hooks.finished, fn)
+}
+
+// afterCommit runs fn once the request's transaction commits, and not at
+// all if it gets rolled back.  Outside a transaction it runs right away.
+func afterCommit(c buffalo.Context, fn func()) {
+	hooks, ok := c.Value(txHooksKey).(*txHooks)
+
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.committed = append(hooks.committed, fn)
+}
+
+// afterRollback runs fn if the request's transaction gets rolled back, to
+// forget what was kept in memory about the change.  Outside a transaction
+// there is nothing to roll back, so it never runs.
+func afterRollback(c buffalo.Context, fn func()) {
+	hooks, ok := c.Value(txHooksKey).(*txHooks)
+
+	if !ok {
+		return
+	}
+
+	hooks.rolledBack = append(hooks.rolledBack, fn)
+}
+
+// succeeded is whether the transaction middleware commits what the
+// request did, it rolls back anything outside 2xx and 3xx
+func succeeded(c buffalo.Context) bool {
+	res, ok := c.Response().(*buffalo.Response)
+
+	return !ok || (res.Status >= 200 && res.Status < 400)
+}
+
 // forceSSL will return a middleware that will redirect an incoming request
 // if it is not HTTPS. "http://example.com" => "https://example.com".
 // This middleware does **not** enable SSL. for your application. To do that
@@ -120,9 +347,13 @@ func defaultCookieStore(opts buffalo.Options) sessions.Store {
 	// setup the cookie store, with encryption
 	cookieStore := sessions.NewCookieStore([]byte(secret))
 	// SameSite field values: strict=3, Lax=2, None=4, Default=1.
-	cookieStore.Options.SameSite = 3
+	// Lax, so the cookie comes along when the single sign-on provider
+	// sends the browser back.  The csrf tokens still cover the forms.
+	cookieStore.Options.SameSite = 2
 	//Cookie secure attributes, see: https://www.owasp.org/index.php/Testing_for_cookies_attributes_(OTG-SESS-002)
 	cookieStore.Options.HttpOnly = true
+	// the cookie outlives the browser, for as long as a sign in lasts
+	cookieStore.MaxAge(int(sessionLifetime / time.Second))
 	if ENV == "production" {
 		cookieStore.Options.Secure = true
 	}
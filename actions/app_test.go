@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+func (as *ActionSuite) Test_TransactionRollsBack() {
+	finished := 0
+
+	write := func(name string, status int, fail bool) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			afterTransaction(c, func() { finished++ })
+
+			tx := c.Value("tx").(*pop.Connection)
+			if err := tx.Create(&models.Author{Name: name}); err != nil {
+				return err
+			}
+
+			if fail {
+				return errors.New("it went wrong")
+			}
+
+			return c.Render(status, r.String("done"))
+		}
+	}
+
+	a := buffalo.New(buffalo.Options{})
+	a.Use(transaction())
+	a.GET("/refused", write("Refused", http.StatusUnprocessableEntity, false))
+	a.GET("/failed", write("Failed", http.StatusOK, true))
+	a.GET("/committed", write("Committed", http.StatusOK, false))
+
+	for _, path := range []string{"/refused", "/failed", "/committed"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// only the request that went through keeps what it wrote
+	authors := models.Authors{}
+	as.NoError(models.DB.All(&authors))
+	as.Len(authors, 1)
+	as.Equal("Committed", authors[0].Name)
+
+	// and what waits on the transaction runs however it ended
+	as.Equal(3, finished)
+}
+
+func (as *ActionSuite) Test_ShuffleRolledBack() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	published, err := models.DB.Where("publish = ?", true).Count(&models.Conversation{})
+	as.NoError(err)
+
+	shuffle := func(status int) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			if err := newRequest(c).getShuffleData(); err != nil {
+				return err
+			}
+
+			return c.Render(status, r.String("done"))
+		}
+	}
+
+	a := buffalo.New(buffalo.Options{})
+	a.Use(transaction())
+	a.GET("/rolled-back", shuffle(http.StatusInternalServerError))
+	a.GET("/committed", shuffle(http.StatusOK))
+
+	// the shuffle went with the transaction, so the wall shuffles again
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/rolled-back", nil))
+	as.Nil(curShuffle)
+
+	a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/committed", nil))
+	as.NotNil(curShuffle)
+	as.Equal(published, curShuffle.Size)
+}
@@ -0,0 +1,312 @@
+package actions
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const currentUserKey = "current_user_id"
+const redirectKey = "redirectURL"
+
+// AuthNew loads the signin page
+// This function is mapped to the path GET /signin
+func AuthNew(c buffalo.Context) error {
+	c.Set("user", models.User{})
+	setSSO(c)
+
+	return c.Render(200, r.HTML("auth/new.html"))
+}
+
+// AuthCreate attempts to log the user in with an existing account.
+// This function is mapped to the path POST /signin
+func AuthCreate(c buffalo.Context) error {
+	u := &models.User{}
+	if err := c.Bind(u); err != nil {
+		return errors.WithStack(err)
+	}
+
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	password := u.Password
+	now := time.Now()
+
+	// helper function to handle bad attempts
+	bad := func(status int, msg string) error {
+		verrs := validate.NewErrors()
+		verrs.Add("email", msg)
+
+		c.Set("errors", verrs)
+		c.Set("user", models.User{Email: u.Email})
+		setSSO(c)
+
+		return c.Render(status, r.HTML("auth/new.html"))
+	}
+
+	until, locked, err := lockedOut(c, u.Email, now)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if locked {
+		logLogin(c, models.LoginLockedOut, nil, u.Email)
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(until.Sub(now).Seconds())+1))
+
+		return bad(http.StatusTooManyRequests, "too many failed sign ins, try again after "+until.Format("15:04"))
+	}
+
+	// find a user with the email
+	err = u.FindByEmail(tx, u.Email)
+
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			// couldn't find an user with the supplied email address.
+			recordFailure(c, u.Email, now)
+			logLogin(c, models.LoginFailed, nil, u.Email)
+
+			return bad(http.StatusUnauthorized, "invalid email/password")
+		}
+		return errors.WithStack(err)
+	}
+
+	// confirm that the given password matches the hashed password from the db
+	if !u.CheckPassword(password) {
+		recordFailure(c, u.Email, now)
+		logLogin(c, models.LoginFailed, u, u.Email)
+
+		return bad(http.StatusUnauthorized, "invalid email/password")
+	}
+
+	return signIn(c, u, "Welcome back!")
+}
+
+// AuthDestroy clears the session and logs a user out
+// This function is mapped to the path DELETE /signout
+func AuthDestroy(c buffalo.Context) error {
+	if u := currentUser(c); u != nil {
+		tx, ok := c.Value("tx").(*pop.Connection)
+		if !ok {
+			return errors.WithStack(errors.New("no transaction found"))
+		}
+
+		if sid, ok := c.Session().Get(currentSessionKey).(string); ok {
+			if err := u.RevokeSession(tx, sid); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		logLogin(c, models.LoginSignOut, u, u.Email)
+	}
+
+	c.Session().Clear()
+	c.Flash().Add("success", "You have been signed out")
+	return c.Redirect(302, "/signin")
+}
+
+// setSSO tells the sign in page whether to offer single sign-on
+func setSSO(c buffalo.Context) {
+	c.Set("ssoEnabled", sso.cfg.enabled())
+	c.Set("ssoName", sso.cfg.Name)
+}
+
+// signIn starts a session for the user, unless he has two-factor turned
+// on, then the code has to be checked first.  The failures against the
+// account are only forgotten once he is all the way in, otherwise the
+// password could be sent again between code guesses to reset the lockout.
+func signIn(c buffalo.Context, u *models.User, welcome string) error {
+	if u.TwoFactorEnabled() {
+		return askTwoFactor(c, u, welcome)
+	}
+
+	clearFailures(c, u.Email)
+
+	return startSession(c, u, welcome)
+}
+
+// startSession records the sign in and sends the user back where he was
+// headed before he had to sign in
+func startSession(c buffalo.Context, u *models.User, welcome string) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	us, err := models.NewUserSession(tx, u, clientIP(c), c.Request().UserAgent(), sessionLifetime)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Session().Set(currentUserKey, u.ID.String())
+	c.Session().Set(currentSessionKey, us.ID.String())
+	c.Flash().Add("success", welcome)
+	logLogin(c, models.LoginSignIn, u, u.Email)
+
+	redirectURL := "/account"
+	if redir, ok := c.Session().Get(redirectKey).(string); ok && safeRedirect(redir) {
+		redirectURL = redir
+	}
+	c.Session().Delete(redirectKey)
+
+	return c.Redirect(302, redirectURL)
+}
+
+// safeRedirect only allows paths on this site, so a crafted link can't
+// bounce a fresh login somewhere else
+func safeRedirect(u string) bool {
+	return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") && !strings.HasPrefix(u, "/\\")
+}
+
+// SetCurrentUser attempts to find a user based on the current_user_id
+// in the session. If one is found it is set on the context.  The
+// session has to still be on record, so a revoked or expired one is
+// signed out.
+func SetCurrentUser(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		uid, ok := c.Session().Get(currentUserKey).(string)
+		if !ok {
+			return next(c)
+		}
+
+		tx, ok := c.Value("tx").(*pop.Connection)
+		if !ok {
+			return errors.WithStack(errors.New("no transaction found"))
+		}
+
+		sid, _ := c.Session().Get(currentSessionKey).(string)
+		us := &models.UserSession{}
+		u := &models.User{}
+
+		signOut := func() error {
+			c.Session().Delete(currentUserKey)
+			c.Session().Delete(currentSessionKey)
+			return next(c)
+		}
+
+		// a bad id would abort the transaction, so check before looking
+		if _, err := uuid.FromString(sid); err != nil {
+			return signOut()
+		}
+
+		if _, err := uuid.FromString(uid); err != nil {
+			return signOut()
+		}
+
+		// the session may have been revoked, or the account removed, since
+		// it started
+		if err := us.FindActive(tx, sid, uid, time.Now()); err != nil {
+			return signOut()
+		}
+
+		if err := tx.Find(u, uid); err != nil {
+			return signOut()
+		}
+
+		if err := us.Touch(tx, time.Now()); err != nil {
+			return errors.WithStack(err)
+		}
+
+		c.Set("current_user", u)
+		c.Set("current_session", us)
+		c.LogField("user_id", u.ID)
+
+		return next(c)
+	}
+}
+
+// Authorize require a user be logged in before accessing a route.  Users
+// whose role calls for two-factor can't go anywhere but the two-factor
+// setup until it is turned on.
+func Authorize(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		u, ok := c.Value("current_user").(*models.User)
+		if !ok {
+			if c.Request().Method == http.MethodGet {
+				c.Session().Set(redirectKey, c.Request().URL.String())
+			}
+
+			if err := c.Session().Save(); err != nil {
+				return errors.WithStack(err)
+			}
+
+			c.Flash().Add("danger", "You must be signed in to see that page")
+			return c.Redirect(302, "/signin")
+		}
+
+		if !u.TwoFactorEnabled() && !strings.HasPrefix(c.Request().URL.Path, twoFactorSetupPath) {
+			tx, ok := c.Value("tx").(*pop.Connection)
+			if !ok {
+				return errors.WithStack(errors.New("no transaction found"))
+			}
+
+			required, err := u.TwoFactorRequired(tx, twoFactorRoles)
+
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			if required {
+				c.Flash().Add("warning", "Your role requires two-factor sign in, set it up to continue")
+				return c.Redirect(302, twoFactorSetupPath)
+			}
+		}
+
+		return next(c)
+	}
+}
+
+// AdminRequired only lets through users with the admin permission.  It
+// has to come after Authorize.
+func AdminRequired(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		u, ok := c.Value("current_user").(*models.User)
+		if !ok {
+			return c.Error(http.StatusForbidden, errors.New("not signed in"))
+		}
+
+		tx, ok := c.Value("tx").(*pop.Connection)
+		if !ok {
+			return errors.WithStack(errors.New("no transaction found"))
+		}
+
+		admin, err := u.HasPermission(tx, models.PermissionAdmin)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if !admin {
+			return c.Error(http.StatusForbidden, errors.New("admin permission required"))
+		}
+
+		return next(c)
+	}
+}
+
+// currentUser is the signed in user, Authorize makes sure there is one
+func currentUser(c buffalo.Context) *models.User {
+	u, _ := c.Value("current_user").(*models.User)
+	return u
+}
+
+// currentSession is the signed in browser's session, it comes along with
+// the current user
+func currentSession(c buffalo.Context) *models.UserSession {
+	if us, ok := c.Value("current_session").(*models.UserSession); ok {
+		return us
+	}
+	return &models.UserSession{}
+}
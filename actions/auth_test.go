@@ -0,0 +1,205 @@
+package actions
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_safeRedirect(t *testing.T) {
+	tests := map[string]bool{
+		"/account":             true,
+		"/users?page=2":        true,
+		"//evil.example.com":   false,
+		"/\\evil.example.com":  false,
+		"https://example.com/": false,
+		"":                     false,
+	}
+
+	for u, want := range tests {
+		if got := safeRedirect(u); got != want {
+			t.Errorf("safeRedirect(%q) got %t, wanted %t", u, got, want)
+		}
+	}
+}
+
+func (as *ActionSuite) Test_AuthCreate() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	res := as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "wrong"})
+	as.Equal(http.StatusUnauthorized, res.Code)
+	as.Contains(res.Body.String(), "invalid email/password")
+
+	res = as.HTML("/signin").Post(map[string]string{"Email": "Mark@Example.com", "Password": "password"})
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/account", res.Location())
+
+	res = as.HTML("/account").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "mark@example.com")
+}
+
+func (as *ActionSuite) Test_Authorize() {
+	res := as.HTML("/account").Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/signin", res.Location())
+
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	as.signInAs(u)
+
+	res = as.HTML("/users").Get()
+	as.Equal(http.StatusForbidden, res.Code)
+
+	as.NoError(u.Grant(models.DB, models.PermissionAdmin))
+
+	// admins have to set up two-factor first
+	res = as.HTML("/users").Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal(twoFactorSetupPath, res.Location())
+
+	res = as.HTML(twoFactorSetupPath).Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	as.enableTwoFactor(u)
+
+	res = as.HTML("/users").Get()
+	as.Equal(http.StatusOK, res.Code)
+}
+
+func (as *ActionSuite) Test_PasswordReset() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	raw, _, err := models.NewUserToken(models.DB, u, models.TokenPasswordReset, time.Hour, "")
+	as.NoError(err)
+
+	res := as.HTML("/password/reset/%s", raw).Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	res = as.HTML("/password/reset/%s", raw).Post(map[string]string{"Password": "short", "PasswordConfirmation": "short"})
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+
+	res = as.HTML("/password/reset/%s", raw).Post(map[string]string{"Password": "new password", "PasswordConfirmation": "new password"})
+	as.Equal(http.StatusFound, res.Code)
+
+	found := &models.User{}
+	as.NoError(found.FindByEmail(models.DB, u.Email))
+	as.True(found.CheckPassword("new password"))
+
+	// the link only works once
+	res = as.HTML("/password/reset/%s", raw).Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/signin", res.Location())
+}
+
+func Test_parseLifetime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":      14 * 24 * time.Hour,
+		"72h":   72 * time.Hour,
+		"soon":  14 * 24 * time.Hour,
+		"30s":   14 * 24 * time.Hour,
+		" 90m ": 90 * time.Minute,
+	}
+
+	for s, want := range tests {
+		if got := parseLifetime(s); got != want {
+			t.Errorf("parseLifetime(%q) got %s, wanted %s", s, got, want)
+		}
+	}
+}
+
+// signInAs starts a session for u the same way signing in does
+func (as *ActionSuite) signInAs(u *models.User) *models.UserSession {
+	us, err := models.NewUserSession(models.DB, u, "127.0.0.1", "test", time.Hour)
+	as.NoError(err)
+
+	as.Session.Set(currentUserKey, u.ID.String())
+	as.Session.Set(currentSessionKey, us.ID.String())
+
+	return us
+}
+
+func (as *ActionSuite) Test_AuthCreate_Lockout() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	for i := 0; i < accountLockoutAfter; i++ {
+		res := as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "wrong"})
+		as.Equal(http.StatusUnauthorized, res.Code)
+	}
+
+	// even the right password is turned away until the lockout ends
+	res := as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "password"})
+	as.Equal(http.StatusTooManyRequests, res.Code)
+	as.NotEmpty(res.Header().Get("Retry-After"))
+
+	events := models.LoginEvents{}
+	as.NoError(events.Recent(models.DB, 10))
+	as.Len(events, accountLockoutAfter+1)
+	as.Equal(models.LoginLockedOut, events[0].Event)
+}
+
+func (as *ActionSuite) Test_AuthCreate_ClearsFailures() {
+	u := &models.User{Email: " Mark@Example.com ", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	res := as.HTML("/signin").Post(map[string]string{"Email": " Mark@Example.com", "Password": "wrong"})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	count, err := models.DB.Where("scope = ?", models.ScopeAccount).Count(&models.LoginFailure{})
+	as.NoError(err)
+	as.Equal(1, count)
+
+	// however the address was typed, signing in forgets the failures
+	res = as.HTML("/signin").Post(map[string]string{"Email": "MARK@example.com ", "Password": "password"})
+	as.Equal(http.StatusFound, res.Code)
+
+	count, err = models.DB.Where("scope = ?", models.ScopeAccount).Count(&models.LoginFailure{})
+	as.NoError(err)
+	as.Equal(0, count)
+}
+
+func (as *ActionSuite) Test_AccountSessionRevoke() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	other, err := models.NewUserSession(models.DB, u, "10.0.0.2", "phone", time.Hour)
+	as.NoError(err)
+
+	as.signInAs(u)
+
+	res := as.HTML("/account").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "phone")
+
+	res = as.HTML("/account/sessions/%s", other.ID).Delete()
+	as.Equal(http.StatusFound, res.Code)
+
+	sessions := models.UserSessions{}
+	as.NoError(sessions.ForUser(models.DB, u.ID, time.Now()))
+	as.Len(sessions, 1)
+
+	// revoking this one signs us out
+	as.NoError(u.RevokeSessions(models.DB, other.ID))
+
+	res = as.HTML("/account").Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/signin", res.Location())
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
 
 	"github.com/navionguy/quotewall/models"
 	"github.com/pkg/errors"
@@ -27,7 +28,7 @@ func (v AuthorsResource) List(c buffalo.Context) error {
 	// Default values are "page=1" and "per_page=20".
 
 	// Get all the authors names and their quote count
-	cq := tx.PaginateFromParams(c.Params()).RawQuery("SELECT authors.id, authors.name, COUNT(DISTINCT quotes.id) FROM authors LEFT JOIN quotes ON quotes.author_id = authors.id GROUP BY authors.id ORDER BY authors.name")
+	cq := tx.PaginateFromParams(c.Params()).RawQuery("SELECT authors.id, authors.name, COUNT(DISTINCT quotes.id) AS count FROM authors LEFT JOIN quotes ON quotes.author_id = authors.id GROUP BY authors.id ORDER BY authors.name")
 
 	authorCredits := &models.AuthorCredits{}
 
@@ -61,7 +62,7 @@ func (v AuthorsResource) Create(c buffalo.Context) error {
 		return errors.WithStack(err)
 	}
 
-	fmt.Printf("new speaker %s, %s\n", speaker.Name, speaker.ID.String())
+	c.Logger().Debugf("new speaker %s", speaker.Name)
 
 	tx, ok := c.Value("tx").(*pop.Connection)
 
@@ -69,8 +70,12 @@ func (v AuthorsResource) Create(c buffalo.Context) error {
 		return errors.WithStack(errors.New("no transaction found"))
 	}
 
-	if nil != speaker.FindByName() {
-		verrs, err := speaker.Create()
+	repo := models.NewPopRepository(tx)
+
+	if found, err := repo.Authors.FindByName(speaker.Name); err == nil {
+		*speaker = *found
+	} else {
+		verrs, err := repo.Authors.Create(speaker)
 
 		if err != nil {
 			return err
@@ -86,11 +91,13 @@ func (v AuthorsResource) Create(c buffalo.Context) error {
 			return c.Render(422, r.Auto(c, speaker))
 		}
 		c.Flash().Add("success", "Speaker created successfully!")
+
+		fireAuthor(c, speaker, models.EventAuthorCreated)
 	}
 
 	cvjson := c.Request().Form.Get("cvjson")
 
-	fmt.Printf("json length %d, %s\n", len(cvjson), cvjson)
+	c.Logger().Debugf("conversation json is %d bytes", len(cvjson))
 
 	if len(cvjson) == 0 {
 		return c.Redirect(201, "authors")
@@ -98,16 +105,14 @@ func (v AuthorsResource) Create(c buffalo.Context) error {
 
 	// put the conversation back into the form
 	conv := models.Conversation{}
-	err := conv.ExtractConversationFromJSON(cvjson)
-
-	if err != nil {
+	if err := conv.ExtractConversationFromJSON(cvjson); err != nil {
 		return errors.WithStack(err)
 	}
 
-	authors := []models.Author{}
-
 	// Retrieve all Authors from the DB
-	if err := tx.Order("name").All(&authors); err != nil {
+	authors, err := repo.Authors.All()
+
+	if err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -121,15 +126,18 @@ func (v AuthorsResource) Create(c buffalo.Context) error {
 // Edit renders a edit form for an Author. This function is
 // mapped to the path GET /authors/{author_id}/edit
 func (v AuthorsResource) Edit(c buffalo.Context) error {
-	// Get the DB connection from the context
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return errors.WithStack(errors.New("no transaction found"))
+	repo, err := repository(c)
+	if err != nil {
+		return err
 	}
 
-	spkr := models.Author{}
+	id, err := uuid.FromString(c.Param("author_id"))
+	if err != nil {
+		return c.Error(404, err)
+	}
 
-	if err := tx.Find(&spkr, c.Param("author_id")); err != nil {
+	spkr, err := repo.Authors.Find(id)
+	if err != nil {
 		return c.Error(404, err)
 	}
 
@@ -155,11 +163,9 @@ func (v AuthorsResource) unMarshalConversation(c buffalo.Context) (*models.Conve
 // Update changes an Author in the DB. This function is mapped to
 // the path PUT /authors/{author_id}
 func (v AuthorsResource) Update(c buffalo.Context) error {
-	fmt.Println("authors PUT")
-	// Get the DB connection from the context
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return errors.WithStack(errors.New("no transaction found"))
+	repo, err := repository(c)
+	if err != nil {
+		return err
 	}
 
 	speaker := &models.Author{}
@@ -169,8 +175,8 @@ func (v AuthorsResource) Update(c buffalo.Context) error {
 		return errors.WithStack(err)
 	}
 
-	fmt.Printf("modified speaker %s, %s\n", speaker.Name, c.Param("author_id"))
-	verrs, err := tx.ValidateAndUpdate(speaker)
+	c.Logger().Debugf("modified speaker %s, %s", speaker.Name, c.Param("author_id"))
+	verrs, err := repo.Authors.Update(speaker)
 
 	if err != nil {
 		return err
@@ -187,7 +193,7 @@ func (v AuthorsResource) Update(c buffalo.Context) error {
 	}
 	c.Flash().Add("success", "Speaker updated successfully!")
 
-	fmt.Println("Moving back to author list.")
+	fireAuthor(c, speaker, models.EventAuthorUpdated)
 
 	return c.Redirect(302, fmt.Sprintf("/author//%%7B%s%%7D/", speaker.ID.String()))
 
@@ -24,12 +24,17 @@ import (
 // Path: Plural (/conversations)
 // View Template Folder: Plural (/templates/conversations/)
 
-var fontScale = map[int]string{
-	1: "48px",
-	2: "40px",
-	3: "30px",
-	4: "20px",
-	5: "10px",
+// showScreen is the box the Show page draws the conversation in
+var showScreen = screenSize{Width: 800, Height: 630}
+
+const showFontMin = 10
+const showFontMax = 48
+
+// showNote is one annotation as the Show page prints it
+type showNote struct {
+	Kind string
+	Note string
+	By   string
 }
 
 // ConversationsResource is the resource for the Conversation model
@@ -85,15 +90,27 @@ func checkAuthorFilter(c buffalo.Context) *models.Author {
 		return auth
 	}
 
-	err := auth.FindByName()
+	repo, err := repository(c)
 
 	if err != nil {
-		// name passed in is not a known author
+		c.Logger().Warnf("author filter: %s", err.Error())
 		auth.Name = ""
-		c.Flash().Add("success", "No Quotes found for Author.")
 		return auth
 	}
 
+	found, err := repo.Authors.FindByName(auth.Name)
+
+	if err == nil {
+		return found
+	}
+
+	if err != models.ErrNotFound {
+		c.Logger().Warnf("author filter: %s", err.Error())
+	}
+
+	// name passed in is not a known author
+	auth.Name = ""
+	c.Flash().Add("success", "No Quotes found for Author.")
 	return auth
 }
 
@@ -107,17 +124,54 @@ func (v ConversationsResource) Show(c buffalo.Context) error {
 		return c.Error(404, err)
 	}
 
-	var fontSize string
-	if len(conversation.Quotes) > 1 {
-		fontSize = fmt.Sprintf("%s", fontScale[len(conversation.Quotes)])
-	} else if len(conversation.Quotes[0].Phrase) > 100 {
-		fontSize = fmt.Sprintf("%s", fontScale[2])
-	}
+	v.layoutConversation(c, conversation)
 
-	c.Set("fontsize", fontSize)
+	// only published conversations unfurl when the link is shared
+	c.Set("shared", conversation.Publish)
 	return c.Render(200, r.Auto(c, conversation))
 }
 
+// Share shows a published conversation to anyone, with the tags chat apps
+// need to unfurl it.  This function is mapped to the path
+// GET /conversations/{conversation_id}/share
+func (v ConversationsResource) Share(c buffalo.Context) error {
+	conversation, err := v.loadConversation(c)
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// unpublished conversations don't exist as far as the world knows
+	if !conversation.Publish || len(conversation.Quotes) == 0 {
+		return c.Error(404, errors.New("conversation not found"))
+	}
+
+	v.layoutConversation(c, conversation)
+
+	c.Set("conversation", conversation)
+	return c.Render(200, r.HTML("conversations/share.html"))
+}
+
+// layoutConversation fits the quotes to the page and fills in the share card
+func (v ConversationsResource) layoutConversation(c buffalo.Context, conversation *models.Conversation) {
+	var phrases []string
+	for _, q := range conversation.Quotes {
+		phrases = append(phrases, q.Phrase)
+	}
+
+	// speaker and date go under every quote, then a line for each note
+	// on the quote with the most of them
+	notes := 1
+	for _, q := range conversation.Quotes {
+		if len(q.Annotations) > notes {
+			notes = len(q.Annotations)
+		}
+	}
+
+	scr := parseResolution(c.Param(resolutionParam), showScreen)
+	c.Set("layout", fitConversation(phrases, scr, showFontMin, showFontMax, 2+notes))
+	c.Set("share", newShareCard(c, conversation))
+}
+
 // New renders the form for creating a new Conversation.
 // This function is mapped to the path GET /conversations/new
 func (v ConversationsResource) New(c buffalo.Context) error {
@@ -170,7 +224,13 @@ func (v ConversationsResource) Create(c buffalo.Context) error {
 		return v.addAuthor(conv, c)
 
 	case "save":
-		verrs, err := conv.Create()
+		repo, err := repository(c)
+
+		if err != nil {
+			return err
+		}
+
+		verrs, err := repo.Conversations.Create(conv)
 
 		if err != nil {
 			return errors.WithStack(err)
@@ -189,6 +249,12 @@ func (v ConversationsResource) Create(c buffalo.Context) error {
 		}
 		c.Flash().Add("success", "Conversation was created successfully")
 
+		events := []string{models.EventConversationCreated}
+		if conv.Publish {
+			events = append(events, models.EventConversationPublished)
+		}
+		fireConversation(c, conv, events...)
+
 		return c.Redirect(302, fmt.Sprintf("/conversations//%%7B%s%%7D/", conv.ID.String()))
 		//return c.Render(201, r.Auto(c, conv))
 	}
@@ -218,10 +284,9 @@ func (v ConversationsResource) Edit(c buffalo.Context) error {
 // Update changes a Conversation in the DB. This function is mapped to
 // the path PUT /conversations/{conversation_id}
 func (v ConversationsResource) Update(c buffalo.Context) error {
-	// Get the DB connection from the context
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return errors.WithStack(errors.New("no transaction found"))
+	repo, err := repository(c)
+	if err != nil {
+		return err
 	}
 
 	req := c.Request()
@@ -229,26 +294,30 @@ func (v ConversationsResource) Update(c buffalo.Context) error {
 		return errors.WithStack(err)
 	}
 
-	conv, option, err := v.bindToForm(c)
+	id, err := uuid.FromString(c.Param("conversation_id"))
+	if err != nil {
+		return c.Error(404, err)
+	}
 
+	// the conversation being edited, the form gets laid over him
+	conv, err := repo.Conversations.Find(id)
 	if err != nil {
-		return err
+		return c.Error(404, err)
 	}
+	wasPublished := conv.Publish
 
-	// Allocate an empty quote
-	quote := &models.Quote{}
+	form, option, err := v.bindToForm(c)
 
-	if err := tx.Find(conv, c.Param("conversation_id")); err != nil {
-		return c.Error(404, err)
+	if err != nil {
+		return err
 	}
-	var verrs *validate.Errors
 
 	switch *option {
 	case "addAuthor":
-		return v.addAuthor(conv, c)
+		return v.addAuthor(form, c)
 
 	case "save":
-		conv, quote, verrs, err = v.saveConversation(quote)
+		verrs, err := v.saveConversation(repo, conv, form)
 
 		if err != nil {
 			return err
@@ -265,7 +334,13 @@ func (v ConversationsResource) Update(c buffalo.Context) error {
 
 			return c.Render(422, r.HTML("conversations/new.html"))
 		}
-		c.Flash().Add("success", "Conversation was created successfully")
+		c.Flash().Add("success", "Conversation was updated successfully")
+
+		events := []string{models.EventConversationUpdated}
+		if conv.Publish && !wasPublished {
+			events = append(events, models.EventConversationPublished)
+		}
+		fireConversation(c, conv, events...)
 
 		//return c.Redirect(302, fmt.Sprintf("/conversations//%%7B%s%%7D/", conversation.ID.String()))
 		return c.Render(201, r.Auto(c, conv))
@@ -283,27 +358,26 @@ func (v ConversationsResource) Destroy(c buffalo.Context) error {
 		return errors.WithStack(errors.New("no transaction found"))
 	}
 
-	// Allocate an empty Conversation
-	conversation := &models.Conversation{}
+	repo := models.NewPopRepository(tx)
 
 	// To find the Conversation the parameter conversation_id is used.
-	if err := tx.Eager("Quotes").Find(conversation, c.Param("conversation_id")); err != nil {
+	id, err := uuid.FromString(c.Param("conversation_id"))
+	if err != nil {
 		return c.Error(404, err)
 	}
 
-	// loop through all the quotes and delete them
-	for i := range conversation.Quotes {
-		q := &models.Quote{}
-		q.ID = conversation.Quotes[i].ID
-		if err := tx.Destroy(q); err != nil {
-			return errors.WithStack(err)
-		}
+	conversation, err := repo.Conversations.Find(id)
+	if err != nil {
+		return c.Error(404, err)
 	}
 
-	if err := tx.Destroy(conversation); err != nil {
+	// the quotes go with it
+	if err := repo.Conversations.Destroy(conversation); err != nil {
 		return errors.WithStack(err)
 	}
 
+	fireConversation(c, conversation, models.EventConversationDeleted)
+
 	// If there are no errors set a flash message
 	c.Flash().Add("success", "Conversation was destroyed successfully")
 
@@ -323,7 +397,7 @@ func (v ConversationsResource) Export(c buffalo.Context) error {
 
 	conversations := &models.Conversations{}
 
-	if err := tx.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotation").All(conversations); err != nil {
+	if err := tx.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").Eager("Quotes.Annotations.Author").All(conversations); err != nil {
 		return c.Error(404, err)
 	}
 
@@ -334,22 +408,39 @@ func (v ConversationsResource) Export(c buffalo.Context) error {
 	return c.Render(200, r.JSON(conversations))
 }
 
-// saveConversation - the user has finished adding quotes and is ready to save the conversation
-func (v ConversationsResource) saveConversation(quote *models.Quote) (*models.Conversation, *models.Quote, *validate.Errors, error) {
-
-	conversation := &models.Conversation{}
+// saveConversation - the user has finished editing the conversation and
+// is ready to save it.  What was on the form gets laid over the stored
+// conversation, his quotes take the place of the stored ones in order and
+// any stored ones left over get removed.
+func (v ConversationsResource) saveConversation(repo *models.Repository, conv *models.Conversation, form *models.Conversation) (*validate.Errors, error) {
+	stored := conv.Quotes
+
+	form.ID = conv.ID
+	form.CreatedAt = conv.CreatedAt
+	for i := range form.Quotes {
+		if i < len(stored) {
+			form.Quotes[i].ID = stored[i].ID
+		}
+	}
+	*conv = *form
 
-	verrs, err := conversation.Create()
+	verrs, err := repo.Conversations.Update(conv)
 
 	if err != nil {
-		return nil, nil, nil, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 
 	if verrs.HasAny() {
-		return nil, quote, verrs, nil
+		return verrs, nil
 	}
 
-	return conversation, nil, nil, nil
+	for i := len(conv.Quotes); i < len(stored); i++ {
+		if err := repo.Quotes.Destroy(&stored[i]); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return verrs, nil
 }
 
 func (v ConversationsResource) nextQuote(c buffalo.Context) (*models.Conversation, error) {
@@ -359,55 +450,55 @@ func (v ConversationsResource) nextQuote(c buffalo.Context) (*models.Conversatio
 // loadConversation handles loading a quote for the Show() function.
 // I may push this back into the function unless I figure out a better way to print.
 func (v ConversationsResource) loadConversation(c buffalo.Context) (*models.Conversation, error) {
-	// Get the DB connection from the context
-	tx, ok := c.Value("tx").(*pop.Connection)
-	if !ok {
-		return nil, errors.WithStack(errors.New("no transaction found"))
+	repo, err := repository(c)
+	if err != nil {
+		return nil, err
 	}
-	conversation := models.Conversation{}
 
-	// Need to tell buffalo to "Eager" load all the objects contained
-	// in the conversation object.
 	// To find the Conversation the parameter conversation_id is used.
+	id, err := uuid.FromString(c.Param("conversation_id"))
+	if err != nil {
+		return nil, c.Error(404, err)
+	}
 
-	if err := tx.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotation").Find(&conversation, c.Param("conversation_id")); err != nil {
+	// the repository loads the quotes with their authors and annotations
+	conversation, err := repo.Conversations.Find(id)
+	if err != nil {
 		return nil, c.Error(404, err)
 	}
 
 	// I have not yet figured out how to detect a null pointer in
 	// my plush code embedded in the HTML.  Until I do, I build
-	// a list of strings that are either empty, or contain any
-	// annotations on a quote.
-
-	var notes []string
-	for _, quote := range conversation.Quotes {
-		if quote.Annotation != nil {
-			notes = append(notes, "* "+quote.Annotation.Note)
-		} else {
-			notes = append(notes, "")
+	// the annotations on each quote with the name of whoever
+	// wrote them already filled in.
+
+	notes := make([][]showNote, len(conversation.Quotes))
+	for i, quote := range conversation.Quotes {
+		for _, a := range quote.Annotations {
+			note := showNote{Kind: a.Kind, Note: a.Note}
+			if a.Author != nil {
+				note.By = a.Author.Name
+			}
+			notes[i] = append(notes[i], note)
 		}
 	}
 
 	c.Set("notes", notes)
 
-	return &conversation, nil
+	return conversation, nil
 }
 
 func (v ConversationsResource) loadForm(conversation *models.Conversation, c buffalo.Context) error {
-	// Get the DB connection from the context
-	tx, ok := c.Value("tx").(*pop.Connection)
+	repo, err := repository(c)
 
-	if !ok {
-		return errors.WithStack(errors.New("no transaction found"))
+	if err != nil {
+		return err
 	}
 
-	authors := []models.Author{}
-	annotation := models.Annotation{}
-
-	annotation.Note = ""
-
 	// Retrieve all Authors from the DB
-	if err := tx.Order("name").All(&authors); err != nil {
+	authors, err := repo.Authors.All()
+
+	if err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -419,6 +510,7 @@ func (v ConversationsResource) loadForm(conversation *models.Conversation, c buf
 
 	c.Set("conversation", conversation)
 	c.Set("authors", authors)
+	c.Set("kinds", models.AnnotationKinds)
 	c.Set("cvj", cvjson)
 
 	return nil
@@ -429,50 +521,20 @@ func (v ConversationsResource) bindToForm(c buffalo.Context) (*models.Conversati
 	conv := &models.Conversation{}
 
 	cvjson := c.Request().Form.Get("cvjson")
-	fmt.Printf("%v\n", cvjson)
+	c.Logger().Debugf("conversation json is %d bytes", len(cvjson))
 	err := conv.ExtractConversationFromJSON(cvjson)
 
 	if err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
 
-	fmt.Printf("conversation author = %s, %v\n", conv.Quotes[0].Author.Name, conv.Quotes[0].Author.ID)
+	c.Logger().Debugf("conversation has %d quotes", len(conv.Quotes))
 
 	option := c.Request().Form.Get("option")
 
 	return conv, &option, nil
 }
 
-// if there is an annotation, add it to the quote
-func attachAnnotation(quote *models.Quote, annotation *models.Annotation) error {
-
-	// get out the annotation value
-
-	if len(annotation.Note) == 0 {
-		quote.Annotation = nil
-		quote.AnnotationID = nil
-		return nil
-	}
-
-	quote.Annotation = annotation
-	err := quote.Annotation.FindByNote() // looks for the Note text in the database, no repeats!
-
-	if err != nil {
-		return err
-	}
-
-	// the quotes annotationID needs to either point to the annotation's id, or be nil
-	// can't use uuid.Nil because buffalo tries to write that to the database as
-	// a zero guid
-	if quote.Annotation.ID == uuid.Nil {
-		quote.AnnotationID = nil
-	} else {
-		quote.AnnotationID = &quote.Annotation.ID
-	}
-
-	return nil
-}
-
 // User wants to add an author to the database.  Save where we are and go do that.
 func (v ConversationsResource) addAuthor(conv *models.Conversation, c buffalo.Context) error {
 	author := &models.Author{}
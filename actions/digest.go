@@ -0,0 +1,197 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/mailers"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const digestTokenParam = "token"
+
+// the digest job runs daily at DIGEST_HOUR unless JOB_DIGEST says
+// otherwise, and the weekly one only goes out on DIGEST_WEEKDAY
+var digestHour = parseHour(envy.Get("DIGEST_HOUR", "7"))
+var digestWeekday = parseWeekday(envy.Get("DIGEST_WEEKDAY", "Monday"))
+
+// DigestUnsubscribe asks the user to confirm they want to stop the
+// digest.  Mail scanners follow links, so a plain GET never unsubscribes.
+// This function is mapped to the path GET /digest/unsubscribe/{token}
+func DigestUnsubscribe(c buffalo.Context) error {
+	user, err := loadDigestUser(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	c.Set("user", user)
+	c.Set("done", false)
+
+	return c.Render(200, r.HTML("digest/unsubscribe.html"))
+}
+
+// DigestUnsubscribeConfirm stops the digest.  Mail programs with a one
+// click unsubscribe button post here straight from the inbox, so the
+// token is all it takes.
+// This function is mapped to the path POST /digest/unsubscribe/{token}
+func DigestUnsubscribeConfirm(c buffalo.Context) error {
+	user, err := loadDigestUser(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if err := user.UnsubscribeDigest(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("user", user)
+	c.Set("done", true)
+	// the csrf middleware is skipped here, the layout still wants a token
+	c.Set("authenticity_token", "")
+
+	return c.Render(http.StatusOK, r.HTML("digest/unsubscribe.html"))
+}
+
+// loadDigestUser finds the user the unsubscribe link belongs to
+func loadDigestUser(c buffalo.Context) (*models.User, error) {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return nil, errors.WithStack(errors.New("no transaction found"))
+	}
+
+	user := &models.User{}
+
+	if err := user.FindByDigestToken(tx, c.Param(digestTokenParam)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// MailDigests sends the digests right now, for trying out the mail
+// setup.  With force set everyone subscribed gets one, due or not.
+func MailDigests(force bool) (int, error) {
+	return runDigests(models.DB, time.Now(), force)
+}
+
+// runDigests mails a digest to every subscriber who is due one.  Weekly
+// subscribers only get theirs on DIGEST_WEEKDAY, unless force is set.
+// One bad address doesn't stop everyone else's digest.
+func runDigests(tx *pop.Connection, now time.Time, force bool) (int, error) {
+	users := models.Users{}
+
+	if err := users.DigestSubscribers(tx); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	pick, err := pickOfTheDay(tx, today(), wallName, "")
+
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	sent := 0
+	var failed []string
+
+	for i := range users {
+		u := &users[i]
+
+		if !force && (!u.DigestDue(now) || (u.Digest == models.DigestWeekly && now.Weekday() != digestWeekday)) {
+			continue
+		}
+
+		d, err := buildDigest(tx, u, pick, now)
+
+		// nothing new and no quote of the day, try again next time
+		if err == nil && d.Empty() {
+			continue
+		}
+
+		if err == nil {
+			err = mailers.SendDigest(*d)
+		}
+
+		if err == nil {
+			err = u.DigestSent(tx, now)
+		}
+
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", u.Email, err.Error()))
+			continue
+		}
+
+		sent++
+	}
+
+	if len(failed) > 0 {
+		return sent, fmt.Errorf("%d digests failed, %s", len(failed), strings.Join(failed, "; "))
+	}
+
+	return sent, nil
+}
+
+// buildDigest gathers what is new since the user's last digest
+func buildDigest(tx *pop.Connection, u *models.User, pick *models.Conversation, now time.Time) (*mailers.Digest, error) {
+	d := &mailers.Digest{
+		User:           *u,
+		QuoteOfTheDay:  pick,
+		SiteURL:        digestSiteURL(),
+		UnsubscribeURL: fmt.Sprintf("%s/digest/unsubscribe/%s", digestSiteURL(), u.DigestToken),
+	}
+
+	if err := d.Conversations.PublishedSince(tx, u.DigestSince(now)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return d, nil
+}
+
+// digestSiteURL is where links in the email point.  There is no request
+// to take the host from, so FORUM_HOST has to be set for real mail.
+func digestSiteURL() string {
+	if host := envy.Get("FORUM_HOST", ""); len(host) > 0 {
+		return strings.TrimSuffix(host, "/")
+	}
+
+	return "http://127.0.0.1:" + envy.Get("PORT", "3000")
+}
+
+// parseHour reads an hour of the day, 0-23
+func parseHour(s string) int {
+	h, err := strconv.Atoi(strings.TrimSpace(s))
+
+	if err != nil || h < 0 || h > 23 {
+		return 7
+	}
+
+	return h
+}
+
+// parseWeekday understands the full or short english day names
+func parseWeekday(s string) time.Weekday {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d
+		}
+	}
+
+	return time.Monday
+}
@@ -0,0 +1,53 @@
+package actions
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_parseWeekday(t *testing.T) {
+	tests := map[string]time.Weekday{
+		"Monday":  time.Monday,
+		"fri":     time.Friday,
+		" SUNDAY": time.Sunday,
+		"someday": time.Monday,
+	}
+
+	for s, want := range tests {
+		if got := parseWeekday(s); got != want {
+			t.Errorf("parseWeekday(%q) got %s, wanted %s", s, got, want)
+		}
+	}
+
+	if parseHour("25") != 7 || parseHour("18") != 18 {
+		t.Errorf("parseHour didn't check the range")
+	}
+}
+
+func (as *ActionSuite) Test_DigestUnsubscribe() {
+	u := &models.User{Email: "reader@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+	as.NoError(u.SubscribeDigest(models.DB, models.DigestDaily))
+
+	res := as.HTML("/digest/unsubscribe/%s", u.DigestToken).Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "reader@example.com")
+
+	res = as.HTML("/digest/unsubscribe/%s", u.DigestToken).Post(nil)
+
+	as.Equal(http.StatusOK, res.Code)
+
+	found := &models.User{}
+	as.NoError(found.FindByDigestToken(models.DB, u.DigestToken))
+	as.Equal(models.DigestOff, found.Digest)
+
+	res = as.HTML("/digest/unsubscribe/nosuchtoken").Get()
+
+	as.Equal(http.StatusNotFound, res.Code)
+}
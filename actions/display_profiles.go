@@ -0,0 +1,177 @@
+package actions
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// Following naming logic is implemented in Buffalo:
+// Model: Singular (DisplayProfile)
+// DB Table: Plural (display_profiles)
+// Resource: Plural (DisplayProfiles)
+// Path: Plural (/display_profiles)
+// View Template Folder: Plural (/templates/display_profiles/)
+
+// DisplayProfilesResource is the resource for the DisplayProfile model
+type DisplayProfilesResource struct {
+	buffalo.Resource
+}
+
+// List gets all DisplayProfiles. This function is mapped to the path
+// GET /display_profiles
+func (v DisplayProfilesResource) List(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	profiles := &models.DisplayProfiles{}
+
+	if err := tx.Order("name").All(profiles); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("displayProfiles", profiles)
+
+	return c.Render(200, r.HTML("display_profiles/index.html"))
+}
+
+// Show gets the data for one DisplayProfile. This function is mapped to
+// the path GET /display_profiles/{display_profile_id}
+func (v DisplayProfilesResource) Show(c buffalo.Context) error {
+	return v.Edit(c)
+}
+
+// New renders the form for creating a new DisplayProfile.
+// This function is mapped to the path GET /display_profiles/new
+func (v DisplayProfilesResource) New(c buffalo.Context) error {
+	profile := models.DefaultDisplayProfile()
+	profile.Name = ""
+
+	c.Set("displayProfile", profile)
+
+	return c.Render(200, r.HTML("display_profiles/new.html"))
+}
+
+// Create adds a DisplayProfile to the DB. This function is mapped to the
+// path POST /display_profiles
+func (v DisplayProfilesResource) Create(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	profile := &models.DisplayProfile{}
+
+	// Bind profile to the html form elements
+	if err := c.Bind(profile); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndCreate(profile)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("displayProfile", profile)
+
+		// set the verification errors into the context and send back the profile
+		c.Set("errors", verrs)
+
+		return c.Render(422, r.HTML("display_profiles/new.html"))
+	}
+
+	c.Flash().Add("success", "Display profile was created successfully")
+
+	return c.Redirect(302, "/display_profiles")
+}
+
+// Edit renders a edit form for a DisplayProfile. This function is
+// mapped to the path GET /display_profiles/{display_profile_id}/edit
+func (v DisplayProfilesResource) Edit(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	profile := &models.DisplayProfile{}
+
+	if err := tx.Find(profile, c.Param("display_profile_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	c.Set("displayProfile", profile)
+
+	return c.Render(200, r.HTML("display_profiles/edit.html"))
+}
+
+// Update changes a DisplayProfile in the DB. This function is mapped to
+// the path PUT /display_profiles/{display_profile_id}
+func (v DisplayProfilesResource) Update(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	profile := &models.DisplayProfile{}
+
+	if err := tx.Find(profile, c.Param("display_profile_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	// Bind profile to the html form elements
+	if err := c.Bind(profile); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(profile)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("displayProfile", profile)
+
+		// set the verification errors into the context and send back the profile
+		c.Set("errors", verrs)
+
+		return c.Render(422, r.HTML("display_profiles/edit.html"))
+	}
+
+	c.Flash().Add("success", "Display profile was updated successfully")
+
+	return c.Redirect(302, "/display_profiles")
+}
+
+// Destroy deletes a DisplayProfile from the DB. This function is mapped
+// to the path DELETE /display_profiles/{display_profile_id}
+func (v DisplayProfilesResource) Destroy(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	profile := &models.DisplayProfile{}
+
+	if err := tx.Find(profile, c.Param("display_profile_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := tx.Destroy(profile); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Display profile was destroyed successfully")
+
+	return c.Redirect(302, "/display_profiles")
+}
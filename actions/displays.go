@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/google/uuid"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// DisplaysResource lets an admin see and control the displays
+// that are streaming the wall.
+type DisplaysResource struct {
+	buffalo.Resource
+}
+
+// List shows the connected displays and what they are showing.
+// This function is mapped to the path GET /displays
+func (v DisplaysResource) List(c buffalo.Context) error {
+	c.Set("displays", hub.list())
+
+	pinned := ""
+	if id := hub.pinnedID(); id != nil {
+		pinned = id.String()
+	}
+	c.Set("pinned", pinned)
+
+	return c.Render(200, r.HTML("displays/index.html"))
+}
+
+// Advance moves every display on to its next conversation.
+// This function is mapped to the path POST /displays/advance
+func (v DisplaysResource) Advance(c buffalo.Context) error {
+	hub.advance()
+
+	c.Flash().Add("success", "Displays advanced")
+
+	return c.Redirect(302, "/displays")
+}
+
+// Pin puts one conversation on every display.
+// This function is mapped to the path POST /displays/pin
+func (v DisplaysResource) Pin(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	id, err := uuid.Parse(c.Param("conversation_id"))
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// make sure he exists before showing him everywhere
+	conv := &models.Conversation{}
+	if err := tx.Eager("Quotes").Find(conv, id); err != nil {
+		return c.Error(404, err)
+	}
+
+	// the walls are public, so no drafts and nothing empty
+	if !conv.Publish || len(conv.Quotes) == 0 {
+		c.Flash().Add("danger", "Only a published conversation with quotes can be pinned")
+		return c.Redirect(302, "/displays")
+	}
+
+	hub.pin(id)
+
+	c.Flash().Add("success", "Conversation pinned to all displays")
+
+	return c.Redirect(302, "/displays")
+}
+
+// Unpin lets the displays go back to shuffling.
+// This function is mapped to the path POST /displays/unpin
+func (v DisplaysResource) Unpin(c buffalo.Context) error {
+	hub.unpin()
+
+	c.Flash().Add("success", "Displays unpinned")
+
+	return c.Redirect(302, "/displays")
+}
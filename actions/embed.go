@@ -0,0 +1,229 @@
+package actions
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const embedKeyParam = "embed_key"
+
+// embedhtml is the page that goes in an iframe on somebody else's site.
+// It is kept small so it fits in a dashboard tile.
+const embedhtml = `<!DOCTYPE html><html lang="en">
+<head><meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}" />
+<title>{{.Title}}</title>
+</head>
+<body style="margin:0; color:{{.TextColor}}; background-color:{{.BackgroundColor}}; font-family:{{.FontFamily}}">
+{{$page := .}}
+{{if $page.ShowContext}}{{if $page.Context}}<div style="text-align:center; color:{{$page.SpeakerColor}}">{{$page.Context}}</div>{{end}}{{end}}
+{{range .Conversation}}
+<p style="text-align:center; font-size:1.5em; margin:0.5em">{{range $i, $line := .Lines}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p>
+{{if $page.ShowSpeaker}}<div style="text-align:right; color:{{$page.SpeakerColor}}">{{.Name}}</div>{{end}}
+{{if $page.ShowDate}}<div style="text-align:right; color:{{$page.SpeakerColor}}">{{.Date}}</div>{{end}}
+{{if $page.ShowNote}}<div style="text-align:center; white-space:pre-line; color:{{$page.NoteColor}}">{{.Comment}}</div>{{end}}
+{{end}}
+</body></html>`
+
+// embedjs finds every <div class="quotewall" data-key="..."> on the page
+// and fills it with a quote.  It only uses textContent so nothing in a
+// quote can run on the host page.
+const embedjs = `(function() {
+	var base = %q;
+
+	function add(el, text, align) {
+		if (!text) {
+			return;
+		}
+		var d = document.createElement("div");
+		d.style.textAlign = align;
+		d.style.whiteSpace = "pre-line";
+		d.textContent = text;
+		el.appendChild(d);
+	}
+
+	function show(el, page) {
+		while (el.firstChild) {
+			el.removeChild(el.firstChild);
+		}
+		if (page.ShowContext) {
+			add(el, page.Context, "center");
+		}
+		page.Conversation.forEach(function(q) {
+			var p = document.createElement("p");
+			(q.Lines || [q.Quote]).forEach(function(line, i) {
+				if (i > 0) {
+					p.appendChild(document.createElement("br"));
+				}
+				p.appendChild(document.createTextNode(line));
+			});
+			el.appendChild(p);
+			if (page.ShowSpeaker) {
+				add(el, q.Name, "right");
+			}
+			if (page.ShowDate) {
+				add(el, q.Date, "right");
+			}
+			if (page.ShowNote) {
+				add(el, q.Comment, "center");
+			}
+		});
+	}
+
+	var tiles = document.querySelectorAll("div.quotewall[data-key]");
+	Array.prototype.forEach.call(tiles, function(el) {
+		var xhr = new XMLHttpRequest();
+		xhr.open("GET", base + "/embed/" + encodeURIComponent(el.getAttribute("data-key")) + ".json");
+		xhr.onload = function() {
+			if (xhr.status === 200) {
+				show(el, JSON.parse(xhr.responseText));
+			}
+		};
+		xhr.send();
+	});
+})();
+`
+
+// Embed shows a random quote in a page meant for an iframe.
+// This function is mapped to the path GET /embed/{embed_key}
+func (v ConversationsResource) Embed(c buffalo.Context) error {
+	key, err := loadEmbedKey(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// only the allowed sites can frame the page
+	ancestors := strings.Join(key.OriginList(), " ")
+	if len(ancestors) == 0 {
+		ancestors = "'none'"
+	}
+	c.Response().Header().Set("Content-Security-Policy", "frame-ancestors "+ancestors)
+
+	page, err := embedPage(c, key)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	templ := template.Must(template.New("embed").Parse(embedhtml))
+
+	return c.Render(200, render.Func("text/html", func(w io.Writer, d render.Data) error {
+		return templ.Execute(w, page)
+	}))
+}
+
+// EmbedJSON returns a random quote for the embed script.  Browsers
+// only get to read it from the allowed origins.
+// This function is mapped to the path GET /embed/{embed_key}.json
+func (v ConversationsResource) EmbedJSON(c buffalo.Context) error {
+	key, err := loadEmbedKey(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := allowOrigin(c, key); err != nil {
+		return c.Error(http.StatusForbidden, err)
+	}
+
+	page, err := embedPage(c, key)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	return c.Render(200, r.JSON(page))
+}
+
+// EmbedPreflight answers the browser's CORS preflight check.
+// This function is mapped to the path OPTIONS /embed/{embed_key}.json
+func (v ConversationsResource) EmbedPreflight(c buffalo.Context) error {
+	key, err := loadEmbedKey(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := allowOrigin(c, key); err != nil {
+		return c.Error(http.StatusForbidden, err)
+	}
+
+	c.Response().Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	c.Response().Header().Set("Access-Control-Max-Age", "86400")
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// EmbedScript serves the javascript that fills in embeds on other pages.
+// This function is mapped to the path GET /embed.js
+func (v ConversationsResource) EmbedScript(c buffalo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+
+	return c.Render(200, render.Func("application/javascript", func(w io.Writer, d render.Data) error {
+		_, err := fmt.Fprintf(w, embedjs, siteURL(c))
+		return err
+	}))
+}
+
+// loadEmbedKey looks up the embed named on the request
+func loadEmbedKey(c buffalo.Context) (*models.EmbedKey, error) {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return nil, errors.WithStack(errors.New("no transaction found"))
+	}
+
+	key := &models.EmbedKey{Key: c.Param(embedKeyParam)}
+
+	if err := key.FindByKey(tx); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// allowOrigin sets the CORS headers if the calling site is allowed to
+// use the embed.  Requests without an Origin aren't from another site's
+// page, so they are left alone.
+func allowOrigin(c buffalo.Context, key *models.EmbedKey) error {
+	origin := c.Request().Header.Get("Origin")
+
+	if len(origin) == 0 {
+		return nil
+	}
+
+	if !key.AllowsOrigin(origin) {
+		return fmt.Errorf("origin %s not allowed for embed %s", origin, key.Name)
+	}
+
+	c.Response().Header().Set("Access-Control-Allow-Origin", origin)
+	c.Response().Header().Add("Vary", "Origin")
+
+	return nil
+}
+
+// embedPage picks a quote the same way the quickie wall does, with the
+// embed's filters locked on
+func embedPage(c buffalo.Context, key *models.EmbedKey) (*pageParams, error) {
+	rq := newRequest(c)
+	defer rq.LogMetrics()
+
+	rq.locked = key.FilterValues()
+
+	page, err := rq.randomPage()
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return page, nil
+}
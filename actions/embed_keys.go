@@ -0,0 +1,186 @@
+package actions
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// Following naming logic is implemented in Buffalo:
+// Model: Singular (EmbedKey)
+// DB Table: Plural (embed_keys)
+// Resource: Plural (EmbedKeys)
+// Path: Plural (/embed_keys)
+// View Template Folder: Plural (/templates/embed_keys/)
+
+// EmbedKeysResource is the resource for the EmbedKey model
+type EmbedKeysResource struct {
+	buffalo.Resource
+}
+
+// List gets all EmbedKeys. This function is mapped to the path
+// GET /embed_keys
+func (v EmbedKeysResource) List(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	keys := &models.EmbedKeys{}
+
+	if err := tx.Order("name").All(keys); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("embedKeys", keys)
+
+	return c.Render(200, r.HTML("embed_keys/index.html"))
+}
+
+// Show gets the data for one EmbedKey. This function is mapped to
+// the path GET /embed_keys/{embed_key_id}
+func (v EmbedKeysResource) Show(c buffalo.Context) error {
+	return v.Edit(c)
+}
+
+// New renders the form for creating a new EmbedKey.
+// This function is mapped to the path GET /embed_keys/new
+func (v EmbedKeysResource) New(c buffalo.Context) error {
+	key, err := models.NewEmbedKey()
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("embedKey", key)
+
+	return c.Render(200, r.HTML("embed_keys/new.html"))
+}
+
+// Create adds an EmbedKey to the DB. This function is mapped to the
+// path POST /embed_keys
+func (v EmbedKeysResource) Create(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	key, err := models.NewEmbedKey()
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Bind key to the html form elements
+	if err := c.Bind(key); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndCreate(key)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("embedKey", key)
+
+		// set the verification errors into the context and send back the key
+		c.Set("errors", verrs)
+
+		return c.Render(422, r.HTML("embed_keys/new.html"))
+	}
+
+	c.Flash().Add("success", "Embed key was created successfully")
+
+	return c.Redirect(302, "/embed_keys")
+}
+
+// Edit renders an edit form for an EmbedKey. This function is
+// mapped to the path GET /embed_keys/{embed_key_id}/edit
+func (v EmbedKeysResource) Edit(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	key := &models.EmbedKey{}
+
+	if err := tx.Find(key, c.Param("embed_key_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	c.Set("embedKey", key)
+	c.Set("siteURL", siteURL(c))
+
+	return c.Render(200, r.HTML("embed_keys/edit.html"))
+}
+
+// Update changes an EmbedKey in the DB. This function is mapped to
+// the path PUT /embed_keys/{embed_key_id}
+func (v EmbedKeysResource) Update(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	key := &models.EmbedKey{}
+
+	if err := tx.Find(key, c.Param("embed_key_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	// Bind key to the html form elements
+	if err := c.Bind(key); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(key)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("embedKey", key)
+
+		// set the verification errors into the context and send back the key
+		c.Set("errors", verrs)
+		c.Set("siteURL", siteURL(c))
+
+		return c.Render(422, r.HTML("embed_keys/edit.html"))
+	}
+
+	c.Flash().Add("success", "Embed key was updated successfully")
+
+	return c.Redirect(302, "/embed_keys")
+}
+
+// Destroy deletes an EmbedKey from the DB. This function is mapped
+// to the path DELETE /embed_keys/{embed_key_id}
+func (v EmbedKeysResource) Destroy(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	key := &models.EmbedKey{}
+
+	if err := tx.Find(key, c.Param("embed_key_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := tx.Destroy(key); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Embed key was destroyed successfully")
+
+	return c.Redirect(302, "/embed_keys")
+}
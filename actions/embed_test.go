@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/navionguy/quotewall/models"
+)
+
+func (as *ActionSuite) Test_Embed() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	key, err := models.NewEmbedKey()
+	as.NoError(err)
+	key.Name = "dashboard"
+	key.AllowedOrigins = "https://dash.example.com"
+	as.NoError(as.DB.Create(key))
+
+	res := as.HTML("/embed/%s", key.Key).Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Equal("frame-ancestors https://dash.example.com", res.Header().Get("Content-Security-Policy"))
+
+	req := as.JSON("/embed/%s.json", key.Key)
+	req.Headers["Origin"] = "https://dash.example.com"
+	jres := req.Get()
+
+	as.Equal(http.StatusOK, jres.Code)
+	as.Equal("https://dash.example.com", jres.Header().Get("Access-Control-Allow-Origin"))
+
+	req = as.JSON("/embed/%s.json", key.Key)
+	req.Headers["Origin"] = "https://evil.example.com"
+	jres = req.Get()
+
+	as.Equal(http.StatusForbidden, jres.Code)
+
+	res = as.HTML("/embed/nosuchkey").Get()
+
+	as.Equal(http.StatusNotFound, res.Code)
+}
+
+func (as *ActionSuite) Test_EmbedFiltersLocked() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	key, err := models.NewEmbedKey()
+	as.NoError(err)
+	key.Name = "shari"
+	key.Filters = "speaker=Shari"
+	as.NoError(as.DB.Create(key))
+
+	// the page the embed sits on can't change what the key locked on
+	for i := 0; i < 5; i++ {
+		res := as.JSON("/embed/%s.json?speaker=George&before=01/01/1990", key.Key).Get()
+		as.Equal(http.StatusOK, res.Code)
+
+		page := pageParams{}
+		as.NoError(json.Unmarshal(res.Body.Bytes(), &page))
+		as.Len(page.Conversation, 1)
+		as.Equal("Shari Freeman", page.Conversation[0].Name)
+	}
+}
+
+func (as *ActionSuite) Test_SpeakerFilterIsAValue() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	// the speaker gets matched as a name, not run as SQL
+	res := as.HTML("/quickie?speaker=%s", url.QueryEscape("' OR 1=1 OR a.name LIKE '")).Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "No quotes on that wall!")
+}
+
+func (as *ActionSuite) Test_SpeakerFilterIgnoresCase() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	// the same match the quote of the day makes
+	res := as.HTML("/quickie?speaker=sHARI").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "Shari Freeman")
+}
+
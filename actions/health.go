@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+)
+
+// build info, filled in with -ldflags "-X github.com/navionguy/quotewall/actions.Version=..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// how long the readiness checks get before the instance counts as not ready
+const readyTimeout = 2 * time.Second
+
+// buildInfo is what /version reports
+type buildInfo struct {
+	Version       string               `json:"version"`
+	Commit        string               `json:"commit"`
+	BuildTime     string               `json:"build_time"`
+	Go            string               `json:"go"`
+	SchemaVersion string               `json:"schema_version"`
+	Archive       models.ArchiveCounts `json:"archive"`
+}
+
+// Healthz says the process is up.  Nothing else gets checked, so a slow
+// database never gets a healthy container restarted.
+// This function is mapped to the path GET /healthz
+func Healthz(c buffalo.Context) error {
+	return c.Render(http.StatusOK, r.JSON(map[string]string{"status": "ok"}))
+}
+
+// Readyz says whether the instance can take traffic: the database
+// answers, every migration it was built with has run, and there is a
+// shuffle to pick quotes from
+// This function is mapped to the path GET /readyz
+func Readyz(c buffalo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readyTimeout)
+	defer cancel()
+
+	checks := readyChecks(ctx)
+	status, code := "ok", http.StatusOK
+
+	for _, result := range checks {
+		if result != "ok" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+
+	return c.Render(code, r.JSON(map[string]interface{}{"status": status, "checks": checks}))
+}
+
+// readyChecks runs each check, stopping after the database if it isn't
+// there to ask
+func readyChecks(ctx context.Context) map[string]string {
+	db := models.DB.WithContext(ctx)
+	checks := map[string]string{}
+
+	if err := models.Ping(db); err != nil {
+		checks["database"] = err.Error()
+		return checks
+	}
+	checks["database"] = "ok"
+
+	pending, err := models.PendingMigrations(db)
+
+	switch {
+	case err != nil:
+		checks["migrations"] = err.Error()
+	case len(pending) > 0:
+		checks["migrations"] = fmt.Sprintf("%d pending: %s", len(pending), strings.Join(pending, ", "))
+	default:
+		checks["migrations"] = "ok"
+	}
+
+	checks["shuffle"] = shuffleCheck(db)
+
+	return checks
+}
+
+// shuffleCheck wants a shuffle to exist, unless there is nothing to
+// shuffle yet
+func shuffleCheck(db *pop.Connection) string {
+	size, err := models.ShuffleSize(db)
+
+	if err != nil {
+		return err.Error()
+	}
+
+	if size > 0 {
+		return "ok"
+	}
+
+	count, err := db.Count(&models.Conversation{})
+
+	if err != nil {
+		return err.Error()
+	}
+
+	if count > 0 {
+		return "shuffle is empty"
+	}
+
+	return "ok"
+}
+
+// VersionShow reports what is running and what it is running against
+// This function is mapped to the path GET /version
+func VersionShow(c buffalo.Context) error {
+	info := buildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		Go:        runtime.Version(),
+	}
+
+	var err error
+
+	if info.SchemaVersion, err = models.SchemaVersion(models.DB); err != nil {
+		return countDBError("version", err)
+	}
+
+	if info.Archive, err = models.CountArchive(models.DB); err != nil {
+		return countDBError("version", err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(info))
+}
@@ -0,0 +1,45 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (as *ActionSuite) Test_Healthz() {
+	res := as.JSON("/healthz").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), `"ok"`)
+}
+
+func (as *ActionSuite) Test_Readyz() {
+	res := as.JSON("/readyz").Get()
+
+	body := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &body))
+
+	// nothing to shuffle yet is fine
+	as.Equal("ok", body.Checks["database"])
+	as.Equal("ok", body.Checks["shuffle"])
+	as.Contains(body.Checks, "migrations")
+
+	if body.Checks["migrations"] == "ok" {
+		as.Equal(http.StatusOK, res.Code)
+	} else {
+		as.Equal(http.StatusServiceUnavailable, res.Code)
+	}
+}
+
+func (as *ActionSuite) Test_Version() {
+	as.LoadFixture("test conversations")
+
+	res := as.JSON("/version").Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	info := buildInfo{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &info))
+	as.Equal(Version, info.Version)
+	as.True(info.Archive.Conversations > 0)
+}
@@ -0,0 +1,408 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const sizeParam = "size" // pick an image preset by name
+const defaultPreset = "social"
+
+// imagePreset is one of the image sizes we know how to draw
+type imagePreset struct {
+	Width  int
+	Height int
+	Gray   bool // e-ink screens can only show shades of gray
+}
+
+var imagePresets = map[string]imagePreset{
+	"social": {Width: 1200, Height: 630},
+	"1080p":  {Width: 1920, Height: 1080},
+	"eink":   {Width: 800, Height: 480, Gray: true},
+}
+
+// a few of the css color names people are likely to put in a profile
+var namedColors = map[string]color.RGBA{
+	"black":  {0, 0, 0, 255},
+	"white":  {255, 255, 255, 255},
+	"red":    {255, 0, 0, 255},
+	"green":  {0, 128, 0, 255},
+	"blue":   {0, 0, 255, 255},
+	"yellow": {255, 255, 0, 255},
+	"orange": {255, 165, 0, 255},
+	"purple": {128, 0, 128, 255},
+	"gray":   {128, 128, 128, 255},
+	"grey":   {128, 128, 128, 255},
+	"navy":   {0, 0, 128, 255},
+	"maroon": {128, 0, 0, 255},
+	"silver": {192, 192, 192, 255},
+}
+
+// the go fonts are built in, so rendering doesn't depend on what is
+// installed on the server
+var quoteFont *opentype.Font
+var fontOnce sync.Once
+var fontErr error
+
+// faces hold a buffer inside, so each image gets its own set
+type faceCache map[int]font.Face
+
+// textLine is one line of text placed on the image
+type textLine struct {
+	Text  string
+	Size  int
+	Color string
+	Align string // left, center or right, same as the wall
+	Y     int    // baseline
+}
+
+// ConversationPNG draws a published conversation as a png image.
+// This function is mapped to the path GET /conversations/{conversation_id}.png
+func (v ConversationsResource) ConversationPNG(c buffalo.Context) error {
+	return v.conversationImage(c, "image/png", renderPNG)
+}
+
+// ConversationSVG draws a published conversation as an svg image.
+// This function is mapped to the path GET /conversations/{conversation_id}.svg
+func (v ConversationsResource) ConversationSVG(c buffalo.Context) error {
+	return v.conversationImage(c, "image/svg+xml", renderSVG)
+}
+
+// QuickiePNG draws the next conversation off the quickie wall.  He
+// accepts all the same filters as QuickieQuote.
+// This function is mapped to the path GET /quickie.png
+func (v ConversationsResource) QuickiePNG(c buffalo.Context) error {
+	preset, err := pickPreset(c)
+
+	if err != nil {
+		return c.Error(400, err)
+	}
+
+	rq := newRequest(c)
+	defer rq.LogMetrics()
+
+	page, err := rq.imagePage(preset)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-cache")
+
+	return c.Render(200, render.Func("image/png", func(w io.Writer, d render.Data) error {
+		return renderPNG(w, page, preset)
+	}))
+}
+
+// imagePage picks a conversation for an image, laid out for the size of
+// the image instead of the display.  Every image is a fresh pick.
+func (rq *quickieRequest) imagePage(preset imagePreset) (*pageParams, error) {
+	rq.size = &screenSize{Width: preset.Width, Height: preset.Height}
+
+	return rq.randomPage()
+}
+
+// conversationImage loads the conversation and lays it out like the wall
+func (v ConversationsResource) conversationImage(c buffalo.Context, contentType string, paint func(io.Writer, *pageParams, imagePreset) error) error {
+	preset, err := pickPreset(c)
+
+	if err != nil {
+		return c.Error(400, err)
+	}
+
+	conversation, err := v.loadConversation(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// these get posted in public, so no peeking at unpublished ones
+	if !conversation.Publish || len(conversation.Quotes) == 0 {
+		return c.Error(404, errors.New("conversation not found"))
+	}
+
+	rq := newRequest(c)
+	page := prepareConv(*conversation, rq.profile, screenSize{Width: preset.Width, Height: preset.Height})
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=3600")
+
+	return c.Render(200, render.Func(contentType, func(w io.Writer, d render.Data) error {
+		return paint(w, &page, preset)
+	}))
+}
+
+// pickPreset finds the image size asked for on the request
+func pickPreset(c buffalo.Context) (imagePreset, error) {
+	name := c.Param(sizeParam)
+
+	if len(name) == 0 {
+		name = defaultPreset
+	}
+
+	preset, ok := imagePresets[name]
+
+	if !ok {
+		return preset, fmt.Errorf("unknown image size %s", name)
+	}
+
+	return preset, nil
+}
+
+// layoutImage works out where every line of the page goes.  The quotes
+// come out centered with the speaker and date under them on the right,
+// and the note centered below that, same as the wall.
+func layoutImage(page *pageParams, preset imagePreset) []textLine {
+	var lines []textLine
+
+	add := func(text string, size int, col, align string) {
+		lines = append(lines, textLine{Text: text, Size: size, Color: col, Align: align})
+	}
+
+	width := int(float64(preset.Width) * usableWidth)
+
+	if page.ShowContext && len(page.Context) > 0 {
+		for _, l := range wrapPhrase(page.Context, charsPerLine(width, attributionSize)) {
+			add(l, attributionSize, page.SpeakerColor, "center")
+		}
+	}
+
+	for _, q := range page.Conversation {
+		size := q.FontSize
+		if size == 0 {
+			size = page.FontSize
+		}
+
+		qlines := q.Lines
+		if len(qlines) == 0 {
+			qlines = wrapPhrase(q.Quote, charsPerLine(width, size))
+		}
+
+		for _, l := range qlines {
+			add(l, size, page.TextColor, "center")
+		}
+
+		if page.ShowSpeaker && len(q.Name) > 0 {
+			add(q.Name, attributionSize, page.SpeakerColor, "right")
+		}
+		if page.ShowDate && len(q.Date) > 0 {
+			add(q.Date, attributionSize, page.SpeakerColor, "right")
+		}
+		if page.ShowNote && len(q.Comment) > 0 {
+			for _, l := range wrapPhrase(q.Comment, charsPerLine(width, attributionSize)) {
+				add(l, attributionSize, page.NoteColor, "center")
+			}
+		}
+	}
+
+	// center the whole block top to bottom
+	total := 0
+	for _, l := range lines {
+		total += int(float64(l.Size) * lineHeight)
+	}
+
+	top := (preset.Height - total) / 2
+	if top < 0 {
+		top = 0
+	}
+
+	// the baseline sits about 4/5 of the way down the glyphs
+	for i := range lines {
+		h := float64(lines[i].Size) * lineHeight
+		lines[i].Y = top + int((h-float64(lines[i].Size))/2+0.8*float64(lines[i].Size))
+		top += int(h)
+	}
+
+	return lines
+}
+
+// renderPNG draws the page onto an image and encodes it
+func renderPNG(w io.Writer, page *pageParams, preset imagePreset) error {
+	img := image.NewRGBA(image.Rect(0, 0, preset.Width, preset.Height))
+	bg := parseColor(page.BackgroundColor, color.White)
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	margin := int(float64(preset.Width) * (1 - usableWidth) / 2)
+	avail := preset.Width - 2*margin
+	faces := faceCache{}
+
+	for _, l := range layoutImage(page, preset) {
+		face, err := faces.get(l.Size)
+
+		if err != nil {
+			return err
+		}
+
+		d := &font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(parseColor(l.Color, color.Black)),
+			Face: face,
+		}
+
+		// the layout guesses at widths, if it guessed short shrink the line
+		width := d.MeasureString(l.Text).Ceil()
+		if width > avail {
+			if d.Face, err = faces.get(l.Size * avail / width); err != nil {
+				return err
+			}
+			width = d.MeasureString(l.Text).Ceil()
+		}
+
+		x := margin
+		switch l.Align {
+		case "center":
+			x = (preset.Width - width) / 2
+		case "right":
+			x = preset.Width - margin - width
+		}
+
+		d.Dot = fixed.P(x, l.Y)
+		d.DrawString(l.Text)
+	}
+
+	if !preset.Gray {
+		return png.Encode(w, img)
+	}
+
+	gray := image.NewGray(img.Bounds())
+	draw.Draw(gray, gray.Bounds(), img, image.Point{}, draw.Src)
+
+	return png.Encode(w, gray)
+}
+
+// renderSVG writes the page out as svg text.  The viewer does the font
+// work, so only the lines and their positions come from the layout.
+func renderSVG(w io.Writer, page *pageParams, preset imagePreset) error {
+	var b bytes.Buffer
+
+	margin := int(float64(preset.Width) * (1 - usableWidth) / 2)
+	family := page.FontFamily
+	if len(family) == 0 {
+		family = "serif"
+	}
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		preset.Width, preset.Height, preset.Width, preset.Height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgAttr(svgColor(page.BackgroundColor, "white", preset.Gray)))
+
+	for _, l := range layoutImage(page, preset) {
+		x, anchor := margin, "start"
+		switch l.Align {
+		case "center":
+			x, anchor = preset.Width/2, "middle"
+		case "right":
+			x, anchor = preset.Width-margin, "end"
+		}
+
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="%s" font-size="%d" fill="%s" text-anchor="%s">`,
+			x, l.Y, svgAttr(family), l.Size, svgAttr(svgColor(l.Color, "black", preset.Gray)), anchor)
+		if err := xml.EscapeText(&b, []byte(l.Text)); err != nil {
+			return err
+		}
+		b.WriteString("</text>\n")
+	}
+
+	b.WriteString("</svg>\n")
+
+	_, err := b.WriteTo(w)
+
+	return err
+}
+
+// svgAttr escapes a value for use inside an attribute
+func svgAttr(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// svgColor passes css colors through, unless the image has to be gray
+func svgColor(s, fallback string, gray bool) string {
+	if len(s) == 0 {
+		s = fallback
+	}
+
+	if !gray {
+		return s
+	}
+
+	g := color.GrayModel.Convert(parseColor(s, color.Black)).(color.Gray)
+
+	return fmt.Sprintf("#%02x%02x%02x", g.Y, g.Y, g.Y)
+}
+
+// get returns the quote font at size pixels
+func (faces faceCache) get(size int) (font.Face, error) {
+	fontOnce.Do(func() {
+		quoteFont, fontErr = opentype.Parse(goregular.TTF)
+	})
+
+	if fontErr != nil {
+		return nil, fontErr
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	if face, ok := faces[size]; ok {
+		return face, nil
+	}
+
+	face, err := opentype.NewFace(quoteFont, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+
+	if err != nil {
+		return nil, err
+	}
+
+	faces[size] = face
+
+	return face, nil
+}
+
+// parseColor understands #rgb, #rrggbb and a few color names.  Anything
+// else gets the fallback.
+func parseColor(s string, fallback color.Color) color.Color {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if c, ok := namedColors[s]; ok {
+		return c
+	}
+
+	if !strings.HasPrefix(s, "#") {
+		return fallback
+	}
+
+	hex := s[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	if len(hex) != 6 {
+		return fallback
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+
+	if err != nil {
+		return fallback
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+}
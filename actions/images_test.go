@@ -0,0 +1,201 @@
+package actions
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/navionguy/quotewall/models"
+)
+
+func testImagePage(preset imagePreset) *pageParams {
+	conv := models.Conversation{Quotes: models.Quotes{
+		{Phrase: "I never said that.", Author: models.Author{Name: "Shari"}},
+		{Phrase: "You said it twice & meant it <both> times.", Author: models.Author{Name: "Jeff"}},
+	}}
+
+	page := prepareConv(conv, models.DefaultDisplayProfile(), screenSize{Width: preset.Width, Height: preset.Height})
+
+	return &page
+}
+
+func Test_ParseColor(t *testing.T) {
+	tests := []struct {
+		inp  string
+		want color.Color
+	}{
+		{"blue", color.RGBA{0, 0, 255, 255}},
+		{" Black ", color.RGBA{0, 0, 0, 255}},
+		{"#102030", color.RGBA{0x10, 0x20, 0x30, 255}},
+		{"#fff", color.RGBA{255, 255, 255, 255}},
+		{"#12", color.White},
+		{"chartreuse-ish", color.White},
+	}
+
+	for _, tt := range tests {
+		if got := parseColor(tt.inp, color.White); got != tt.want {
+			t.Errorf("parseColor(%s) got %v, wanted %v", tt.inp, got, tt.want)
+		}
+	}
+}
+
+func Test_RenderPNG(t *testing.T) {
+	for name, preset := range imagePresets {
+		var b bytes.Buffer
+
+		if err := renderPNG(&b, testImagePage(preset), preset); err != nil {
+			t.Fatalf("renderPNG %s: %s", name, err.Error())
+		}
+
+		img, err := png.Decode(&b)
+
+		if err != nil {
+			t.Fatalf("renderPNG %s made a bad png: %s", name, err.Error())
+		}
+
+		if img.Bounds() != image.Rect(0, 0, preset.Width, preset.Height) {
+			t.Errorf("renderPNG %s got size %v", name, img.Bounds())
+		}
+
+		if _, gray := img.(*image.Gray); gray != preset.Gray {
+			t.Errorf("renderPNG %s grayscale was %t, wanted %t", name, gray, preset.Gray)
+		}
+	}
+}
+
+func Test_RenderSVG(t *testing.T) {
+	preset := imagePresets["eink"]
+
+	var b bytes.Buffer
+
+	if err := renderSVG(&b, testImagePage(preset), preset); err != nil {
+		t.Fatalf("renderSVG: %s", err.Error())
+	}
+
+	svg := b.String()
+
+	for _, want := range []string{`width="800"`, "I never said that.", "meant it &lt;both&gt;", "Shari", `fill="#1d1d1d"`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("renderSVG didn't include %s\n%s", want, svg)
+		}
+	}
+}
+
+func Test_LayoutImageParagraphs(t *testing.T) {
+	preset := imagePresets["eink"]
+	page := &pageParams{FontSize: 40, ShowNote: true, Conversation: []quoteType{
+		{Quote: "Well.\n\nThat went well.", Comment: "at the retro\nafter the outage"},
+	}}
+
+	var got []string
+	for _, l := range layoutImage(page, preset) {
+		got = append(got, l.Text)
+	}
+
+	want := []string{"Well.", "", "That went well.", "at the retro", "after the outage"}
+
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("layoutImage got %q, wanted %q", got, want)
+	}
+}
+
+func Test_LayoutImageContext(t *testing.T) {
+	conv := models.Conversation{Meeting: "Retro", Location: "Room 4", Quotes: models.Quotes{
+		{Phrase: "Well.", Author: models.Author{Name: "Shari"}, Annotations: models.Annotations{
+			{Kind: models.AnnotationContext, Note: "after the outage"},
+			{Kind: models.AnnotationAside, Note: "it did not"},
+		}},
+	}}
+
+	prof := models.DefaultDisplayProfile()
+	prof.ShowContext = true
+
+	preset := imagePresets["eink"]
+	page := prepareConv(conv, prof, screenSize{Width: preset.Width, Height: preset.Height})
+
+	if page.Context != "Retro · Room 4" || page.Conversation[0].Comment != "after the outage\nit did not" {
+		t.Fatalf("prepareConv got context %q, comment %q", page.Context, page.Conversation[0].Comment)
+	}
+
+	lines := layoutImage(&page, preset)
+
+	if len(lines) == 0 || lines[0].Text != "Retro · Room 4" {
+		t.Errorf("layoutImage didn't start with the context, got %v", lines)
+	}
+
+	page.ShowContext = false
+
+	if lines = layoutImage(&page, preset); lines[0].Text == "Retro · Room 4" {
+		t.Errorf("layoutImage showed the context when the profile hides it")
+	}
+}
+
+func (as *ActionSuite) Test_ConversationImages() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	id := "e682fe38-23f4-4410-8d67-bdcc7f3782cb"
+
+	res := as.HTML("/conversations/%s.png", id).Get()
+
+	as.Equal(200, res.Code)
+	as.Equal("image/png", res.Header().Get("Content-Type"))
+
+	res = as.HTML("/conversations/%s.svg?size=1080p", id).Get()
+
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), `width="1920"`)
+
+	res = as.HTML("/conversations/%s.png?size=poster", id).Get()
+
+	as.Equal(400, res.Code)
+}
+
+func (as *ActionSuite) Test_QuickieImageSize() {
+	author := &models.Author{Name: "Shari"}
+	as.NoError(models.DB.Create(author))
+
+	now := time.Now()
+	conv := &models.Conversation{OccurredOn: now, Publish: true, Quotes: models.Quotes{{
+		Phrase:   "If the build is green on my machine and red on yours, then clearly your machine needs to be replaced with one more like mine.",
+		SaidOn:   now,
+		Publish:  true,
+		AuthorID: author.ID,
+	}}}
+	verrs, err := models.NewPopRepository(models.DB).Conversations.Create(conv)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	pages := map[string]*pageParams{}
+
+	a := buffalo.New(buffalo.Options{})
+	a.Use(transaction())
+	a.GET("/quickie.png", func(c buffalo.Context) error {
+		page, err := newRequest(c).imagePage(imagePresets[c.Param(sizeParam)])
+		if err != nil {
+			return err
+		}
+
+		pages[c.Param(sizeParam)] = page
+
+		return c.Render(http.StatusOK, r.String("done"))
+	})
+
+	for _, size := range []string{"eink", "1080p"} {
+		a.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/quickie.png?"+sizeParam+"="+size, nil))
+		as.Require().NotNil(pages[size], size)
+	}
+
+	// the quote gets laid out for the size of the image
+	eink, hd := pages["eink"].Conversation[0], pages["1080p"].Conversation[0]
+	as.NotEqual(len(eink.Lines), len(hd.Lines))
+	as.Greater(hd.FontSize, eink.FontSize)
+}
@@ -0,0 +1,61 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// how many past runs the job status page lists
+const jobRunsShown = 50
+
+// JobsList shows each scheduled job, when it runs next and how it went
+// This function is mapped to the path GET /admin/jobs
+func JobsList(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	latest := models.JobRuns{}
+
+	if err := countDBError("jobs", latest.Latest(tx)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	recent := models.JobRuns{}
+
+	if err := countDBError("jobs", recent.Recent(tx, jobRunsShown)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("jobs", jobs.statuses(latest))
+	c.Set("runs", recent)
+
+	return c.Render(200, r.HTML("admin/jobs.html"))
+}
+
+// JobsRun starts a job right away.  It runs in the background, the
+// status page shows how it went.
+// This function is mapped to the path POST /admin/jobs/{job}/run
+func JobsRun(c buffalo.Context) error {
+	name := c.Param("job")
+
+	if _, ok := jobs.find(name); !ok {
+		return c.Error(http.StatusNotFound, errJobUnknown)
+	}
+
+	go func() {
+		if _, err := jobs.run(name, time.Now()); err != nil && err != errJobRunning {
+			log.Errorf("job %s: %s", name, err.Error())
+		}
+	}()
+
+	c.Flash().Add("success", "Started "+name)
+
+	return c.Redirect(302, "/admin/jobs")
+}
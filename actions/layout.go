@@ -0,0 +1,174 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const resolutionParam = "resolution" // screen size written as 1920x1080
+
+// The browser does the real rendering, so the layout works from rough
+// proportions of a serif font rather than real glyph metrics.
+const glyphWidth = 0.55    // average character width, as a share of the font size
+const lineHeight = 1.2     // line spacing, as a share of the font size
+const usableWidth = 0.9    // share of the screen width the quotes can use
+const usableHeight = 0.8   // share of the screen height left after the title
+const attributionSize = 24 // px used by each speaker, date or note line
+
+// screenSize is the resolution of the display showing the quotes
+type screenSize struct {
+	Width  int
+	Height int
+}
+
+// quoteLayout is how one quote should be drawn
+type quoteLayout struct {
+	FontSize int
+	Lines    []string
+}
+
+// parseResolution reads a resolution like "1920x1080".  If it doesn't
+// make sense, the fallback is returned.
+func parseResolution(res string, fallback screenSize) screenSize {
+	var scr screenSize
+
+	_, err := fmt.Sscanf(strings.ToLower(res), "%dx%d", &scr.Width, &scr.Height)
+
+	if err != nil || scr.Width < 1 || scr.Height < 1 {
+		return fallback
+	}
+
+	return scr
+}
+
+// fitConversation picks a font size and line breaks for every phrase so
+// the whole conversation fits on the screen.  The conversation first gets
+// the largest size everyone can share, then each quote grows into its
+// share of the height, so a one liner next to a long speech isn't stuck
+// at the same small size.  extra is the number of attribution lines
+// drawn under each quote.
+func fitConversation(phrases []string, scr screenSize, fontMin, fontMax, extra int) []quoteLayout {
+	if len(phrases) == 0 {
+		return nil
+	}
+
+	if fontMin < 1 {
+		fontMin = 1
+	}
+	if fontMax < fontMin {
+		fontMax = fontMin
+	}
+
+	width := int(float64(scr.Width) * usableWidth)
+	height := int(float64(scr.Height)*usableHeight - float64(len(phrases)*extra)*attributionSize*lineHeight)
+
+	// find the biggest size the whole conversation fits in
+	shared := fontMin
+	for size := fontMax; size > fontMin; size-- {
+		if conversationHeight(phrases, width, size) <= height {
+			shared = size
+			break
+		}
+	}
+
+	total := conversationHeight(phrases, width, shared)
+	layouts := make([]quoteLayout, len(phrases))
+
+	for i, phrase := range phrases {
+		size := shared
+		mine := quoteHeight(phrase, width, shared)
+
+		// hand out the height in proportion to what each quote needs
+		share := height
+		if total > 0 {
+			share = height * mine / total
+		}
+
+		for bigger := fontMax; bigger > shared; bigger-- {
+			if quoteHeight(phrase, width, bigger) <= share {
+				size = bigger
+				break
+			}
+		}
+
+		layouts[i] = quoteLayout{FontSize: size, Lines: wrapPhrase(phrase, charsPerLine(width, size))}
+	}
+
+	return layouts
+}
+
+// conversationHeight is how tall all the phrases are at size
+func conversationHeight(phrases []string, width, size int) int {
+	height := 0
+	for _, phrase := range phrases {
+		height += quoteHeight(phrase, width, size)
+	}
+	return height
+}
+
+// quoteHeight is how tall one phrase is at size
+func quoteHeight(phrase string, width, size int) int {
+	lines := len(wrapPhrase(phrase, charsPerLine(width, size)))
+	return int(float64(lines*size) * lineHeight)
+}
+
+// noteLines is how many attribution lines a note takes across scr, a note
+// can have paragraphs of its own
+func noteLines(note string, scr screenSize) int {
+	width := int(float64(scr.Width) * usableWidth)
+	return len(wrapPhrase(note, charsPerLine(width, attributionSize)))
+}
+
+// charsPerLine estimates how many characters fit across width at size
+func charsPerLine(width, size int) int {
+	n := int(float64(width) / (float64(size) * glyphWidth))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// wrapPhrase breaks a phrase into lines of no more than width characters.
+// Line breaks already in the phrase are kept, and words too long for a
+// line get split.
+func wrapPhrase(phrase string, width int) []string {
+	var lines []string
+
+	for _, para := range strings.Split(phrase, "\n") {
+		line := ""
+
+		for _, word := range strings.Fields(para) {
+			for utf8.RuneCountInString(word) > width {
+				if len(line) > 0 {
+					lines = append(lines, line)
+					line = ""
+				}
+				r := []rune(word)
+				lines = append(lines, string(r[:width]))
+				word = string(r[width:])
+			}
+
+			if len(word) == 0 {
+				continue
+			}
+
+			if len(line) == 0 {
+				line = word
+				continue
+			}
+
+			if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+
+			line += " " + word
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,85 @@
+package actions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_ParseResolution(t *testing.T) {
+	fallback := screenSize{Width: 800, Height: 600}
+
+	tests := []struct {
+		inp  string
+		want screenSize
+	}{
+		{"1920x1080", screenSize{Width: 1920, Height: 1080}},
+		{"800X480", screenSize{Width: 800, Height: 480}},
+		{"", fallback},
+		{"wide", fallback},
+		{"0x100", fallback},
+	}
+
+	for _, tt := range tests {
+		if got := parseResolution(tt.inp, fallback); got != tt.want {
+			t.Errorf("parseResolution(%s) got %v, wanted %v", tt.inp, got, tt.want)
+		}
+	}
+}
+
+func Test_WrapPhrase(t *testing.T) {
+	tests := []struct {
+		inp   string
+		width int
+		want  []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"first\nsecond", 20, []string{"first", "second"}},
+		{"first\n\nsecond", 20, []string{"first", "", "second"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"abcdefgh", 4, []string{"abcd", "efgh"}},
+	}
+
+	for _, tt := range tests {
+		if got := wrapPhrase(tt.inp, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapPhrase(%q, %d) got %q, wanted %q", tt.inp, tt.width, got, tt.want)
+		}
+	}
+}
+
+func Test_FitConversation(t *testing.T) {
+	scr := screenSize{Width: 1920, Height: 1080}
+
+	// a short quote gets the biggest font
+	fit := fitConversation([]string{"Hi."}, scr, 24, 64, 3)
+
+	if len(fit) != 1 || fit[0].FontSize != 64 {
+		t.Fatalf("fitConversation short quote got %v", fit)
+	}
+
+	// a long speech has to shrink, and still fit on the screen
+	long := strings.Repeat("blah blah blah ", 100)
+	fit = fitConversation([]string{long, "Yes."}, scr, 24, 64, 3)
+
+	if fit[0].FontSize >= 64 {
+		t.Fatalf("fitConversation didn't shrink a long quote, got %d", fit[0].FontSize)
+	}
+
+	// but the one liner next to it doesn't have to be as small
+	if fit[1].FontSize < fit[0].FontSize {
+		t.Fatalf("fitConversation short reply got %d, smaller than %d", fit[1].FontSize, fit[0].FontSize)
+	}
+
+	width := int(float64(scr.Width) * usableWidth)
+	used := quoteHeight(long, width, fit[0].FontSize) + quoteHeight("Yes.", width, fit[1].FontSize)
+
+	if used > int(float64(scr.Height)*usableHeight) {
+		t.Fatalf("fitConversation used %d pixels of %d", used, scr.Height)
+	}
+
+	// nothing in, nothing out
+	if fitConversation(nil, scr, 24, 64, 3) != nil {
+		t.Fatal("fitConversation laid out an empty conversation")
+	}
+}
@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/navionguy/quotewall/logging"
+)
+
+// log is for logging outside of a request, inside one use c.Logger() so
+// the line carries the request id
+var log = logging.For("actions")
+
+// requestIDHeader brings in the request id from a proxy, and sends it back
+const requestIDHeader = "X-Request-ID"
+
+// an id from a proxy is only used if it looks like one
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// logRequests takes the place of buffalo's request logger.  It logs one
+// line for every request with its id, route, status and how long it took.
+// SetCurrentUser adds the user when there is one.
+func logRequests(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		rid := requestID(c.Request().Header.Get(requestIDHeader))
+
+		c.Set("request_id", rid)
+		c.LogField("request_id", rid)
+		c.Response().Header().Set(requestIDHeader, rid)
+
+		start := time.Now()
+		err := next(c)
+
+		c.LogFields(map[string]interface{}{
+			"method":     c.Request().Method,
+			"path":       c.Request().URL.Path,
+			"route":      routePath(c),
+			"status":     responseStatus(c, err),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		})
+
+		if err != nil {
+			c.LogField("error", err.Error())
+		}
+
+		c.Logger().Info("request")
+
+		return err
+	}
+}
+
+// requestID keeps the id the proxy gave the request, or makes one up
+func requestID(given string) string {
+	if validRequestID.MatchString(given) {
+		return given
+	}
+
+	b := make([]byte, 8)
+
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// LoggingShow lists each package's log level
+// This function is mapped to the path GET /admin/logging
+func LoggingShow(c buffalo.Context) error {
+	c.Set("levels", logging.Levels())
+	c.Set("choices", logLevelChoices)
+
+	return c.Render(http.StatusOK, r.HTML("admin/logging.html"))
+}
+
+// the levels the admin page offers, Default puts a package back to
+// following the default
+var logLevelChoices = []string{logging.Default, "debug", "info", "warning", "error"}
+
+// LoggingUpdate changes a package's log level, until the app restarts
+// This function is mapped to the path POST /admin/logging
+func LoggingUpdate(c buffalo.Context) error {
+	pkg, level := c.Param("Package"), c.Param("Level")
+
+	if err := logging.SetLevel(pkg, level); err != nil {
+		c.Flash().Add("danger", err.Error())
+		return c.Redirect(302, "/admin/logging")
+	}
+
+	c.Logger().Infof("log level for %s set to %s", pkg, level)
+	c.Flash().Add("success", "Logging for "+pkg+" is now "+level)
+
+	return c.Redirect(302, "/admin/logging")
+}
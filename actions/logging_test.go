@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/navionguy/quotewall/logging"
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_requestID(t *testing.T) {
+	if got := requestID("proxy-id.42"); got != "proxy-id.42" {
+		t.Errorf("kept id got %q", got)
+	}
+
+	for _, given := range []string{"", "has spaces", "semi;colon", strings.Repeat("x", 65)} {
+		if got := requestID(given); got == given || len(got) != 16 {
+			t.Errorf("requestID(%q) got %q", given, got)
+		}
+	}
+}
+
+func (as *ActionSuite) Test_RequestIDHeader() {
+	req := as.HTML("/healthz")
+	req.Headers[requestIDHeader] = "from-the-proxy"
+
+	res := req.Get()
+	as.Equal("from-the-proxy", res.Header().Get(requestIDHeader))
+
+	res = as.HTML("/healthz").Get()
+	as.Len(res.Header().Get(requestIDHeader), 16)
+}
+
+func (as *ActionSuite) Test_Logging() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	as.NoError(u.Grant(models.DB, models.PermissionAdmin))
+	as.signInAs(u)
+	as.enableTwoFactor(u)
+
+	res := as.HTML("/admin/logging").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), logging.Default)
+
+	res = as.HTML("/admin/logging").Post(map[string]string{"Package": "test_admin", "Level": "debug"})
+	as.Equal(http.StatusFound, res.Code)
+	defer logging.SetLevel("test_admin", logging.Default)
+
+	found := false
+	for _, l := range logging.Levels() {
+		if l.Package == "test_admin" {
+			found = l.Own && l.Level == "debug"
+		}
+	}
+	as.True(found)
+}
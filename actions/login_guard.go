@@ -0,0 +1,118 @@
+package actions
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/navionguy/quotewall/models"
+)
+
+const currentSessionKey = "user_session_id"
+
+// failures in a row before the lockouts start.  An address can be shared
+// by a whole office, so it gets more room than a single account.
+const accountLockoutAfter = 5
+const ipLockoutAfter = 20
+const resetLockoutAfter = 5
+
+// sessionLifetime is how long a sign in lasts, set with SESSION_LIFETIME
+// as a Go duration like 72h
+var sessionLifetime = parseLifetime(envy.Get("SESSION_LIFETIME", ""))
+
+// only believe X-Forwarded-For when running behind a proxy that sets it
+var trustProxy = envy.Get("TRUST_PROXY", "false") == "true"
+
+// parseLifetime turns the setting into a duration, two weeks unless it
+// makes sense
+func parseLifetime(s string) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+
+	if err != nil || d < time.Minute {
+		return 14 * 24 * time.Hour
+	}
+
+	return d
+}
+
+// clientIP is the address the request came from
+func clientIP(c buffalo.Context) string {
+	req := c.Request()
+
+	if trustProxy {
+		if fwd := req.Header.Get("X-Forwarded-For"); len(fwd) > 0 {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// lockedOut checks both the account and the address the attempt came
+// from, returning the later of the two lockouts
+func lockedOut(c buffalo.Context, email string, now time.Time) (time.Time, bool, error) {
+	until, locked, err := models.LockedOut(models.DB, models.ScopeIP, clientIP(c), now)
+
+	if err != nil || locked {
+		return until, locked, err
+	}
+
+	return models.LockedOut(models.DB, models.ScopeAccount, normalEmail(email), now)
+}
+
+// recordFailure counts a bad password against the account and the address.
+// The request transaction gets rolled back, so it's written to the database
+// once that's over.
+func recordFailure(c buffalo.Context, email string, now time.Time) {
+	ip := clientIP(c)
+
+	afterTransaction(c, func() {
+		if _, err := models.RecordLoginFailure(models.DB, models.ScopeIP, ip, ipLockoutAfter, now); err != nil {
+			c.Logger().Errorf("login failure for %s: %s", ip, err.Error())
+		}
+
+		if len(normalEmail(email)) == 0 {
+			return
+		}
+
+		if _, err := models.RecordLoginFailure(models.DB, models.ScopeAccount, normalEmail(email), accountLockoutAfter, now); err != nil {
+			c.Logger().Errorf("login failure for %s: %s", email, err.Error())
+		}
+	})
+}
+
+// clearFailures forgets the failures against the account, once the sign
+// in that earned it has been committed
+func clearFailures(c buffalo.Context, email string) {
+	afterCommit(c, func() {
+		if err := models.ClearLoginFailures(models.DB, models.ScopeAccount, normalEmail(email)); err != nil {
+			c.Logger().Errorf("clearing login failures for %s: %s", email, err.Error())
+		}
+	})
+}
+
+// logLogin adds to the sign in log, a broken log never stops a sign in.
+// Failed sign ins get rolled back, so it waits until the transaction is
+// over too.
+func logLogin(c buffalo.Context, event string, u *models.User, email string) {
+	ip, agent := clientIP(c), c.Request().UserAgent()
+
+	afterTransaction(c, func() {
+		if err := models.LogLoginEvent(models.DB, event, u, email, ip, agent); err != nil {
+			c.Logger().Errorf("login event %s for %s: %s", event, email, err.Error())
+		}
+	})
+}
+
+// normalEmail is how email addresses get compared
+func normalEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
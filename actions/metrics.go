@@ -0,0 +1,209 @@
+package actions
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds everything /metrics reports
+var metrics = prometheus.NewRegistry()
+
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "quotewall_http_request_duration_seconds",
+	Help:    "How long requests took, by route.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "method", "code"})
+
+var quickiePicks = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "quotewall_quickie_picks_total",
+	Help: "Quotes picked for the wall, by whether one was found.",
+}, []string{"result"})
+
+var shuffleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "quotewall_shuffle_duration_seconds",
+	Help:    "How long reshuffling the conversations took.",
+	Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+})
+
+var dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "quotewall_db_errors_total",
+	Help: "Database errors, by what was being done.",
+}, []string{"op"})
+
+var activeDisplays = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+	Name: "quotewall_active_displays",
+	Help: "Wall displays connected to the live stream.",
+}, func() float64 {
+	return float64(len(hub.list()))
+})
+
+var metricsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "quotewall_metric_log_dropped_total",
+	Help: "Request timings and shows not written to the database because the queue was full.",
+})
+
+func init() {
+	metrics.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		requestDuration,
+		quickiePicks,
+		shuffleDuration,
+		dbErrors,
+		activeDisplays,
+		metricsDropped,
+	)
+}
+
+// metricsToken, when set with METRICS_TOKEN, has to be sent as a bearer
+// token to read /metrics
+var metricsToken = envy.Get("METRICS_TOKEN", "")
+
+// Metrics reports in the Prometheus text format
+// This function is mapped to the path GET /metrics
+func Metrics(c buffalo.Context) error {
+	if len(metricsToken) > 0 {
+		got := c.Request().Header.Get("Authorization")
+
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+metricsToken)) != 1 {
+			c.Response().Header().Set("WWW-Authenticate", "Bearer")
+			return c.Error(http.StatusUnauthorized, errors.New("metrics need a token"))
+		}
+	}
+
+	promhttp.HandlerFor(metrics, promhttp.HandlerOpts{}).ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+// RecordMetrics times every request against the route that matched it
+func RecordMetrics(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		requestDuration.WithLabelValues(routePath(c), c.Request().Method, strconv.Itoa(responseStatus(c, err))).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// routePath is the route that matched the request, not the path asked for
+func routePath(c buffalo.Context) string {
+	if ri, ok := c.Value("current_route").(buffalo.RouteInfo); ok {
+		return ri.Path
+	}
+
+	return "unmatched"
+}
+
+// responseStatus is the status the request ends up with, the error
+// handlers haven't written it yet when a handler returned an error
+func responseStatus(c buffalo.Context, err error) int {
+	if err != nil {
+		if he, ok := errors.Cause(err).(buffalo.HTTPError); ok {
+			return he.Status
+		}
+
+		return http.StatusInternalServerError
+	}
+
+	if res, ok := c.Response().(*buffalo.Response); ok && res.Status != 0 {
+		return res.Status
+	}
+
+	return http.StatusOK
+}
+
+// countDBError counts err against op, not finding a row isn't an error
+func countDBError(op string, err error) error {
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		dbErrors.WithLabelValues(op).Inc()
+	}
+
+	return err
+}
+
+// metricLog keeps filling the requests and conversation_shows tables in
+// the background, turn it off with METRIC_LOG=false
+var metricLog = newMetricSink(envy.Get("METRIC_LOG", "true") == "true", 1000)
+
+// metricSink writes request timings to log_metric(), and what each display
+// showed, off the request path.  When the database falls behind they get
+// dropped, not requests.
+type metricSink struct {
+	enabled bool
+	queue   chan metricWrite
+	once    sync.Once
+	pending sync.WaitGroup // writes queued and not done yet
+}
+
+// metricWrite is one queued up write, op names it for the error count
+type metricWrite struct {
+	op    string
+	write func(db *pop.Connection) error
+}
+
+func newMetricSink(enabled bool, size int) *metricSink {
+	return &metricSink{enabled: enabled, queue: make(chan metricWrite, size)}
+}
+
+// log queues up a request timing in milliseconds
+func (s *metricSink) log(elapsed int64) {
+	s.push(metricWrite{op: "log_metric", write: func(db *pop.Connection) error {
+		return models.LogMetric(db, elapsed)
+	}})
+}
+
+// shown queues up a count of the display showing the conversation
+func (s *metricSink) shown(display string, id uuid.UUID) {
+	now := time.Now()
+
+	s.push(metricWrite{op: "record_show", write: func(db *pop.Connection) error {
+		return models.RecordShow(db, display, id, now)
+	}})
+}
+
+func (s *metricSink) push(w metricWrite) {
+	if !s.enabled {
+		return
+	}
+
+	s.once.Do(func() { go s.run() })
+
+	s.pending.Add(1)
+
+	select {
+	case s.queue <- w:
+	default:
+		s.pending.Done()
+		metricsDropped.Inc()
+	}
+}
+
+// wait returns once everything queued so far has been written
+func (s *metricSink) wait() {
+	s.pending.Wait()
+}
+
+func (s *metricSink) run() {
+	for w := range s.queue {
+		if err := countDBError(w.op, w.write(models.DB)); err != nil {
+			log.Errorf("metric log %s: %s", w.op, err.Error())
+		}
+		s.pending.Done()
+	}
+}
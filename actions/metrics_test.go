@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Metrics(t *testing.T) {
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	res := httptest.NewRecorder()
+	App().ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("/metrics got %d, wanted 200", res.Code)
+	}
+
+	// the first request is timed once it finishes, so ask again
+	res = httptest.NewRecorder()
+	App().ServeHTTP(res, req)
+
+	for _, want := range []string{
+		`quotewall_http_request_duration_seconds_count{code="200",method="GET",route="/metrics/"}`,
+		"quotewall_active_displays 0",
+		"go_goroutines",
+	} {
+		if !strings.Contains(res.Body.String(), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
+
+func Test_Metrics_Token(t *testing.T) {
+	metricsToken = "sekrit"
+	defer func() { metricsToken = "" }()
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	res := httptest.NewRecorder()
+	App().ServeHTTP(res, req)
+
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("/metrics without the token got %d, wanted 401", res.Code)
+	}
+
+	req.Header.Set("Authorization", "Bearer sekrit")
+	res = httptest.NewRecorder()
+	App().ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Errorf("/metrics with the token got %d, wanted 200", res.Code)
+	}
+}
+
+func Test_countDBError(t *testing.T) {
+	before := testutil.ToFloat64(dbErrors.WithLabelValues("test"))
+
+	countDBError("test", nil)
+	countDBError("test", errors.WithStack(sql.ErrNoRows))
+	countDBError("test", errors.New("connection refused"))
+
+	if got := testutil.ToFloat64(dbErrors.WithLabelValues("test")) - before; got != 1 {
+		t.Errorf("countDBError counted %v errors, wanted 1", got)
+	}
+}
+
+func Test_metricSink(t *testing.T) {
+	before := testutil.ToFloat64(metricsDropped)
+
+	off := newMetricSink(false, 1)
+	off.log(10)
+	off.log(10)
+
+	if len(off.queue) != 0 {
+		t.Errorf("a disabled sink queued %d timings", len(off.queue))
+	}
+
+	// keep the writer from starting so the queue fills up
+	full := newMetricSink(true, 1)
+	full.once.Do(func() {})
+	full.log(10)
+	full.log(20)
+
+	if len(full.queue) != 1 {
+		t.Errorf("sink queued %d timings, wanted 1", len(full.queue))
+	}
+
+	if got := testutil.ToFloat64(metricsDropped) - before; got != 1 {
+		t.Errorf("sink dropped %v timings, wanted 1", got)
+	}
+}
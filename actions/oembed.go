@@ -0,0 +1,206 @@
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const providerName = "Quote Wall"
+const maxDescription = 200 // chat apps cut off long descriptions anyway
+const embedWidth = 600
+const embedHeight = 315
+
+// conversation links look like /conversations/{id}, and the id may have
+// come through wrapped in braces
+var conversationURL = regexp.MustCompile(`/conversations/(?:%7[bB]|\{)?([0-9a-fA-F-]{36})`)
+
+// shareCard is what a chat app shows when someone pastes a conversation link
+type shareCard struct {
+	Title       string
+	Description string
+	URL         string
+	Image       string
+	ImageWidth  int
+	ImageHeight int
+	OEmbed      string
+}
+
+// oembedResponse follows the rich type from https://oembed.com
+type oembedResponse struct {
+	Type            string `json:"type"`
+	Version         string `json:"version"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailWidth  int    `json:"thumbnail_width"`
+	ThumbnailHeight int    `json:"thumbnail_height"`
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(
+	`<blockquote class="quotewall-embed" style="max-width:{{.Width}}px">` +
+		`{{range .Quotes}}<p style="white-space:pre-line">{{.Phrase}}</p><footer>{{.Author.Name}}</footer>{{end}}` +
+		`<a href="{{.URL}}">{{.Provider}}</a></blockquote>`))
+
+// OEmbed describes a published conversation so chat apps can embed it.
+// This function is mapped to the path GET /oembed
+func OEmbed(c buffalo.Context) error {
+	if f := c.Param("format"); len(f) > 0 && f != "json" {
+		return c.Error(http.StatusNotImplemented, fmt.Errorf("format %s not supported", f))
+	}
+
+	id, err := conversationFromURL(c.Param("url"))
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	conversation := &models.Conversation{}
+
+	if err := tx.Eager("Quotes.Author").Find(conversation, id); err != nil {
+		return c.Error(404, err)
+	}
+
+	// unpublished conversations don't exist as far as the world knows
+	if !conversation.Publish || len(conversation.Quotes) == 0 {
+		return c.Error(404, errors.New("conversation not found"))
+	}
+
+	card := newShareCard(c, conversation)
+	width := limitParam(c.Param("maxwidth"), embedWidth)
+	height := limitParam(c.Param("maxheight"), embedHeight)
+
+	var html bytes.Buffer
+	err = embedTemplate.Execute(&html, struct {
+		Width    int
+		Quotes   models.Quotes
+		URL      string
+		Provider string
+	}{width, conversation.Quotes, card.URL, providerName})
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Render(200, r.JSON(oembedResponse{
+		Type:            "rich",
+		Version:         "1.0",
+		Title:           card.Title,
+		AuthorName:      card.Title,
+		ProviderName:    providerName,
+		ProviderURL:     siteURL(c),
+		HTML:            html.String(),
+		Width:           width,
+		Height:          height,
+		ThumbnailURL:    card.Image,
+		ThumbnailWidth:  card.ImageWidth,
+		ThumbnailHeight: card.ImageHeight,
+	}))
+}
+
+// newShareCard fills in the unfurl details for a conversation
+func newShareCard(c buffalo.Context, conv *models.Conversation) *shareCard {
+	var names []string
+	var phrases []string
+
+	for _, q := range conv.Quotes {
+		if !containsString(names, q.Author.Name) {
+			names = append(names, q.Author.Name)
+		}
+		phrases = append(phrases, fmt.Sprintf("“%s” — %s", q.Phrase, q.Author.Name))
+	}
+
+	// the link goes to the page anyone can see, the image sits next to it
+	page := fmt.Sprintf("%s/conversations/%s", siteURL(c), conv.ID)
+	link := page + "/share"
+	preset := imagePresets[defaultPreset]
+
+	return &shareCard{
+		Title:       strings.Join(names, ", "),
+		Description: truncate(strings.Join(phrases, " "), maxDescription),
+		URL:         link,
+		Image:       fmt.Sprintf("%s.png?%s=%s", page, sizeParam, defaultPreset),
+		ImageWidth:  preset.Width,
+		ImageHeight: preset.Height,
+		OEmbed:      fmt.Sprintf("%s/oembed?url=%s&format=json", siteURL(c), url.QueryEscape(link)),
+	}
+}
+
+// conversationFromURL pulls the conversation id out of a shared link
+func conversationFromURL(link string) (string, error) {
+	m := conversationURL.FindStringSubmatch(link)
+
+	if m == nil {
+		return "", fmt.Errorf("%s is not a conversation link", link)
+	}
+
+	return strings.ToLower(m[1]), nil
+}
+
+// siteURL is where people reach the quote wall from outside
+func siteURL(c buffalo.Context) string {
+	if host := envy.Get("FORUM_HOST", ""); len(host) > 0 {
+		return strings.TrimSuffix(host, "/")
+	}
+
+	req := c.Request()
+	scheme := "http"
+	if req.TLS != nil || req.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + req.Host
+}
+
+// limitParam lets the consumer ask for something smaller than max
+func limitParam(p string, max int) int {
+	var n int
+
+	if _, err := fmt.Sscanf(p, "%d", &n); err != nil || n < 1 || n > max {
+		return max
+	}
+
+	return n
+}
+
+// truncate shortens s to at most n characters, marking the cut
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	r := []rune(s)
+
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func Test_ConversationFromURL(t *testing.T) {
+	id := "e682fe38-23f4-4410-8d67-bdcc7f3782cb"
+
+	tests := []struct {
+		inp  string
+		want string
+		ok   bool
+	}{
+		{"https://quotes.example.com/conversations/" + id, id, true},
+		{"http://localhost:3000/conversations/%7BE682FE38-23F4-4410-8D67-BDCC7F3782CB%7D", id, true},
+		{"/conversations/{" + id + "}/edit", id, true},
+		{"https://quotes.example.com/authors/" + id, "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := conversationFromURL(tt.inp)
+
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("conversationFromURL(%s) got %s, %v", tt.inp, got, err)
+		}
+	}
+}
+
+func Test_Truncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate changed a short string to %s", got)
+	}
+
+	if got := truncate("“a long quote”", 6); got != "“a lo…" {
+		t.Errorf("truncate got %s", got)
+	}
+}
+
+func (as *ActionSuite) Test_OEmbed() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	link := "http://localhost/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb"
+
+	res := as.JSON("/oembed?url=%s&maxwidth=400", link).Get()
+
+	as.Equal(http.StatusOK, res.Code)
+
+	var embed oembedResponse
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &embed))
+	as.Equal("rich", embed.Type)
+	as.Equal(400, embed.Width)
+	as.Contains(embed.ThumbnailURL, ".png")
+
+	res = as.JSON("/oembed?url=%s&format=xml", link).Get()
+	as.Equal(http.StatusNotImplemented, res.Code)
+
+	// unpublished conversations don't embed
+	as.NoError(as.DB.RawQuery("UPDATE conversations SET publish = FALSE").Exec())
+
+	res = as.JSON("/oembed?url=%s", link).Get()
+	as.Equal(http.StatusNotFound, res.Code)
+}
+
+func (as *ActionSuite) Test_ConversationShare() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	res := as.HTML("/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb/share").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+
+	body := res.Body.String()
+	as.Contains(body, `/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb/share">`)
+	as.Contains(body, `/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb.png?size=`)
+	as.Contains(body, "application/json+oembed")
+	as.Contains(body, "George P. Burdell")
+	as.NotContains(body, "/displays/pin")
+
+	// the link the card hands out embeds too
+	embed := as.JSON("/oembed?url=%s", "http://localhost/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb/share").Get()
+	as.Equal(http.StatusOK, embed.Code)
+
+	// unpublished conversations aren't there to share
+	as.NoError(as.DB.RawQuery("UPDATE conversations SET publish = FALSE").Exec())
+
+	res = as.HTML("/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb/share").Get()
+	as.Equal(http.StatusNotFound, res.Code)
+}
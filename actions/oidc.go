@@ -0,0 +1,181 @@
+package actions
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// what the sign-on round trip keeps in the session
+const oidcStateKey = "oidc_state"
+const oidcNonceKey = "oidc_nonce"
+const oidcVerifierKey = "oidc_verifier"
+
+// OIDCStart sends the browser to the identity provider.  The state,
+// nonce and PKCE verifier stay behind in the session to check the
+// answer against.
+// This function is mapped to the path GET /auth/oidc
+func OIDCStart(c buffalo.Context) error {
+	if !sso.cfg.enabled() {
+		return c.Error(http.StatusNotFound, errors.New("single sign-on is not set up"))
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	nonce, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	verifier, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	to, err := sso.authURL(oidcRedirectURL(c), state, nonce, verifier)
+
+	if err != nil {
+		c.Logger().Errorf("single sign-on: %s", err.Error())
+		c.Flash().Add("danger", "Single sign-on isn't working right now, sign in with your password")
+		return c.Redirect(302, "/signin")
+	}
+
+	c.Session().Set(oidcStateKey, state)
+	c.Session().Set(oidcNonceKey, nonce)
+	c.Session().Set(oidcVerifierKey, verifier)
+
+	return c.Redirect(302, to)
+}
+
+// OIDCCallback is where the provider sends the browser back to.  The
+// code gets swapped for an ID token, which picks the user to sign in.
+// This function is mapped to the path GET /auth/oidc/callback
+func OIDCCallback(c buffalo.Context) error {
+	if !sso.cfg.enabled() {
+		return c.Error(http.StatusNotFound, errors.New("single sign-on is not set up"))
+	}
+
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	state, _ := c.Session().Get(oidcStateKey).(string)
+	nonce, _ := c.Session().Get(oidcNonceKey).(string)
+	verifier, _ := c.Session().Get(oidcVerifierKey).(string)
+
+	// each round trip can only be finished once
+	c.Session().Delete(oidcStateKey)
+	c.Session().Delete(oidcNonceKey)
+	c.Session().Delete(oidcVerifierKey)
+
+	failed := func(email, why string) error {
+		c.Logger().Errorf("single sign-on: %s", why)
+		logLogin(c, models.LoginFailed, nil, email)
+		c.Flash().Add("danger", "Single sign-on failed: "+why)
+
+		return c.Redirect(302, "/signin")
+	}
+
+	if e := c.Param("error"); len(e) > 0 {
+		return failed("", "the provider said "+e)
+	}
+
+	if len(state) == 0 || subtle.ConstantTimeCompare([]byte(state), []byte(c.Param("state"))) != 1 {
+		return failed("", "the sign-on request didn't match, try again")
+	}
+
+	idToken, err := sso.exchange(oidcRedirectURL(c), c.Param("code"), verifier)
+
+	if err != nil {
+		return failed("", err.Error())
+	}
+
+	claims, err := sso.verify(idToken, nonce, time.Now())
+
+	if err != nil {
+		return failed("", err.Error())
+	}
+
+	u, err := oidcUser(tx, sso.cfg, claims)
+
+	if err != nil {
+		return failed(claims.Email, err.Error())
+	}
+
+	if len(sso.cfg.Roles) > 0 {
+		if err := u.SyncPermissions(tx, sso.cfg.managedRoles(), sso.cfg.rolesFor(claims.Groups)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return signIn(c, u, "Welcome back!")
+}
+
+// oidcUser finds the account for the ID token.  A subject seen before
+// goes straight to its account, otherwise a verified email address links
+// up with an existing account, otherwise one gets made.
+func oidcUser(tx *pop.Connection, cfg oidcConfig, claims *oidcClaims) (*models.User, error) {
+	u := &models.User{}
+
+	err := u.FindByOIDC(tx, cfg.Issuer, claims.Subject)
+
+	if err == nil {
+		return u, nil
+	}
+
+	if errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(claims.Email) == 0 {
+		return nil, errors.New("the provider didn't share an email address")
+	}
+
+	err = u.FindByEmail(tx, claims.Email)
+
+	switch {
+	case err == nil && !bool(claims.EmailVerified):
+		return nil, errors.New("the provider hasn't verified " + claims.Email)
+	case err == nil && len(u.OIDCSubject) > 0:
+		return nil, errors.New(claims.Email + " is already linked to another sign-on account")
+	case err == nil:
+		return u, errors.WithStack(u.LinkOIDC(tx, cfg.Issuer, claims.Subject))
+	case errors.Cause(err) != sql.ErrNoRows:
+		return nil, errors.WithStack(err)
+	case !cfg.Provision:
+		return nil, errors.New("there is no account for " + claims.Email)
+	}
+
+	u = &models.User{Email: claims.Email}
+
+	verrs, err := u.Invite(tx)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return nil, errors.New(verrs.String())
+	}
+
+	return u, errors.WithStack(u.LinkOIDC(tx, cfg.Issuer, claims.Subject))
+}
+
+// oidcRedirectURL is the callback the provider has registered for us
+func oidcRedirectURL(c buffalo.Context) string {
+	if len(sso.cfg.RedirectURL) > 0 {
+		return sso.cfg.RedirectURL
+	}
+
+	return siteURL(c) + "/auth/oidc/callback"
+}
@@ -0,0 +1,552 @@
+package actions
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/pkg/errors"
+)
+
+// tokens can be a little ahead of or behind our clock
+const oidcClockSkew = 2 * time.Minute
+
+// don't go back for keys more than once a minute, however many unknown
+// key IDs show up
+const oidcKeyRefresh = time.Minute
+
+// oidcConfig is how single sign-on is set up, from the environment
+type oidcConfig struct {
+	Issuer       string              // OIDC_ISSUER, sign-on is off without it
+	ClientID     string              // OIDC_CLIENT_ID
+	ClientSecret string              // OIDC_CLIENT_SECRET, public clients leave it empty
+	RedirectURL  string              // OIDC_REDIRECT_URL, defaults to this site's callback
+	Name         string              // OIDC_NAME, shown on the sign in button
+	Scopes       []string            // OIDC_SCOPES
+	GroupsClaim  string              // OIDC_GROUPS_CLAIM
+	Roles        map[string][]string // OIDC_ROLE_MAP, group=role pairs
+	Provision    bool                // OIDC_PROVISION, make accounts for new users
+}
+
+// loadOIDCConfig reads the sign-on settings
+func loadOIDCConfig() oidcConfig {
+	return oidcConfig{
+		Issuer:       strings.TrimSuffix(envy.Get("OIDC_ISSUER", ""), "/"),
+		ClientID:     envy.Get("OIDC_CLIENT_ID", ""),
+		ClientSecret: envy.Get("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  envy.Get("OIDC_REDIRECT_URL", ""),
+		Name:         envy.Get("OIDC_NAME", "Single Sign-On"),
+		Scopes:       strings.Fields(envy.Get("OIDC_SCOPES", "openid email profile")),
+		GroupsClaim:  envy.Get("OIDC_GROUPS_CLAIM", "groups"),
+		Roles:        parseRoleMap(envy.Get("OIDC_ROLE_MAP", "")),
+		Provision:    envy.Get("OIDC_PROVISION", "true") == "true",
+	}
+}
+
+// enabled says if there is enough set up to offer sign-on
+func (cfg oidcConfig) enabled() bool {
+	return len(cfg.Issuer) > 0 && len(cfg.ClientID) > 0
+}
+
+// managedRoles are the roles sign-on hands out and takes away
+func (cfg oidcConfig) managedRoles() []string {
+	seen := map[string]bool{}
+	roles := []string{}
+
+	for _, rs := range cfg.Roles {
+		for _, r := range rs {
+			if !seen[r] {
+				seen[r] = true
+				roles = append(roles, r)
+			}
+		}
+	}
+
+	return roles
+}
+
+// rolesFor maps the provider's groups to our roles
+func (cfg oidcConfig) rolesFor(groups []string) []string {
+	roles := []string{}
+
+	for _, g := range groups {
+		roles = append(roles, cfg.Roles[g]...)
+	}
+
+	return roles
+}
+
+// parseRoleMap reads "group=role,group=role", a group can be listed
+// more than once to get more than one role
+func parseRoleMap(s string) map[string][]string {
+	roles := map[string][]string{}
+
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		group, role := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		if len(group) > 0 && len(role) > 0 {
+			roles[group] = append(roles[group], role)
+		}
+	}
+
+	return roles
+}
+
+// oidcDiscovery is the part of the provider's metadata we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims is what we need out of an ID token
+type oidcClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	Expiry        int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified oidcBool     `json:"email_verified"`
+	Groups        []string     `json:"-"`
+}
+
+// oidcAudience can come as one string or a list of them
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(b []byte) error {
+	var one string
+
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = oidcAudience{one}
+		return nil
+	}
+
+	var many []string
+
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+
+	*a = many
+
+	return nil
+}
+
+// oidcBool copes with providers that send "true" as a string
+type oidcBool bool
+
+func (o *oidcBool) UnmarshalJSON(b []byte) error {
+	*o = oidcBool(strings.Trim(string(b), `"`) == "true")
+	return nil
+}
+
+// oidcProvider talks to the identity provider.  The metadata and keys
+// are fetched when first needed and kept.
+type oidcProvider struct {
+	cfg    oidcConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// sso is the configured provider, tests swap in their own
+var sso = newOIDCProvider(loadOIDCConfig())
+
+func newOIDCProvider(cfg oidcConfig) *oidcProvider {
+	return &oidcProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discover loads the provider metadata, once
+func (p *oidcProvider) discover() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &oidcDiscovery{}
+
+	if err := p.getJSON(p.cfg.Issuer+"/.well-known/openid-configuration", d); err != nil {
+		return nil, err
+	}
+
+	// the metadata has to be for the issuer we were told to trust
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("provider says it is %q, expected %q", d.Issuer, p.cfg.Issuer)
+	}
+
+	p.discovery = d
+
+	return d, nil
+}
+
+// authURL is where to send the browser to sign in
+func (p *oidcProvider) authURL(redirectURL, state, nonce, verifier string) (string, error) {
+	d, err := p.discover()
+
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", redirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", pkceChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// exchange swaps the code from the callback for an ID token
+func (p *oidcProvider) exchange(redirectURL, code, verifier string) (string, error) {
+	d, err := p.discover()
+
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+
+	if len(p.cfg.ClientSecret) > 0 {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+
+	res, err := p.client.PostForm(d.TokenEndpoint, form)
+
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	reply := struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}{}
+
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return "", fmt.Errorf("token endpoint answered %s", res.Status)
+	}
+
+	if res.StatusCode != http.StatusOK || len(reply.Error) > 0 {
+		return "", fmt.Errorf("token endpoint refused the code: %s", reply.Error)
+	}
+
+	if len(reply.IDToken) == 0 {
+		return "", errors.New("token endpoint sent no id_token")
+	}
+
+	return reply.IDToken, nil
+}
+
+// verify checks the ID token's signature and claims
+func (p *oidcProvider) verify(raw, nonce string, now time.Time) (*oidcClaims, error) {
+	parts := strings.Split(raw, ".")
+
+	if len(parts) != 3 {
+		return nil, errors.New("id_token is not a JWT")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, errors.New("id_token signature is not base64")
+	}
+
+	key, err := p.key(header.Kid, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	claims := &oidcClaims{}
+
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+
+	all := map[string]interface{}{}
+
+	if err := decodeSegment(parts[1], &all); err != nil {
+		return nil, err
+	}
+
+	claims.Groups = stringList(all[p.cfg.GroupsClaim])
+
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.cfg.Issuer:
+		return nil, fmt.Errorf("id_token is from %q", claims.Issuer)
+	case !containsString(claims.Audience, p.cfg.ClientID):
+		return nil, errors.New("id_token is for a different client")
+	case now.After(time.Unix(claims.Expiry, 0).Add(oidcClockSkew)):
+		return nil, errors.New("id_token has expired")
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(oidcClockSkew)):
+		return nil, errors.New("id_token was issued in the future")
+	case claims.Nonce != nonce:
+		return nil, errors.New("id_token nonce doesn't match")
+	case len(claims.Subject) == 0:
+		return nil, errors.New("id_token has no subject")
+	}
+
+	return claims, nil
+}
+
+// key finds the provider's signing key, fetching them again when a new
+// one shows up after a rotation
+func (p *oidcProvider) key(kid string, now time.Time) (crypto.PublicKey, error) {
+	d, err := p.discover()
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+
+	if now.Sub(p.keysFetched) < oidcKeyRefresh {
+		return nil, fmt.Errorf("no signing key %q", kid)
+	}
+
+	set := struct {
+		Keys []oidcJWK `json:"keys"`
+	}{}
+
+	if err := p.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	p.keys = map[string]crypto.PublicKey{}
+	p.keysFetched = now
+
+	for _, jwk := range set.Keys {
+		if k, err := jwk.publicKey(); err == nil {
+			p.keys[jwk.Kid] = k
+		}
+	}
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+
+	return nil, fmt.Errorf("no signing key %q", kid)
+}
+
+func (p *oidcProvider) getJSON(u string, v interface{}) error {
+	res, err := p.client.Get(u)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", u, res.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1024*1024)).Decode(v)
+}
+
+// oidcJWK is one key out of the provider's key set
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k oidcJWK) publicKey() (crypto.PublicKey, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, errors.New("not a signing key")
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// verifySignature checks a JWS signature.  Only the algorithms providers
+// actually use are allowed, "none" never is.
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	sum := sha256.Sum256(signed)
+
+	switch alg {
+	case "RS256":
+		k, ok := key.(*rsa.PublicKey)
+
+		if !ok {
+			return errors.New("RS256 token signed with a non-RSA key")
+		}
+
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("id_token signature is bad")
+		}
+
+		return nil
+	case "ES256":
+		k, ok := key.(*ecdsa.PublicKey)
+
+		if !ok || len(sig) != 64 {
+			return errors.New("ES256 token signed with a non-EC key")
+		}
+
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+
+		if !ecdsa.Verify(k, sum[:], r, s) {
+			return errors.New("id_token signature is bad")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("id_token algorithm %q is not allowed", alg)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+
+	if err != nil {
+		return errors.New("id_token is not base64")
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.New("id_token is not JSON")
+	}
+
+	return nil
+}
+
+// stringList reads a claim that should be a list of strings, some
+// providers send a single group as a plain string
+func stringList(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		list := []string{}
+		for _, i := range val {
+			if s, ok := i.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+
+	return nil
+}
+
+// randomToken makes an unguessable URL safe string
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge is the S256 code challenge for a verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
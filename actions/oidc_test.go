@@ -0,0 +1,167 @@
+package actions
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navionguy/quotewall/models"
+	"github.com/navionguy/quotewall/oidctest"
+)
+
+func Test_pkceChallenge(t *testing.T) {
+	// the example from RFC 7636 appendix B
+	got := pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+
+	if got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("pkceChallenge got %s", got)
+	}
+
+	if got != oidctest.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk") {
+		t.Errorf("mock issuer computes a different challenge")
+	}
+}
+
+func Test_parseRoleMap(t *testing.T) {
+	cfg := oidcConfig{Roles: parseRoleMap(" wall-admins=admin, wall-admins=editor,editors=editor,broken, =x")}
+
+	want := map[string][]string{"wall-admins": {"admin", "editor"}, "editors": {"editor"}}
+
+	if !reflect.DeepEqual(cfg.Roles, want) {
+		t.Errorf("parseRoleMap got %v", cfg.Roles)
+	}
+
+	if got := cfg.rolesFor([]string{"editors", "nobody"}); !reflect.DeepEqual(got, []string{"editor"}) {
+		t.Errorf("rolesFor got %v", got)
+	}
+
+	if got := cfg.managedRoles(); len(got) != 2 {
+		t.Errorf("managedRoles got %v", got)
+	}
+}
+
+func Test_oidcProvider_verify(t *testing.T) {
+	iss, srv, err := oidctest.NewServer("quotewall")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	p := newOIDCProvider(oidcConfig{Issuer: iss.URL, ClientID: "quotewall", GroupsClaim: "groups"})
+	now := time.Now()
+
+	good, _ := iss.IDToken(oidctest.User{Subject: "s1", Email: "a@example.com", Groups: []string{"admins"}}, "n1")
+
+	claims, err := p.verify(good, "n1", now)
+
+	if err != nil {
+		t.Fatalf("good token failed: %s", err)
+	}
+
+	if claims.Subject != "s1" || !reflect.DeepEqual(claims.Groups, []string{"admins"}) {
+		t.Errorf("claims got %+v", claims)
+	}
+
+	if _, err := p.verify(good, "other", now); err == nil {
+		t.Errorf("wrong nonce was accepted")
+	}
+
+	if _, err := p.verify(good, "n1", now.Add(time.Hour)); err == nil {
+		t.Errorf("expired token was accepted")
+	}
+
+	other, _ := iss.Sign(map[string]interface{}{"iss": iss.URL, "sub": "s1", "aud": []string{"someone-else"}, "exp": now.Add(time.Minute).Unix(), "nonce": "n1"})
+
+	if _, err := p.verify(other, "n1", now); err == nil {
+		t.Errorf("token for another client was accepted")
+	}
+
+	// a token with its payload swapped keeps the old signature
+	parts := strings.Split(good, ".")
+	swapped := strings.Split(other, ".")
+
+	if _, err := p.verify(parts[0]+"."+swapped[1]+"."+parts[2], "n1", now); err == nil {
+		t.Errorf("tampered token was accepted")
+	}
+
+	unsigned := `eyJhbGciOiJub25lIn0.` + parts[1] + `.`
+
+	if _, err := p.verify(unsigned, "n1", now); err == nil {
+		t.Errorf("unsigned token was accepted")
+	}
+}
+
+func (as *ActionSuite) Test_OIDCSignIn() {
+	iss, srv, err := oidctest.NewServer("quotewall")
+	as.NoError(err)
+	defer srv.Close()
+
+	iss.User = oidctest.User{Subject: "abc123", Email: "Sso@Example.com", EmailVerified: true, Groups: []string{"wall-admins"}}
+
+	saved := sso
+	sso = newOIDCProvider(oidcConfig{
+		Issuer:      iss.URL,
+		ClientID:    "quotewall",
+		RedirectURL: "http://127.0.0.1:3000/auth/oidc/callback",
+		Scopes:      []string{"openid", "email"},
+		GroupsClaim: "groups",
+		Roles:       parseRoleMap("wall-admins=admin"),
+		Provision:   true,
+	})
+	defer func() { sso = saved }()
+
+	res := as.HTML("/auth/oidc").Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.True(strings.HasPrefix(res.Location(), iss.URL+"/authorize"))
+
+	// play the browser at the provider, which sends it back with a code
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	back, err := noFollow.Get(res.Location())
+	as.NoError(err)
+
+	callback, err := url.Parse(back.Header.Get("Location"))
+	as.NoError(err)
+	as.NotEmpty(callback.Query().Get("code"))
+
+	res = as.HTML("/auth/oidc/callback?%s", callback.RawQuery).Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/account", res.Location())
+
+	u := &models.User{}
+	as.NoError(u.FindByOIDC(models.DB, iss.URL, "abc123"))
+	as.Equal("sso@example.com", u.Email)
+
+	admin, err := u.HasPermission(models.DB, models.PermissionAdmin)
+	as.NoError(err)
+	as.True(admin)
+
+	// the same answer can't be played back
+	res = as.HTML("/auth/oidc/callback?%s", callback.RawQuery).Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/signin", res.Location())
+}
+
+func (as *ActionSuite) Test_oidcUser_Link() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	cfg := oidcConfig{Issuer: "https://id.example.com"}
+
+	// an unverified address could belong to anybody
+	_, err = oidcUser(models.DB, cfg, &oidcClaims{Subject: "s1", Email: "mark@example.com"})
+	as.Error(err)
+
+	found, err := oidcUser(models.DB, cfg, &oidcClaims{Subject: "s1", Email: "mark@example.com", EmailVerified: true})
+	as.NoError(err)
+	as.Equal(u.ID, found.ID)
+
+	// without provisioning strangers are turned away
+	_, err = oidcUser(models.DB, cfg, &oidcClaims{Subject: "s2", Email: "new@example.com", EmailVerified: true})
+	as.Error(err)
+}
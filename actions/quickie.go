@@ -10,13 +10,16 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v5"
 	"github.com/google/uuid"
 	"github.com/navionguy/quotewall/models"
 	"github.com/pkg/errors"
@@ -30,22 +33,97 @@ const quotewallhtml = `<!DOCTYPE html><html lang="en">
 <meta name="dcterms.created" content="{{.Datestr}}">
 <meta name="description" content="">
 <meta name="keywords" content="">
-<meta http-equiv="refresh" content="{{.Refresh}}" />
+<noscript><meta http-equiv="refresh" content="{{.Refresh}}" /></noscript>
 <title>{{.Title}}</title>
-<h1><div align="center">{{.Title}}</div></h1>
+<h1><div align="center" id="title">{{.Title}}</div></h1>
 </head>
-<body>
+<body style="color:{{.TextColor}}; background-color:{{.BackgroundColor}}; font-family:{{.FontFamily}}">
 <div align="center"><table><col width=100%>
-<tr height="80%"><td><table width="100%">
-{{$height := .QuoteShare}}
+<tr height="80%"><td><table width="100%" id="wall">
+{{$page := .}}
+{{if $page.ShowContext}}{{if $page.Context}}<tr><td ALIGN="CENTER"><font color="{{$page.SpeakerColor}}">{{$page.Context}}</font></td></tr>{{end}}{{end}}
 {{range $element := .Conversation}}
-<tr><td ALIGN="CENTER"><p style="font-size:64px; height:{{$height}}%">{{.Quote}}</p></td></tr><tr><td/>
-</tr><tr><td ALIGN="RIGHT"><font color="blue">{{.Name}}</font></td></tr>
-<tr><td ALIGN="RIGHT"><font color="blue">{{.Date}}</font></td></tr>
-<tr><td ALIGN="CENTER"><font color="red">{{.Comment}}</font></td></tr>
+<tr><td ALIGN="CENTER"><p style="font-size:{{if .FontSize}}{{.FontSize}}{{else}}{{$page.FontSize}}{{end}}px; height:{{$page.QuoteShare}}%">{{range $i, $line := .Lines}}{{if $i}}<br>{{end}}{{$line}}{{end}}</p></td></tr><tr><td/>
+</tr>{{if $page.ShowSpeaker}}<tr><td ALIGN="RIGHT"><font color="{{$page.SpeakerColor}}">{{.Name}}</font></td></tr>{{end}}
+{{if $page.ShowDate}}<tr><td ALIGN="RIGHT"><font color="{{$page.SpeakerColor}}">{{.Date}}</font></td></tr>{{end}}
+{{if $page.ShowNote}}<tr><td ALIGN="CENTER" style="white-space:pre-line"><font color="{{$page.NoteColor}}">{{.Comment}}</font></td></tr>{{end}}
 {{end}}
 </table></td></tr>
-</div></body></html>`
+</div>
+<script>
+(function() {
+	var refresh = parseInt({{.Refresh}}, 10) * 1000;
+	var stream = {{.Stream}};
+
+	// browsers that can't stream just reload the page
+	if (!stream || !window.EventSource || !window.JSON) {
+		setTimeout(function() { window.location.reload(); }, refresh);
+		return;
+	}
+
+	function addRow(wall, text, align, color) {
+		var tr = document.createElement("tr");
+		var td = document.createElement("td");
+		var font = document.createElement("font");
+		td.align = align;
+		td.style.whiteSpace = "pre-line";
+		font.color = color;
+		font.textContent = text;
+		td.appendChild(font);
+		tr.appendChild(td);
+		wall.appendChild(tr);
+	}
+
+	function render(page) {
+		var wall = document.getElementById("wall");
+		while (wall.firstChild) {
+			wall.removeChild(wall.firstChild);
+		}
+
+		if (page.ShowContext && page.Context) {
+			addRow(wall, page.Context, "CENTER", page.SpeakerColor);
+		}
+
+		page.Conversation.forEach(function(q) {
+			var tr = document.createElement("tr");
+			var td = document.createElement("td");
+			var p = document.createElement("p");
+			td.align = "CENTER";
+			p.style.fontSize = (q.FontSize || page.FontSize) + "px";
+			p.style.height = page.QuoteShare + "%";
+			(q.Lines || [q.Quote]).forEach(function(line, i) {
+				if (i > 0) {
+					p.appendChild(document.createElement("br"));
+				}
+				p.appendChild(document.createTextNode(line));
+			});
+			td.appendChild(p);
+			tr.appendChild(td);
+			wall.appendChild(tr);
+			wall.appendChild(document.createElement("tr"));
+
+			if (page.ShowSpeaker) {
+				addRow(wall, q.Name, "RIGHT", page.SpeakerColor);
+			}
+			if (page.ShowDate) {
+				addRow(wall, q.Date, "RIGHT", page.SpeakerColor);
+			}
+			if (page.ShowNote) {
+				addRow(wall, q.Comment, "CENTER", page.NoteColor);
+			}
+		});
+
+		document.title = page.Title;
+		document.getElementById("title").textContent = page.Title;
+	}
+
+	var source = new EventSource(stream);
+	source.addEventListener("quote", function(e) {
+		render(JSON.parse(e.data));
+	});
+})();
+</script>
+</body></html>`
 
 const errorhtml = `<!DOCTYPE html><html lang="en">
 <head><meta charset="utf-8">
@@ -61,10 +139,15 @@ const errorhtml = `<!DOCTYPE html><html lang="en">
 
 const ctLayout = "2006-01-02" // the date format used in my json file
 
-const ageParam = "max-age" // only pull quotes that happened in the last n days
-const endRange = "before"  // only pull quotes that happened before specified date
-const startRange = "after" // only pull quotes that happened after specified date
-const speaker = "speaker"  // only pull quotes that involved the specified speaker
+const ageParam = "max-age"        // only pull quotes that happened in the last n days
+const endRange = "before"         // only pull quotes that happened before specified date
+const startRange = "after"        // only pull quotes that happened after specified date
+const speaker = "speaker"         // only pull quotes that involved the specified speaker
+const anniversary = "anniversary" // only pull quotes said on this day in previous years
+const window = "window"           // days either side of today to use if there are no anniversaries
+const profileParam = "profile"    // pick a display profile by name
+const defaultWindow = 3
+const maxWindow = 30
 
 var sdft time.Time // date time stamp of quote file I'm using
 
@@ -75,6 +158,10 @@ type quoteType struct {
 	Date    string
 	Publish string
 	Comment string
+
+	// filled in by the layout engine
+	FontSize int      `json:",omitempty"`
+	Lines    []string `json:",omitempty"`
 }
 
 type conversationtype struct {
@@ -93,8 +180,22 @@ type pageParams struct {
 	Datestr      string
 	Title        string
 	QuoteShare   int
+	Context      string // where and why the conversation happened
 	Conversation []quoteType
 	Refresh      string
+	Stream       string `json:"-"` // where to listen for the next quote
+
+	// look and feel from the display profile
+	FontFamily      string
+	FontSize        int
+	TextColor       string
+	BackgroundColor string
+	SpeakerColor    string
+	NoteColor       string
+	ShowSpeaker     bool
+	ShowDate        bool
+	ShowNote        bool
+	ShowContext     bool
 }
 
 type filterSet map[string]string
@@ -131,10 +232,15 @@ type ShuffleData struct {
 type quickieRequest struct {
 	c          buffalo.Context
 	rqParams   map[string]string
+	rqArgs     map[string][]interface{} // values for the placeholders in rqParams
 	paramsHash []byte
 	paramsChgd bool
 	quoteID    *uuid.UUID
 	rcvdTime   time.Time
+	fallback   int // anniversary window to try if today has no quotes
+	profile    *models.DisplayProfile
+	locked     url.Values  // filters set by an embed key, nothing else gets a say
+	size       *screenSize // an image lays out for its own size, not the display's
 }
 
 var curShuffle *ShuffleData
@@ -161,9 +267,16 @@ func (v ConversationsResource) QuickieQuote(c buffalo.Context) error {
 		return c.Error(404, err)
 	}
 
+	// an admin can pin a conversation to every wall, even one whose
+	// filters match nothing
+	if id := hub.pinnedID(); id != nil {
+		rq.quoteID = id
+	}
+
 	if rq.quoteID == nil {
 		// no quotes found
 		page := setDefaultConversation()
+		page.applyProfile(rq.profile)
 		templ := template.New("quote wall")
 		templ = template.Must((templ.Parse((quotewallhtml))))
 
@@ -172,15 +285,17 @@ func (v ConversationsResource) QuickieQuote(c buffalo.Context) error {
 		}))
 	}
 
-	conv := models.Conversation{}
-	err = models.DB.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotation").Find(&conv, rq.quoteID)
+	conv, err := loadWallConversation(rq.db(), rq.quoteID)
 
 	if err != nil {
 		return c.Error(404, err)
 	}
 
+	metricLog.shown(rq.displayName(), conv.ID)
+
 	// prepare conversation for display
-	page := prepareConv(conv, c)
+	page := prepareConv(*conv, rq.profile, rq.screen())
+	page.Stream = streamPath + "?" + c.Request().URL.RawQuery
 	templ := template.New("quote wall")
 	templ = template.Must((templ.Parse((quotewallhtml))))
 
@@ -195,6 +310,8 @@ func newRequest(c buffalo.Context) *quickieRequest {
 	rq.rcvdTime = time.Now()
 	rq.c = c
 	rq.rqParams = make(map[string]string)
+	rq.rqArgs = make(map[string][]interface{})
+	rq.loadProfile()
 
 	// ToDo retrieve the filterkey from the session
 
@@ -211,49 +328,164 @@ func newRequest(c buffalo.Context) *quickieRequest {
 	return &rq
 }
 
-func (rq *quickieRequest) LogMetrics() {
-	elapsed := time.Since(rq.rcvdTime).Milliseconds()
-	strElapsed := fmt.Sprint(elapsed)
+// loadProfile looks up the display profile named on the request.
+// If there isn't one, or it can't be found, the wall uses the default.
+func (rq *quickieRequest) loadProfile() {
+	rq.profile = models.DefaultDisplayProfile()
+
+	name := rq.c.Request().URL.Query().Get(profileParam)
 
-	q := models.DB.RawQuery("SELECT * FROM log_metric( ? );", strElapsed)
+	if len(name) == 0 {
+		return
+	}
 
-	err := q.Exec()
+	prof := &models.DisplayProfile{Name: name}
+	err := prof.FindByName(rq.db())
 
 	if err != nil {
-		fmt.Printf("LogMetrics err: %s\n", err.Error())
+		rq.c.Logger().Warnf("display profile %s: %s", name, err.Error())
 		return
 	}
+
+	rq.profile = prof
+}
+
+// db is the request's transaction, or the plain connection where there
+// isn't one, like on the wall streams and in the scheduler
+func (rq *quickieRequest) db() *pop.Connection {
+	if rq.c != nil {
+		if tx, ok := rq.c.Value("tx").(*pop.Connection); ok {
+			return tx
+		}
+	}
+
+	return models.DB
+}
+
+// screen works out the resolution of the display from the request,
+// falling back to the one in the display profile.  An image already
+// knows its size.
+func (rq *quickieRequest) screen() screenSize {
+	if rq.size != nil {
+		return *rq.size
+	}
+
+	scr := screenSize{Width: rq.profile.Width, Height: rq.profile.Height}
+
+	return parseResolution(rq.c.Request().URL.Query().Get(resolutionParam), scr)
+}
+
+// query returns the request parameters with the profile filters
+// filled in underneath them.  Anything on the request wins.  An embed
+// only gets the filters its key locked on, the page it sits on can't
+// widen or change them.
+func (rq *quickieRequest) query() url.Values {
+	if rq.locked != nil {
+		return rq.locked
+	}
+
+	vals := url.Values{}
+
+	if rq.profile != nil {
+		vals = rq.profile.FilterValues()
+	}
+
+	for k, v := range rq.c.Request().URL.Query() {
+		vals[k] = v
+	}
+
+	return vals
+}
+
+// LogMetrics adds how long the request took to the daily buckets in the
+// requests table.  The write happens in the background.
+func (rq *quickieRequest) LogMetrics() {
+	metricLog.log(time.Since(rq.rcvdTime).Milliseconds())
 }
 
 // prepareConv moves the information into a structure that the html template
-// understands.  He also attempts to scale the text so the whole conversation
-// fits on the screen.
-//
-// ToDo: Fix scaling for convs with > 2 quotes
-// ToDo: Fix line breaks for long quotes
-func prepareConv(conv models.Conversation, c buffalo.Context) pageParams {
+// understands.  He also lays out the text so the whole conversation fits on
+// the screen, and keeps it up long enough to be read.
+func prepareConv(conv models.Conversation, prof *models.DisplayProfile, scr screenSize) pageParams {
 	var p pageParams
 
 	p.Datestr = time.Now().Format("Mon Jan _2 15:04:05 2006")
-	p.Title = "Quote Wall Quickie"
+	p.Title = prof.Title
+	p.applyProfile(prof)
+	p.Context = conv.Context()
 
 	for _, qt := range conv.Quotes {
 		var utt quoteType
 		utt.Date = qt.SaidOn.Format("Jan 2, 2006")
 		utt.Name = qt.Author.Name
 		utt.Quote = qt.Phrase
-		if qt.Annotation != nil {
-			utt.Comment = qt.Annotation.Note
-		}
+		utt.Comment = qt.Annotations.Text()
 		p.Conversation = append(p.Conversation, utt)
 	}
 
+	// nothing to lay out, show the empty wall instead
+	if len(p.Conversation) == 0 {
+		empty := setDefaultConversation()
+		empty.applyProfile(prof)
+		return *empty
+	}
+
+	chars := 0
+	var phrases []string
+	for _, utt := range p.Conversation {
+		chars += utf8.RuneCountInString(utt.Quote)
+		phrases = append(phrases, utt.Quote)
+	}
+
+	extra := 0
+	for _, shown := range []bool{prof.ShowSpeaker, prof.ShowDate} {
+		if shown {
+			extra++
+		}
+	}
+
+	// leave room under every quote for the longest note
+	if prof.ShowNote {
+		notes := 1
+		for _, utt := range p.Conversation {
+			if n := noteLines(utt.Comment, scr); n > notes {
+				notes = n
+			}
+		}
+		extra += notes
+	}
+
+	// the context sits above the conversation, out of everyone's share
+	fit := scr
+	if prof.ShowContext && len(p.Context) > 0 {
+		fit.Height -= int(float64(noteLines(p.Context, scr)*attributionSize) * lineHeight / usableHeight)
+	}
+
+	for i, l := range fitConversation(phrases, fit, prof.FontMin, prof.FontMax, extra) {
+		p.Conversation[i].FontSize = l.FontSize
+		p.Conversation[i].Lines = l.Lines
+	}
+
 	p.QuoteShare = 80 / len(p.Conversation)
-	p.Refresh = "10"
+	p.Refresh = strconv.Itoa(prof.RefreshFor(chars))
 
 	return p
 }
 
+// applyProfile copies the look and feel of the display profile into the page
+func (p *pageParams) applyProfile(prof *models.DisplayProfile) {
+	p.FontFamily = prof.FontFamily
+	p.FontSize = prof.FontMax
+	p.TextColor = prof.TextColor
+	p.BackgroundColor = prof.BackgroundColor
+	p.SpeakerColor = prof.SpeakerColor
+	p.NoteColor = prof.NoteColor
+	p.ShowSpeaker = prof.ShowSpeaker
+	p.ShowDate = prof.ShowDate
+	p.ShowNote = prof.ShowNote
+	p.ShowContext = prof.ShowContext
+}
+
 func (rq *quickieRequest) pickQuote() error {
 	// see if there is a "nextQuote" on the request
 	index := rq.nextQuoteCookie()
@@ -261,6 +493,7 @@ func (rq *quickieRequest) pickQuote() error {
 	// check to see if no quote found
 	if index == -1 {
 		rq.quoteID = nil
+		quickiePicks.WithLabelValues("none").Inc()
 		return nil
 	}
 
@@ -279,18 +512,42 @@ func (rq *quickieRequest) pickQuote() error {
 		rq.saveNextQuoteCookie(&blob)
 	}
 
-	var sc ShuffledConversations
-	err := models.DB.RawQuery("SELECT * FROM shuffled_conversations WHERE sequence = ?", index).First(&sc)
+	id, err := shuffledConversation(rq.db(), index)
 
 	if err != nil {
 		return err
 	}
 
-	rq.quoteID = &sc.ID
+	rq.quoteID = id
+	quickiePicks.WithLabelValues("picked").Inc()
 
 	return nil
 }
 
+// shuffledConversation looks up the conversation at index in the shuffled list
+func shuffledConversation(db *pop.Connection, index int) (*uuid.UUID, error) {
+	var sc ShuffledConversations
+	err := db.RawQuery("SELECT * FROM shuffled_conversations WHERE sequence = ?", index).First(&sc)
+
+	if countDBError("pick", err) != nil {
+		return nil, err
+	}
+
+	return &sc.ID, nil
+}
+
+// loadWallConversation loads everything the wall needs to show a conversation
+func loadWallConversation(db *pop.Connection, id *uuid.UUID) (*models.Conversation, error) {
+	conv := &models.Conversation{}
+	err := db.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").Find(conv, id)
+
+	if countDBError("load_conversation", err) != nil {
+		return nil, err
+	}
+
+	return conv, nil
+}
+
 func (rq *quickieRequest) chkParams(blob *cookieBlob) error {
 	// go check for any parameters on the request
 	rq.checkForFilters()
@@ -304,30 +561,29 @@ func (rq *quickieRequest) chkParams(blob *cookieBlob) error {
 
 	// let's try to apply the parameters and build a query
 
-	qry := "SELECT s.sequence FROM authors a JOIN quotes q ON a.id = q.author_id JOIN shuffled_conversations s ON q.conversation_id = s.id"
-
-	first := true
-	for _, cond := range rq.rqParams {
-		if first {
-			qry = qry + fmt.Sprintf(" WHERE %s", cond)
-		} else {
-			qry = qry + fmt.Sprintf(" AND %s", cond)
-		}
-		first = false
-	}
-	qry = qry + ";"
-
-	//var blob cookieBlob
-
 	var filteredConvs []ShuffledConversations
 
-	err := models.DB.RawQuery(qry).All(&filteredConvs)
+	qry, args := rq.filterQuery()
+	err := rq.db().RawQuery(qry, args...).All(&filteredConvs)
 
 	if err != nil {
 		blob.FilteredList = blob.FilteredList[:0]
 		return err
 	}
 
+	// nothing happened on this day, try the days around it
+	if len(filteredConvs) == 0 && rq.fallback > 0 {
+		rq.rqParams[anniversary] = models.AnniversaryClause(models.DB.Dialect.Name(), "q.saidon", today(), rq.fallback)
+
+		qry, args = rq.filterQuery()
+		err = rq.db().RawQuery(qry, args...).All(&filteredConvs)
+
+		if err != nil {
+			blob.FilteredList = blob.FilteredList[:0]
+			return err
+		}
+	}
+
 	blob.FilteredList = blob.FilteredList[:0]
 	for _, fil := range filteredConvs {
 		blob.FilteredList = append(blob.FilteredList, fil.Sequence)
@@ -342,6 +598,26 @@ func (rq *quickieRequest) chkParams(blob *cookieBlob) error {
 	return nil
 }
 
+// filterQuery builds the query that applies all the filters in rqParams,
+// along with the values for its placeholders
+func (rq *quickieRequest) filterQuery() (string, []interface{}) {
+	qry := "SELECT s.sequence FROM authors a JOIN quotes q ON a.id = q.author_id JOIN shuffled_conversations s ON q.conversation_id = s.id"
+	var args []interface{}
+
+	first := true
+	for name, cond := range rq.rqParams {
+		if first {
+			qry = qry + fmt.Sprintf(" WHERE %s", cond)
+		} else {
+			qry = qry + fmt.Sprintf(" AND %s", cond)
+		}
+		args = append(args, rq.rqArgs[name]...)
+		first = false
+	}
+
+	return qry + ";", args
+}
+
 // I support letting the users apply the following filters:
 // 		max-age=n  			: only pull quotes that happened in the last n days (saved as 'startRange')
 //		before=date			: only pull quotes that happened before specified date
@@ -349,6 +625,8 @@ func (rq *quickieRequest) chkParams(blob *cookieBlob) error {
 //		speaker=name		: only pull quotes that involved the specified speaker
 //								speaker name is in a 'LIKE' clause so partials will match
 //								name of Sha would return both "Shari Freeman" quotes and "Mitesh Shah" quotes
+//		anniversary=true	: only pull quotes said on today's month and day in previous years
+//		window=n			: if there are no anniversaries today, widen to n days either side
 
 func (rq *quickieRequest) checkForFilters() {
 	// clear my param map in case it has changed
@@ -362,7 +640,7 @@ func (rq *quickieRequest) checkForFilters() {
 	if ok {
 		d, _ := time.ParseDuration(fmt.Sprintf("%dh", val*24))
 		dt := time.Now().Add(-d)
-		rq.rqParams[startRange] = fmt.Sprintf("q.saidon > '%s'", dt.Format("01/02/2006"))
+		rq.rqParams[startRange] = fmt.Sprintf("q.saidon > '%s'", dt.Format("2006-01-02"))
 	}
 
 	// check for after param
@@ -383,11 +661,28 @@ func (rq *quickieRequest) checkForFilters() {
 
 	spkr, ok := rq.checkStringFilter(speaker)
 
+	// case doesn't matter, on postgres LIKE alone would care
 	if ok {
-		rq.rqParams[speaker] = "a.name LIKE '%" + spkr + "%'"
+		spkr = strings.ToLower(spkr)
+		rq.rqParams[speaker] = "LOWER(a.name) LIKE ?"
+		rq.rqArgs[speaker] = []interface{}{"%" + spkr + "%"}
 	}
 
-	hash := sha256.Sum256([]byte(rq.rqParams[startRange] + rq.rqParams[endRange] + rq.rqParams[speaker]))
+	// check for anniversary param
+
+	if rq.checkBoolFilter(anniversary) {
+		rq.rqParams[anniversary] = models.AnniversaryClause(models.DB.Dialect.Name(), "q.saidon", today(), 0)
+
+		rq.fallback = defaultWindow
+		if val, ok := rq.checkNumericFilter(window); ok && val >= 0 {
+			rq.fallback = val
+		}
+		if rq.fallback > maxWindow {
+			rq.fallback = maxWindow
+		}
+	}
+
+	hash := sha256.Sum256([]byte(rq.rqParams[startRange] + rq.rqParams[endRange] + spkr + rq.rqParams[anniversary]))
 
 	if !bytes.Equal(rq.paramsHash, hash[0:]) {
 		rq.paramsChgd = true
@@ -398,7 +693,7 @@ func (rq *quickieRequest) checkForFilters() {
 
 // check for a string filter
 func (rq *quickieRequest) checkStringFilter(name string) (string, bool) {
-	strVal, ok := rq.c.Request().URL.Query()[name]
+	strVal, ok := rq.query()[name]
 
 	if !ok {
 		// didn't find filter by name
@@ -408,28 +703,46 @@ func (rq *quickieRequest) checkStringFilter(name string) (string, bool) {
 	return strVal[0], true
 }
 
+// filter value is expected to be true or false
+func (rq *quickieRequest) checkBoolFilter(name string) bool {
+	strVal, ok := rq.query()[name]
+
+	if !ok {
+		return false
+	}
+
+	val, err := strconv.ParseBool(strVal[0])
+
+	if err != nil {
+		return false
+	}
+
+	return val
+}
+
 // filter value should be a date
 func (rq *quickieRequest) checkDateFilter(name string) (string, bool) {
-	strVal, ok := rq.c.Request().URL.Query()[name]
+	strVal, ok := rq.query()[name]
 
 	if !ok {
 		// didn't find the parameter by name
 		return "", false
 	}
 
-	_, err := time.Parse("01/02/2006", strVal[0])
+	day, err := time.Parse("01/02/2006", strVal[0])
 
 	if err != nil {
 		// couldn't parse it, ignore it
 		return "", false
 	}
 
-	return strVal[0], true
+	// written back out the way every database reads a date
+	return day.Format("2006-01-02"), true
 }
 
 // filter value is expected to be an integer
 func (rq *quickieRequest) checkNumericFilter(name string) (int, bool) {
-	strVal, ok := rq.c.Request().URL.Query()[name]
+	strVal, ok := rq.query()[name]
 
 	if !ok {
 		return 0, false
@@ -476,58 +789,63 @@ func (rq *quickieRequest) getShuffleData() error {
 	return err
 }
 
-// Shuffling the conversations is actuall done in a stored procedure.
+// Shuffling the conversations is actuall done in a stored procedure,
+// or in models.ShuffleDeck on SQLite.
 // I call him here, then query for the number of conversations and
 // the date of the last shuffle.
+// Inside a request he shuffles in its transaction, and if that gets
+// rolled back, so does the shuffle, and the next request shuffles again.
 // WARNING!  If the database is running on a server whose clock is wrong
 // or disagrees with the local clock about utc, you can go into a
 // shuffle loop until they agree.
 
-// ShuffleDate is only used to pull back the Shuffled Date comment on the table
-type ShuffleDate struct {
-	ObjDescription string `db:"obj_description"`
-}
-
 func (rq *quickieRequest) shuffleConversations() error {
-	err := models.DB.RawQuery("SELECT * FROM shuffle_deck();").Exec()
+	db := rq.db()
+	start := time.Now()
+	_, err := models.ShuffleDeck(db)
 
-	if err != nil {
+	if countDBError("shuffle", err) != nil {
 		return err
 	}
 
-	count, err := models.DB.Count(ShuffledConversations{})
+	shuffleDuration.Observe(time.Since(start).Seconds())
 
-	if err != nil {
-		return err
-	}
-
-	var date ShuffleDate
-	err = models.DB.RawQuery("SELECT OBJ_DESCRIPTION('public.shuffled_conversations'::regclass);").First(&date)
+	count, err := db.Count(ShuffledConversations{})
 
 	if err != nil {
 		return err
 	}
 
-	date.ObjDescription = strings.Trim(date.ObjDescription, "\"")
-	var t time.Time
-	t, err = time.Parse("2006-01-02", date.ObjDescription)
+	t, err := models.ShuffledOn(db)
 
 	if err != nil {
 		return err
 	}
 
 	var corr *time.Duration
-	corr, err = getDBTimeDiff()
+	corr, err = getDBTimeDiff(db)
 
 	if err != nil {
 		return err
 	}
 
-	curShuffle = &ShuffleData{
+	shuffle := &ShuffleData{
 		Size:        count,
 		ShuffledDay: t,
 		ServCor:     *corr,
 	}
+	curShuffle = shuffle
+
+	if rq.c != nil {
+		afterRollback(rq.c, func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if curShuffle == shuffle {
+				curShuffle = nil
+			}
+		})
+	}
 
 	return nil
 }
@@ -552,23 +870,9 @@ func (rq *quickieRequest) shuffleCurrent() bool {
 	return false
 }
 
-// ServerTime is used to get the SQL servers current time in UTC
-type ServerTime struct {
-	UTCTime string `db:"timezone"`
-}
-
 // calculate clock difference between me and SQL server
-func getDBTimeDiff() (*time.Duration, error) {
-	var sTime ServerTime
-	err := models.DB.RawQuery("SELECT CURRENT_TIMESTAMP AT TIME ZONE 'UTC';").First(&sTime)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// drop the fraction of a second
-	sTime.UTCTime = strings.Split(sTime.UTCTime, ".")[0]
-	sTimeVal, err := time.Parse("2006-01-02T15:04:05", sTime.UTCTime)
+func getDBTimeDiff(db *pop.Connection) (*time.Duration, error) {
+	sTimeVal, err := models.ServerTimeUTC(db)
 
 	if err != nil {
 		return nil, err
@@ -749,6 +1053,7 @@ func setDefaultConversation() *pageParams {
 		Publish: "True",
 		Quote:   "Life isn't about quotes about life.",
 	}
+	qt.Lines = []string{qt.Quote}
 	p := &pageParams{
 		Datestr:    "",
 		Title:      "No quotes on that wall!",
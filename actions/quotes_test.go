@@ -10,7 +10,18 @@ func (as *ActionSuite) Test_QuotesResource_List() {
 }
 
 func (as *ActionSuite) Test_QuotesResource_Show() {
-	//as.Fail("Not Implemented!")
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	res := as.HTML("/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+
+	body := res.Body.String()
+	for _, want := range []string{"Product Review", "Conference Room B", "Note about the quote", "Editor&#39;s Aside", "First Timer!", "George P. Burdell"} {
+		as.Contains(body, want)
+	}
 }
 
 func (as *ActionSuite) Test_QuotesResource_New() {
@@ -22,7 +33,18 @@ func (as *ActionSuite) Test_QuotesResource_Create() {
 }
 
 func (as *ActionSuite) Test_QuotesResource_Edit() {
-	//as.Fail("Not Implemented!")
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	res := as.HTML("/conversations/e682fe38-23f4-4410-8d67-bdcc7f3782cb/edit").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+
+	body := res.Body.String()
+	for _, want := range []string{`value="Product Review"`, `<option value="explanation">Explanation</option>`, "conversation-NotedBy"} {
+		as.Contains(body, want)
+	}
 }
 
 func (as *ActionSuite) Test_QuotesResource_Update() {
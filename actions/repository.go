@@ -0,0 +1,18 @@
+package actions
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// repository is the archive as seen through the request's transaction
+func repository(c buffalo.Context) (*models.Repository, error) {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return nil, errors.WithStack(errors.New("no transaction found"))
+	}
+
+	return models.NewPopRepository(tx), nil
+}
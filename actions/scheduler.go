@@ -0,0 +1,366 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// scheduledJob is a maintenance task that runs on a cron schedule.  Run
+// returns a line about what it did for the job status page.
+type scheduledJob struct {
+	Name string
+	Desc string
+	Spec string // standard five field cron expression, empty when turned off
+	Run  func(now time.Time) (string, error)
+
+	entry cron.EntryID
+}
+
+// jobStatus is what the status page shows for a job
+type jobStatus struct {
+	Name    string
+	Desc    string
+	Spec    string
+	Next    time.Time
+	Running bool
+	HasRun  bool
+	Last    models.JobRun
+}
+
+// scheduler runs the jobs.  Every instance runs one, the advisory lock
+// makes sure only one of them actually does each run.
+type scheduler struct {
+	sync.Mutex
+	cron    *cron.Cron
+	jobs    []*scheduledJob
+	running map[string]bool
+	host    string
+}
+
+var errJobRunning = errors.New("job is already running")
+var errJobUnknown = errors.New("no such job")
+
+// where the backup job writes, and how many backups it keeps
+var backupDir = envy.Get("BACKUP_DIR", "backups")
+var backupKeep = parseKeep(envy.Get("BACKUP_KEEP", "14"))
+
+var jobs = newScheduler(defaultJobs())
+
+// defaultJobs are the maintenance jobs.  Each one's schedule comes from
+// JOB_<NAME> as a cron expression like "30 3 * * *", or "off".
+func defaultJobs() []*scheduledJob {
+	return []*scheduledJob{
+		{Name: "reshuffle", Desc: "Reshuffles the conversations for the new day", Spec: jobSpec("JOB_RESHUFFLE", "1 0 * * *"), Run: reshuffleJob},
+		{Name: "rotate_requests", Desc: "Moves the requests table aside and starts a new one", Spec: jobSpec("JOB_ROTATE_REQUESTS", "5 0 1 * *"), Run: rotateRequestsJob},
+		{Name: "purge", Desc: "Clears out expired sessions, tokens and old logs", Spec: jobSpec("JOB_PURGE", "30 3 * * *"), Run: purgeJob},
+		{Name: "digest", Desc: "Mails the email digests", Spec: jobSpec("JOB_DIGEST", fmt.Sprintf("0 %d * * *", digestHour)), Run: digestJob},
+		{Name: "backup", Desc: "Writes the whole archive out to " + backupDir, Spec: jobSpec("JOB_BACKUP", "0 2 * * *"), Run: backupJob},
+		{Name: "webhooks", Desc: "Queues the webhook deliveries that were waiting when the server stopped", Spec: jobSpec("JOB_WEBHOOKS", "*/5 * * * *"), Run: webhooksJob},
+	}
+}
+
+func newScheduler(list []*scheduledJob) *scheduler {
+	host, err := os.Hostname()
+
+	if err != nil {
+		host = "unknown"
+	}
+
+	return &scheduler{
+		cron:    cron.New(),
+		jobs:    list,
+		running: map[string]bool{},
+		host:    host,
+	}
+}
+
+// jobSpec reads a job's schedule, "off" turns it off
+func jobSpec(key, def string) string {
+	s := strings.TrimSpace(envy.Get(key, def))
+
+	if strings.EqualFold(s, "off") {
+		return ""
+	}
+
+	return s
+}
+
+// parseKeep reads how many backups to keep, at least one
+func parseKeep(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+
+	if err != nil || n < 1 {
+		return 14
+	}
+
+	return n
+}
+
+// start checks every schedule and starts the clock
+func (s *scheduler) start() error {
+	for _, j := range s.jobs {
+		if len(j.Spec) == 0 {
+			continue
+		}
+
+		j := j
+		id, err := s.cron.AddFunc(j.Spec, func() {
+			if _, err := s.run(j.Name, time.Now()); err != nil && err != errJobRunning {
+				log.Errorf("job %s: %s", j.Name, err.Error())
+			}
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "job %s schedule %q", j.Name, j.Spec)
+		}
+
+		j.entry = id
+	}
+
+	s.cron.Start()
+
+	return nil
+}
+
+// find looks up a job by name
+func (s *scheduler) find(name string) (*scheduledJob, bool) {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j, true
+		}
+	}
+
+	return nil, false
+}
+
+// run runs the job, unless it is already running here or another
+// instance holds its lock.  It returns false when the job didn't run.
+// How the job itself went is recorded in job_runs, not returned.
+func (s *scheduler) run(name string, now time.Time) (bool, error) {
+	j, ok := s.find(name)
+
+	if !ok {
+		return false, errJobUnknown
+	}
+
+	s.Lock()
+	if s.running[name] {
+		s.Unlock()
+		return false, errJobRunning
+	}
+	s.running[name] = true
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		delete(s.running, name)
+		s.Unlock()
+	}()
+
+	return models.WithAdvisoryLock(models.DB, "job:"+name, func() error {
+		jr, err := models.StartJobRun(models.DB, name, s.host, now)
+
+		if err != nil {
+			return err
+		}
+
+		msg, err := j.Run(now)
+
+		if err != nil {
+			log.Errorf("job %s: %s", name, err.Error())
+		} else {
+			log.Infof("job %s: %s", name, msg)
+		}
+
+		return jr.Finish(models.DB, msg, err, time.Now())
+	})
+}
+
+// statuses gathers what the status page shows for every job
+func (s *scheduler) statuses(latest models.JobRuns) []jobStatus {
+	last := map[string]models.JobRun{}
+	for _, r := range latest {
+		last[r.Name] = r
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	list := []jobStatus{}
+
+	for _, j := range s.jobs {
+		st := jobStatus{
+			Name:    j.Name,
+			Desc:    j.Desc,
+			Spec:    j.Spec,
+			Running: s.running[j.Name],
+		}
+
+		if j.entry != 0 {
+			st.Next = s.cron.Entry(j.entry).Next
+		}
+
+		st.Last, st.HasRun = last[j.Name]
+		list = append(list, st)
+	}
+
+	return list
+}
+
+// RunJob runs one of the scheduled jobs right now, for running them by
+// hand.  It still won't run if another instance is running it.
+func RunJob(name string) (bool, error) {
+	return jobs.run(name, time.Now())
+}
+
+// reshuffleJob shuffles the deck ahead of the first request of the day
+func reshuffleJob(now time.Time) (string, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := (&quickieRequest{}).shuffleConversations(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d conversations shuffled", curShuffle.Size), nil
+}
+
+// rotateRequestsJob moves the requests table aside, as long as there is
+// something in it
+func rotateRequestsJob(now time.Time) (string, error) {
+	buckets, err := models.DB.Count("requests")
+
+	if countDBError("rotate_requests", err) != nil {
+		return "", err
+	}
+
+	if buckets == 0 {
+		return "nothing to rotate", nil
+	}
+
+	if err := countDBError("rotate_requests", models.RotateRequests(models.DB)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("rotated %d buckets", buckets), nil
+}
+
+// purgeJob clears out what has expired
+func purgeJob(now time.Time) (string, error) {
+	purged, err := models.Purge(models.DB, now)
+
+	if countDBError("purge", err) != nil {
+		return "", err
+	}
+
+	tables := []string{}
+	for t := range purged {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+
+	parts := []string{}
+	for _, t := range tables {
+		parts = append(parts, fmt.Sprintf("%s %d", t, purged[t]))
+	}
+
+	return "purged " + strings.Join(parts, ", "), nil
+}
+
+// digestJob mails whoever is due a digest
+func digestJob(now time.Time) (string, error) {
+	sent, err := runDigests(models.DB, now, false)
+
+	return fmt.Sprintf("sent %d", sent), err
+}
+
+// webhooksJob queues the webhook deliveries the worker lost
+func webhooksJob(now time.Time) (string, error) {
+	queued, err := requeueWebhooks(models.DB, now)
+
+	return fmt.Sprintf("queued %d", queued), err
+}
+
+// backupJob writes the archive to a new file, then drops the oldest
+// backups past BACKUP_KEEP
+func backupJob(now time.Time) (string, error) {
+	conversations := models.Conversations{}
+
+	err := models.DB.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").All(&conversations)
+
+	if countDBError("backup", err) != nil {
+		return "", err
+	}
+
+	path, err := writeBackup(backupDir, conversations, now)
+
+	if err != nil {
+		return "", err
+	}
+
+	removed, err := pruneBackups(backupDir, backupKeep)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("wrote %d conversations to %s, removed %d old backups", len(conversations), path, removed), nil
+}
+
+// writeBackup writes v out as json.  It goes to a temporary file first so
+// a half written backup never looks like a good one.
+func writeBackup(dir string, v interface{}, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	raw, err := json.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	path := filepath.Join(dir, "quotewall-"+now.Format("20060102-150405")+".json")
+
+	if err := ioutil.WriteFile(path+".tmp", raw, 0640); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return path, errors.WithStack(os.Rename(path+".tmp", path))
+}
+
+// pruneBackups removes all but the newest keep backups.  The names sort
+// by time, so the oldest come first.
+func pruneBackups(dir string, keep int) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "quotewall-*.json"))
+
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	sort.Strings(files)
+	removed := 0
+
+	for len(files)-removed > keep {
+		if err := os.Remove(files[removed]); err != nil {
+			return removed, errors.WithStack(err)
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
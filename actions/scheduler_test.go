@@ -0,0 +1,161 @@
+package actions
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/navionguy/quotewall/models"
+	"github.com/robfig/cron/v3"
+)
+
+func Test_jobSpec(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("JOB_TEST_SPEC", " off ")
+
+		if got := jobSpec("JOB_TEST_SPEC", "0 1 * * *"); got != "" {
+			t.Errorf("off got %q", got)
+		}
+	})
+
+	if got := jobSpec("JOB_TEST_MISSING", "0 1 * * *"); got != "0 1 * * *" {
+		t.Errorf("default got %q", got)
+	}
+}
+
+func Test_defaultJobs(t *testing.T) {
+	for _, j := range defaultJobs() {
+		if _, err := cron.ParseStandard(j.Spec); err != nil {
+			t.Errorf("%s schedule %q: %s", j.Name, j.Spec, err)
+		}
+	}
+}
+
+func Test_scheduler_start(t *testing.T) {
+	s := newScheduler([]*scheduledJob{{Name: "bad", Spec: "every so often"}})
+
+	if err := s.start(); err == nil {
+		t.Error("a bad schedule started")
+	}
+
+	s = newScheduler([]*scheduledJob{{Name: "off"}, {Name: "daily", Spec: "0 4 * * *"}})
+
+	if err := s.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.cron.Stop()
+
+	st := s.statuses(models.JobRuns{{Name: "daily", Status: models.JobOK}})
+
+	if !st[0].Next.IsZero() || st[0].HasRun {
+		t.Errorf("off job got %v", st[0])
+	}
+
+	if st[1].Next.Hour() != 4 || !st[1].HasRun {
+		t.Errorf("daily job got %v", st[1])
+	}
+}
+
+func Test_parseKeep(t *testing.T) {
+	tests := map[string]int{"3": 3, "0": 14, "many": 14, " 30 ": 30}
+
+	for s, want := range tests {
+		if got := parseKeep(s); got != want {
+			t.Errorf("parseKeep(%q) got %d, wanted %d", s, got, want)
+		}
+	}
+}
+
+func Test_backups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		if _, err := writeBackup(dir, []string{"a"}, start.AddDate(0, 0, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := pruneBackups(dir, 2)
+
+	if err != nil || removed != 2 {
+		t.Fatalf("pruneBackups got %d, %v", removed, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+
+	if len(files) != 2 || filepath.Base(files[0]) != "quotewall-20261021-020000.json" {
+		t.Errorf("kept %v", files)
+	}
+}
+
+func (as *ActionSuite) Test_scheduler_run() {
+	s := newScheduler([]*scheduledJob{
+		{Name: "works", Run: func(now time.Time) (string, error) { return "did it", nil }},
+		{Name: "breaks", Run: func(now time.Time) (string, error) { return "", errors.New("it broke") }},
+	})
+	now := time.Now()
+
+	ran, err := s.run("works", now)
+	as.NoError(err)
+	as.True(ran)
+
+	ran, err = s.run("breaks", now)
+	as.NoError(err)
+	as.True(ran)
+
+	_, err = s.run("missing", now)
+	as.Equal(errJobUnknown, err)
+
+	runs := models.JobRuns{}
+	as.NoError(runs.Latest(models.DB))
+	as.Len(runs, 2)
+	as.Equal("breaks", runs[0].Name)
+	as.Equal(models.JobFailed, runs[0].Status)
+	as.Equal("it broke", runs[0].Message)
+	as.Equal(models.JobOK, runs[1].Status)
+	as.Equal("did it", runs[1].Message)
+
+	// someone else holding the lock keeps it from running here
+	_, err = models.WithAdvisoryLock(models.DB, "job:works", func() error {
+		ran, err = s.run("works", now)
+		return err
+	})
+	as.NoError(err)
+	as.False(ran)
+}
+
+func (as *ActionSuite) Test_Jobs() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	as.signInAs(u)
+
+	res := as.HTML("/admin/jobs").Get()
+	as.Equal(http.StatusForbidden, res.Code)
+
+	as.NoError(u.Grant(models.DB, models.PermissionAdmin))
+	as.enableTwoFactor(u)
+
+	_, err = models.StartJobRun(models.DB, "purge", "test", time.Now())
+	as.NoError(err)
+
+	res = as.HTML("/admin/jobs").Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "rotate_requests")
+
+	res = as.HTML("/admin/jobs/nothing/run").Post(nil)
+	as.Equal(http.StatusNotFound, res.Code)
+}
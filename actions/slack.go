@@ -0,0 +1,369 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const slackVersion = "v0"
+const slackMaxAge = 5 * time.Minute // older requests might be replays
+const slackMaxBody = 64 * 1024
+const slackSearchLimit = 5
+
+const slackUsage = "Usage:\n" +
+	"`/quote` or `/quote random` - a random quote\n" +
+	"`/quote search words` - quotes with those words in them\n" +
+	"`/quote by Name` - a random quote from that speaker\n" +
+	"`/quote add \"what was said\" --by Name` - submit a quote for review"
+
+// slackResponse is the message Slack shows in reply to a command
+type slackResponse struct {
+	ResponseType string `json:"response_type,omitempty"` // in_channel or ephemeral
+	Text         string `json:"text"`
+}
+
+// slackCommand is the parsed text of a slash command
+type slackCommand struct {
+	Name   string // random, search, by or add
+	Args   string
+	Phrase string // only for add
+	By     string // only for add
+}
+
+// SlackCommand answers a Slack slash command.  Slack signs every request
+// with the app's signing secret, so the raw body has to be checked before
+// anything else parses it.
+// This function is mapped to the path POST /slack/command
+func SlackCommand(c buffalo.Context) error {
+	secret := envy.Get("SLACK_SIGNING_SECRET", "")
+
+	if len(secret) == 0 {
+		return c.Error(http.StatusNotFound, errors.New("slack commands are not configured"))
+	}
+
+	form, err := slackForm(c, func(body []byte) error {
+		return verifySlackSignature(c.Request().Header, body, secret, time.Now())
+	})
+
+	if err != nil {
+		return c.Error(http.StatusUnauthorized, err)
+	}
+
+	return c.Render(200, r.JSON(runSlackCommand(c, form.Get("text"), form.Get("user_name"))))
+}
+
+// SlackWebhook answers a Slack outgoing webhook.  These use the older
+// verification token instead of a signature, and the trigger word comes
+// in front of the command.
+// This function is mapped to the path POST /slack/webhook
+func SlackWebhook(c buffalo.Context) error {
+	token := envy.Get("SLACK_VERIFICATION_TOKEN", "")
+
+	if len(token) == 0 {
+		return c.Error(http.StatusNotFound, errors.New("slack webhooks are not configured"))
+	}
+
+	form, err := slackForm(c, nil)
+
+	if err != nil {
+		return c.Error(http.StatusBadRequest, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(form.Get("token")), []byte(token)) != 1 {
+		return c.Error(http.StatusUnauthorized, errors.New("bad slack verification token"))
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(form.Get("text"), form.Get("trigger_word")))
+	res := runSlackCommand(c, text, form.Get("user_name"))
+
+	// webhooks always post in the channel
+	res.ResponseType = ""
+
+	return c.Render(200, r.JSON(res))
+}
+
+// slackForm reads the raw body, lets check look at it, then parses it
+func slackForm(c buffalo.Context, check func([]byte) error) (url.Values, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, slackMaxBody))
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if check != nil {
+		if err := check(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return url.ParseQuery(string(body))
+}
+
+// verifySlackSignature checks the X-Slack-Signature header, following
+// https://api.slack.com/authentication/verifying-requests-from-slack
+func verifySlackSignature(h http.Header, body []byte, secret string, now time.Time) error {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	secs, err := strconv.ParseInt(ts, 10, 64)
+
+	if err != nil {
+		return errors.New("missing slack request timestamp")
+	}
+
+	age := now.Sub(time.Unix(secs, 0))
+	if age > slackMaxAge || age < -slackMaxAge {
+		return errors.New("slack request is too old")
+	}
+
+	want := slackSignature(secret, ts, body)
+
+	if !hmac.Equal([]byte(h.Get("X-Slack-Signature")), []byte(want)) {
+		return errors.New("bad slack signature")
+	}
+
+	return nil
+}
+
+// slackSignature signs a request the same way Slack does
+func slackSignature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%s:", slackVersion, ts)
+	mac.Write(body)
+
+	return slackVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseSlackCommand works out what was asked for.  Slack likes to turn
+// quotes into curly quotes, so both kinds are understood.
+func parseSlackCommand(text string) slackCommand {
+	text = strings.TrimSpace(text)
+
+	if len(text) == 0 {
+		return slackCommand{Name: "random"}
+	}
+
+	fields := strings.SplitN(text, " ", 2)
+	cmd := slackCommand{Name: strings.ToLower(fields[0])}
+
+	if len(fields) > 1 {
+		cmd.Args = strings.TrimSpace(fields[1])
+	}
+
+	switch cmd.Name {
+	case "by-author", "author":
+		cmd.Name = "by"
+	case "submit":
+		cmd.Name = "add"
+	case "find":
+		cmd.Name = "search"
+	}
+
+	if cmd.Name != "add" {
+		return cmd
+	}
+
+	phrase := cmd.Args
+	if i := strings.LastIndex(phrase, "--by"); i >= 0 {
+		cmd.By = strings.TrimSpace(phrase[i+len("--by"):])
+		phrase = phrase[:i]
+	}
+
+	phrase = strings.TrimSpace(phrase)
+	phrase = strings.TrimPrefix(strings.TrimPrefix(phrase, "\""), "“")
+	phrase = strings.TrimSuffix(strings.TrimSuffix(phrase, "\""), "”")
+	cmd.Phrase = strings.TrimSpace(phrase)
+
+	return cmd
+}
+
+// runSlackCommand does what the command asks and builds the reply
+func runSlackCommand(c buffalo.Context, text, user string) slackResponse {
+	cmd := parseSlackCommand(text)
+
+	var res slackResponse
+	var err error
+
+	switch cmd.Name {
+	case "random":
+		res, err = slackRandom(c)
+	case "search":
+		res, err = slackSearch(c, cmd.Args)
+	case "by":
+		res, err = slackBySpeaker(c, cmd.Args)
+	case "add":
+		res, err = slackSubmit(c, cmd, user)
+	default:
+		return slackResponse{ResponseType: "ephemeral", Text: slackUsage}
+	}
+
+	if err != nil {
+		c.Logger().Errorf("slack %s: %s", cmd.Name, err.Error())
+		return slackResponse{ResponseType: "ephemeral", Text: "Sorry, something went wrong."}
+	}
+
+	return res
+}
+
+// slackRandom picks a quote the same way the quickie wall does
+func slackRandom(c buffalo.Context) (slackResponse, error) {
+	rq := newRequest(c)
+	defer rq.LogMetrics()
+
+	page, err := rq.randomPage()
+
+	if err != nil {
+		return slackResponse{}, err
+	}
+
+	return slackResponse{ResponseType: "in_channel", Text: slackFormatPage(page)}, nil
+}
+
+// slackSearch finds quotes with the words in them
+func slackSearch(c buffalo.Context, words string) (slackResponse, error) {
+	if len(words) == 0 {
+		return slackResponse{ResponseType: "ephemeral", Text: slackUsage}, nil
+	}
+
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return slackResponse{}, errors.New("no transaction found")
+	}
+
+	quotes := models.Quotes{}
+
+	if err := quotes.Search(tx, words, slackSearchLimit); err != nil {
+		return slackResponse{}, err
+	}
+
+	if len(quotes) == 0 {
+		return slackResponse{ResponseType: "ephemeral", Text: "No quotes found with " + slackEscape(words)}, nil
+	}
+
+	var lines []string
+	for _, q := range quotes {
+		lines = append(lines, slackFormatQuote(q.Phrase, q.Author.Name, q.SaidOn.Format("Jan 2, 2006")))
+	}
+
+	return slackResponse{ResponseType: "ephemeral", Text: strings.Join(lines, "\n\n")}, nil
+}
+
+// slackBySpeaker picks a random conversation the speaker was part of
+func slackBySpeaker(c buffalo.Context, name string) (slackResponse, error) {
+	if len(name) == 0 {
+		return slackResponse{ResponseType: "ephemeral", Text: slackUsage}, nil
+	}
+
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return slackResponse{}, errors.New("no transaction found")
+	}
+
+	conv := &models.Conversation{}
+
+	if err := conv.RandomBySpeaker(tx, name); err != nil {
+		return slackResponse{ResponseType: "ephemeral", Text: "No quotes found from " + slackEscape(name)}, nil
+	}
+
+	if err := tx.Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").Find(conv, conv.ID); err != nil {
+		return slackResponse{}, err
+	}
+
+	page := prepareConv(*conv, models.DefaultDisplayProfile(), screenSize{Width: 1920, Height: 1080})
+
+	return slackResponse{ResponseType: "in_channel", Text: slackFormatPage(&page)}, nil
+}
+
+// slackSubmit saves the quote as an unpublished conversation so an admin
+// can look it over before it shows up anywhere
+func slackSubmit(c buffalo.Context, cmd slackCommand, user string) (slackResponse, error) {
+	if len(cmd.Phrase) == 0 || len(cmd.By) == 0 {
+		return slackResponse{ResponseType: "ephemeral", Text: slackUsage}, nil
+	}
+
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return slackResponse{}, errors.New("no transaction found")
+	}
+
+	auths := models.Authors{}
+
+	if err := tx.Where("LOWER(name) = LOWER(?)", cmd.By).All(&auths); err != nil {
+		return slackResponse{}, err
+	}
+
+	if len(auths) == 0 {
+		return slackResponse{ResponseType: "ephemeral", Text: fmt.Sprintf("I don't know anyone named %s, ask an admin to add them.", slackEscape(cmd.By))}, nil
+	}
+
+	now := time.Now()
+	conv := &models.Conversation{
+		OccurredOn: now,
+		Publish:    false,
+		Quotes: models.Quotes{{
+			Phrase:   cmd.Phrase,
+			SaidOn:   now,
+			Publish:  false,
+			AuthorID: auths[0].ID,
+		}},
+	}
+
+	verrs, err := models.NewPopRepository(tx).Conversations.Create(conv)
+
+	if err != nil {
+		return slackResponse{}, err
+	}
+
+	if verrs.HasAny() {
+		return slackResponse{ResponseType: "ephemeral", Text: "That quote couldn't be saved: " + slackEscape(verrs.String())}, nil
+	}
+
+	c.Logger().Infof("slack quote %s submitted by %s", conv.ID, user)
+
+	return slackResponse{ResponseType: "ephemeral", Text: "Thanks! Your quote from " + slackEscape(auths[0].Name) + " is waiting for review."}, nil
+}
+
+// slackFormatPage writes a conversation the way it looks on the wall
+func slackFormatPage(page *pageParams) string {
+	var lines []string
+
+	for _, q := range page.Conversation {
+		lines = append(lines, slackFormatQuote(q.Quote, q.Name, q.Date))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// slackFormatQuote quotes the phrase and puts the speaker under it
+func slackFormatQuote(phrase, name, date string) string {
+	var b strings.Builder
+
+	for _, line := range strings.Split(phrase, "\n") {
+		b.WriteString(">" + slackEscape(line) + "\n")
+	}
+
+	fmt.Fprintf(&b, "— %s, %s", slackEscape(name), date)
+
+	return b.String()
+}
+
+// slackEscape keeps Slack from treating quote text as markup
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
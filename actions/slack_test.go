@@ -0,0 +1,120 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/envy"
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_VerifySlackSignature(t *testing.T) {
+	// the example from Slack's documentation
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	now := time.Unix(1531420618, 0)
+
+	h := http.Header{}
+	h.Set("X-Slack-Request-Timestamp", "1531420618")
+	h.Set("X-Slack-Signature", "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503")
+
+	if err := verifySlackSignature(h, body, secret, now); err != nil {
+		t.Fatalf("verifySlackSignature rejected Slack's example: %s", err.Error())
+	}
+
+	if err := verifySlackSignature(h, body, "wrong secret", now); err == nil {
+		t.Fatal("verifySlackSignature accepted the wrong secret")
+	}
+
+	if err := verifySlackSignature(h, body, secret, now.Add(time.Hour)); err == nil {
+		t.Fatal("verifySlackSignature accepted an old request")
+	}
+
+	h.Del("X-Slack-Request-Timestamp")
+
+	if err := verifySlackSignature(h, body, secret, now); err == nil {
+		t.Fatal("verifySlackSignature accepted a request with no timestamp")
+	}
+}
+
+func Test_ParseSlackCommand(t *testing.T) {
+	tests := []struct {
+		inp  string
+		want slackCommand
+	}{
+		{"", slackCommand{Name: "random"}},
+		{"random", slackCommand{Name: "random"}},
+		{"search free pizza", slackCommand{Name: "search", Args: "free pizza"}},
+		{"by-author Shari", slackCommand{Name: "by", Args: "Shari"}},
+		{`add "I never said that" --by Jeff Smith`, slackCommand{Name: "add", Args: `"I never said that" --by Jeff Smith`, Phrase: "I never said that", By: "Jeff Smith"}},
+		{"submit “curly quotes” --by Shari", slackCommand{Name: "add", Args: "“curly quotes” --by Shari", Phrase: "curly quotes", By: "Shari"}},
+		{"help", slackCommand{Name: "help"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseSlackCommand(tt.inp); got != tt.want {
+			t.Errorf("parseSlackCommand(%s) got %+v, wanted %+v", tt.inp, got, tt.want)
+		}
+	}
+}
+
+// slackPost sends a signed slash command to the app
+func (as *ActionSuite) slackPost(secret, text string) *httptest.ResponseRecorder {
+	body := url.Values{"text": {text}, "user_name": {"roadrunner"}}.Encode()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req := httptest.NewRequest("POST", "/slack/command", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", slackSignature(secret, ts, []byte(body)))
+
+	res := httptest.NewRecorder()
+	as.App.ServeHTTP(res, req)
+
+	return res
+}
+
+func (as *ActionSuite) Test_SlackCommand() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	envy.Temp(func() {
+		envy.Set("SLACK_SIGNING_SECRET", "shh")
+
+		res := as.slackPost("shh", "random")
+		as.Equal(http.StatusOK, res.Code)
+
+		var reply slackResponse
+		as.NoError(json.Unmarshal(res.Body.Bytes(), &reply))
+		as.Equal("in_channel", reply.ResponseType)
+		as.True(strings.HasPrefix(reply.Text, ">"))
+
+		res = as.slackPost("not the secret", "random")
+		as.Equal(http.StatusUnauthorized, res.Code)
+
+		auth := &models.Author{}
+		as.NoError(as.DB.First(auth))
+
+		before, err := as.DB.Count(&models.Conversations{})
+		as.NoError(err)
+
+		res = as.slackPost("shh", `add "it works on my machine" --by `+auth.Name)
+		as.Equal(http.StatusOK, res.Code)
+		as.Contains(res.Body.String(), "waiting for review")
+
+		after, err := as.DB.Count(&models.Conversations{})
+		as.NoError(err)
+		as.Equal(before+1, after)
+
+		// submitted quotes wait for an admin
+		pending := &models.Conversation{}
+		as.NoError(as.DB.Where("publish = FALSE").First(pending))
+	})
+}
@@ -0,0 +1,73 @@
+package actions
+
+import (
+	"strconv"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// how far back the stats go unless asked, and how far they can go
+const statsDays = 30
+const statsMaxDays = 366
+
+// how many displays and conversations get ranked
+const statsTop = 10
+
+// StatsShow shows the usage stats
+// This function is mapped to the path GET /admin/stats
+func StatsShow(c buffalo.Context) error {
+	st, err := loadStats(c)
+
+	if err != nil {
+		return err
+	}
+
+	c.Set("stats", st)
+	c.Set("days", statsRange(c.Param("days")))
+
+	return c.Render(200, r.HTML("admin/stats.html"))
+}
+
+// StatsJSON returns the usage stats for graphing somewhere else
+// This function is mapped to the path GET /admin/stats.json
+func StatsJSON(c buffalo.Context) error {
+	st, err := loadStats(c)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Render(200, r.JSON(st))
+}
+
+func loadStats(c buffalo.Context) (*models.Stats, error) {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return nil, errors.WithStack(errors.New("no transaction found"))
+	}
+
+	days := statsRange(c.Param("days"))
+	since := today().AddDate(0, 0, -days+1)
+
+	st, err := models.LoadStats(tx, since, statsTop)
+
+	return st, countDBError("stats", err)
+}
+
+// statsRange is how many days of stats were asked for
+func statsRange(s string) int {
+	days, err := strconv.Atoi(s)
+
+	if err != nil || days < 1 {
+		return statsDays
+	}
+
+	if days > statsMaxDays {
+		return statsMaxDays
+	}
+
+	return days
+}
@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_statsRange(t *testing.T) {
+	tests := map[string]int{
+		"":     statsDays,
+		"7":    7,
+		"0":    statsDays,
+		"-3":   statsDays,
+		"lots": statsDays,
+		"5000": statsMaxDays,
+	}
+
+	for s, want := range tests {
+		if got := statsRange(s); got != want {
+			t.Errorf("statsRange(%q) got %d, wanted %d", s, got, want)
+		}
+	}
+}
+
+func (as *ActionSuite) Test_Stats() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	as.signInAs(u)
+
+	res := as.HTML("/admin/stats").Get()
+	as.Equal(http.StatusForbidden, res.Code)
+
+	as.NoError(u.Grant(models.DB, models.PermissionAdmin))
+	as.enableTwoFactor(u)
+
+	as.NoError(models.LogMetric(models.DB, 40))
+	as.NoError(models.LogMetric(models.DB, 20))
+
+	res = as.HTML("/admin/stats").Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	jres := as.JSON("/admin/stats.json?days=7").Get()
+	as.Equal(http.StatusOK, jres.Code)
+
+	st := models.Stats{}
+	as.NoError(json.Unmarshal(jres.Body.Bytes(), &st))
+	as.Len(st.Requests, 1)
+	as.Equal(int64(2), st.Requests[0].Count)
+	as.Equal(int64(30), st.Requests[0].AvgMS)
+}
@@ -0,0 +1,197 @@
+package actions
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const wallParam = "wall"    // pick the quote of the day for a named wall
+const historyParam = "days" // how many previous days to show
+const defaultHistory = 7
+const maxHistory = 60
+
+// wallName is the default wall when the request doesn't name one.
+// Each wall gets its own quote of the day.
+var wallName = envy.Get("WALL_NAME", ENV)
+
+// todayPage holds everything shown on the quote of the day page
+type todayPage struct {
+	Day          time.Time            `json:"day"`
+	Wall         string               `json:"wall"`
+	Speaker      string               `json:"speaker,omitempty"`
+	Conversation *models.Conversation `json:"conversation"`
+	History      models.DailyPicks    `json:"history"`
+	OnThisDay    models.Quotes        `json:"on_this_day"`
+}
+
+// Today shows the same conversation to everyone on a given day.
+// This function is mapped to the path GET /today
+func (v ConversationsResource) Today(c buffalo.Context) error {
+	page, err := v.loadToday(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	// plush can't test for a nil pointer, so tell him if there is a pick
+	c.Set("picked", page.Conversation != nil)
+	c.Set("conversation", page.Conversation)
+	c.Set("today", page)
+
+	return c.Render(200, r.HTML("conversations/today.html"))
+}
+
+// TodayJSON returns the quote of the day as JSON.
+// This function is mapped to the path GET /today.json
+func (v ConversationsResource) TodayJSON(c buffalo.Context) error {
+	page, err := v.loadToday(c)
+
+	if err != nil {
+		return c.Error(404, err)
+	}
+
+	return c.Render(200, r.JSON(page))
+}
+
+// loadToday gathers up the pick for today, the picks from previous
+// days and the quotes said on this day in previous years.
+func (v ConversationsResource) loadToday(c buffalo.Context) (*todayPage, error) {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return nil, errors.WithStack(errors.New("no transaction found"))
+	}
+
+	page := &todayPage{
+		Day:  today(),
+		Wall: wallName,
+	}
+
+	if w := c.Param(wallParam); len(w) > 0 {
+		page.Wall = w
+	}
+
+	// a speaker filter, on the request or in the display profile, gets a
+	// pick of its own from just the conversations he is in
+	page.Speaker = strings.ToLower(strings.TrimSpace(newRequest(c).query().Get(speaker)))
+
+	conv, err := pickOfTheDay(tx, page.Day, page.Wall, page.Speaker)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	page.Conversation = conv
+
+	days := defaultHistory
+	if d, err := strconv.Atoi(c.Param(historyParam)); err == nil && d >= 0 {
+		days = d
+	}
+	if days > maxHistory {
+		days = maxHistory
+	}
+
+	if days > 0 {
+		if err := page.History.History(tx, page.Wall, page.Speaker, page.Day, days); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if err := page.OnThisDay.SaidOnThisDay(tx, page.Day); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return page, nil
+}
+
+// pickOfTheDay returns the conversation picked for the wall on the day,
+// out of the ones the speaker is in if there is one.  The first request
+// of the day makes the pick and records it, everybody after that gets
+// the recorded pick.  Returns nil if there is nothing published to pick
+// from.
+func pickOfTheDay(tx *pop.Connection, day time.Time, wall, spkr string) (*models.Conversation, error) {
+	pick := &models.DailyPick{Day: day, Wall: wall, Speaker: spkr}
+
+	err := pick.FindByDay(tx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if pick.ID == uuid.Nil {
+		where := "publish = TRUE"
+		args := []interface{}{}
+
+		// matches names the same way the speaker filter on the wall does
+		if len(spkr) > 0 {
+			where += " AND id IN (SELECT q.conversation_id FROM quotes q JOIN authors a ON a.id = q.author_id WHERE LOWER(a.name) LIKE ?)"
+			args = append(args, "%"+spkr+"%")
+		}
+
+		count, err := tx.Where(where, args...).Count(&models.Conversation{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if count == 0 {
+			return nil, nil
+		}
+
+		conv := models.Conversation{}
+		args = append(args, dailyIndex(day, wall, spkr, count))
+		err = tx.RawQuery("SELECT * FROM conversations WHERE "+where+" ORDER BY id LIMIT 1 OFFSET ?", args...).First(&conv)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pick.ConversationID = conv.ID
+		verrs, err := pick.Claim(tx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if verrs.HasAny() {
+			return nil, errors.New(verrs.String())
+		}
+	}
+
+	conv := &models.Conversation{}
+	err = tx.Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").Find(conv, pick.ConversationID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return conv, nil
+}
+
+// dailyIndex turns the day, wall and speaker into an index in the range
+// [0, count).  The same day, wall and speaker always give the same index.
+func dailyIndex(day time.Time, wall, spkr string, count int) int {
+	if count <= 0 {
+		return 0
+	}
+
+	sum := sha256.Sum256([]byte(wall + spkr + day.Format(ctLayout)))
+
+	return int(binary.BigEndian.Uint64(sum[:8]) % uint64(count))
+}
+
+// today is midnight at the start of the current day
+func today() time.Time {
+	now := time.Now()
+
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
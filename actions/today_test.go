@@ -0,0 +1,121 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_DailyIndex(t *testing.T) {
+	day := time.Date(2020, time.October, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		test  string
+		count int
+	}{
+		{test: "One", count: 1},
+		{test: "Few", count: 3},
+		{test: "Lots", count: 2000},
+	}
+
+	for _, tt := range tests {
+		first := dailyIndex(day, "afaria", "", tt.count)
+
+		if first < 0 || first >= tt.count {
+			t.Fatalf("dailyIndex(%s) got %d, wanted 0-%d\n", tt.test, first, tt.count-1)
+		}
+
+		// same day and wall must always give the same answer
+		again := dailyIndex(day, "afaria", "", tt.count)
+
+		if first != again {
+			t.Fatalf("dailyIndex(%s) changed from %d to %d\n", tt.test, first, again)
+		}
+	}
+
+	if dailyIndex(day, "afaria", "", 0) != 0 {
+		t.Fatal("dailyIndex with no conversations should be zero")
+	}
+}
+
+func (as *ActionSuite) Test_Today() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	res := as.HTML("/today").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "Quote of the Day")
+
+	// everybody gets the same conversation today
+	first := as.JSON("/today.json").Get()
+	second := as.JSON("/today.json").Get()
+
+	as.Equal(http.StatusOK, first.Code)
+	as.Equal(first.Body.String(), second.Body.String())
+}
+
+func (as *ActionSuite) Test_TodaySpeaker() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	res := as.JSON("/today.json?speaker=Shari").Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	page := struct {
+		Speaker      string `json:"speaker"`
+		Conversation struct {
+			Quotes []struct {
+				Author struct {
+					Name string `json:"name"`
+				}
+			}
+		} `json:"conversation"`
+	}{}
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &page))
+
+	// Shari is only in the one conversation
+	as.Equal("shari", page.Speaker)
+	as.Len(page.Conversation.Quotes, 1)
+	as.Equal("Shari Freeman", page.Conversation.Quotes[0].Author.Name)
+
+	// the whole wall still gets a pick of its own
+	res = as.JSON("/today.json").Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	count, err := models.DB.Count(&models.DailyPick{})
+	as.NoError(err)
+	as.Equal(2, count)
+
+	html := as.HTML("/today?speaker=shari").Get()
+	as.Equal(http.StatusOK, html.Code)
+	as.Contains(html.Body.String(), "Picked from the conversations with shari")
+}
+
+func (as *ActionSuite) Test_Anniversary() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	res := as.HTML("/anniversary?window=5").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "On This Day")
+}
+
+func (as *ActionSuite) Test_QuickieAnniversary() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+
+	// the fixture quotes were all said today, so there are no anniversaries
+	res := as.HTML("/quickie?anniversary=true").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), "No quotes on that wall!")
+}
@@ -0,0 +1,390 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
+)
+
+const twoFactorSetupPath = "/account/two-factor"
+
+// what the session holds between the password and the code
+const twoFactorUserKey = "two_factor_user_id"
+const twoFactorStartedKey = "two_factor_started"
+const twoFactorWelcomeKey = "two_factor_welcome"
+
+// the secret being set up, until the first code proves the app has it
+const twoFactorPendingKey = "two_factor_pending"
+
+// how long there is to type in the code after the password
+const twoFactorWindow = 5 * time.Minute
+
+// twoFactorRoles are the roles that can't go without two-factor, set
+// with TWO_FACTOR_ROLES as a comma separated list
+var twoFactorRoles = parseRoles(envy.Get("TWO_FACTOR_ROLES", models.PermissionAdmin))
+
+// the name authenticator apps list the account under
+var totpIssuer = envy.Get("TOTP_ISSUER", "Quote Wall")
+
+// parseRoles splits up a list of role names
+func parseRoles(s string) []string {
+	roles := []string{}
+
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); len(r) > 0 {
+			roles = append(roles, r)
+		}
+	}
+
+	return roles
+}
+
+// askTwoFactor remembers who got the password right and asks for his code
+func askTwoFactor(c buffalo.Context, u *models.User, welcome string) error {
+	c.Session().Set(twoFactorUserKey, u.ID.String())
+	c.Session().Set(twoFactorStartedKey, time.Now().Unix())
+	c.Session().Set(twoFactorWelcomeKey, welcome)
+
+	return c.Redirect(302, "/signin/two-factor")
+}
+
+// pendingTwoFactor loads the user waiting on his code, as long as he
+// hasn't taken too long
+func pendingTwoFactor(c buffalo.Context, tx *pop.Connection) (*models.User, bool) {
+	uid, _ := c.Session().Get(twoFactorUserKey).(string)
+	started, _ := c.Session().Get(twoFactorStartedKey).(int64)
+
+	if len(uid) == 0 || time.Since(time.Unix(started, 0)) > twoFactorWindow {
+		return nil, false
+	}
+
+	u := &models.User{}
+
+	if err := tx.Find(u, uid); err != nil {
+		return nil, false
+	}
+
+	return u, true
+}
+
+// TwoFactorNew asks for the code from the authenticator
+// This function is mapped to the path GET /signin/two-factor
+func TwoFactorNew(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	if _, ok := pendingTwoFactor(c, tx); !ok {
+		c.Flash().Add("danger", "Sign in again to continue")
+		return c.Redirect(302, "/signin")
+	}
+
+	c.Set("user", models.User{})
+	c.Set("errors", validate.NewErrors())
+
+	return c.Render(200, r.HTML("auth/two_factor.html"))
+}
+
+// TwoFactorCreate checks the code, or a recovery code, and finishes
+// signing in.  Wrong codes count toward the lockout like wrong passwords.
+// This function is mapped to the path POST /signin/two-factor
+func TwoFactorCreate(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u, ok := pendingTwoFactor(c, tx)
+
+	if !ok {
+		c.Flash().Add("danger", "Sign in again to continue")
+		return c.Redirect(302, "/signin")
+	}
+
+	now := time.Now()
+
+	bad := func(status int, msg string) error {
+		verrs := validate.NewErrors()
+		verrs.Add("code", msg)
+		c.Set("user", models.User{})
+		c.Set("errors", verrs)
+
+		return c.Render(status, r.HTML("auth/two_factor.html"))
+	}
+
+	until, locked, err := lockedOut(c, u.Email, now)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if locked {
+		logLogin(c, models.LoginLockedOut, u, u.Email)
+		return bad(http.StatusTooManyRequests, "too many failed sign ins, try again after "+until.Format("15:04"))
+	}
+
+	code := c.Param("Code")
+	recovery := false
+
+	passed, err := u.CheckTOTP(tx, code, now)
+
+	if err == nil && !passed {
+		recovery = true
+		passed, err = u.UseRecoveryCode(tx, code, now)
+	}
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !passed {
+		recordFailure(c, u.Email, now)
+		logLogin(c, models.LoginTwoFactorFailed, u, u.Email)
+
+		return bad(http.StatusUnauthorized, "that code didn't work")
+	}
+
+	welcome, _ := c.Session().Get(twoFactorWelcomeKey).(string)
+	c.Session().Delete(twoFactorUserKey)
+	c.Session().Delete(twoFactorStartedKey)
+	c.Session().Delete(twoFactorWelcomeKey)
+
+	clearFailures(c, u.Email)
+
+	if recovery {
+		left, err := u.RecoveryCodesLeft(tx)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		logLogin(c, models.LoginRecoveryUsed, u, u.Email)
+		c.Flash().Add("warning", fmt.Sprintf("You used a recovery code, %d are left", left))
+	}
+
+	return startSession(c, u, welcome)
+}
+
+// AccountTwoFactor shows the two-factor setup.  Until it's turned on a
+// new secret is offered as a QR code for the authenticator app.
+// This function is mapped to the path GET /account/two-factor
+func AccountTwoFactor(c buffalo.Context) error {
+	return renderTwoFactor(c, http.StatusOK, validate.NewErrors(), nil)
+}
+
+// AccountTwoFactorEnable turns two-factor on once a code from the app
+// matches, and hands out the recovery codes
+// This function is mapped to the path POST /account/two-factor
+func AccountTwoFactorEnable(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	key, err := pendingKey(c, u)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	enabled, err := u.EnableTOTP(tx, key.Secret(), c.Param("Code"), time.Now())
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !enabled {
+		verrs := validate.NewErrors()
+		verrs.Add("code", "That code didn't match, check the clock on your phone and try again")
+
+		return renderTwoFactor(c, http.StatusUnprocessableEntity, verrs, nil)
+	}
+
+	codes, err := u.NewRecoveryCodes(tx)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Session().Delete(twoFactorPendingKey)
+	logLogin(c, models.LoginTwoFactorOn, u, u.Email)
+	c.Flash().Add("success", "Two-factor sign in is on")
+
+	return renderTwoFactor(c, http.StatusOK, validate.NewErrors(), codes)
+}
+
+// AccountRecoveryCodes swaps the recovery codes for new ones, a current
+// code from the app is needed
+// This function is mapped to the path POST /account/two-factor/recovery-codes
+func AccountRecoveryCodes(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	passed, err := u.CheckTOTP(tx, c.Param("Code"), time.Now())
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !passed {
+		verrs := validate.NewErrors()
+		verrs.Add("code", "That code didn't work")
+
+		return renderTwoFactor(c, http.StatusUnprocessableEntity, verrs, nil)
+	}
+
+	codes, err := u.NewRecoveryCodes(tx)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Your old recovery codes don't work anymore")
+
+	return renderTwoFactor(c, http.StatusOK, validate.NewErrors(), codes)
+}
+
+// AccountTwoFactorDisable turns two-factor off, the password is needed
+// and it can't be done when the user's role requires it
+// This function is mapped to the path DELETE /account/two-factor
+func AccountTwoFactorDisable(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	required, err := u.TwoFactorRequired(tx, twoFactorRoles)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if required {
+		c.Flash().Add("danger", "Your role requires two-factor sign in")
+		return c.Redirect(302, twoFactorSetupPath)
+	}
+
+	if !u.CheckPassword(c.Param("CurrentPassword")) {
+		verrs := validate.NewErrors()
+		verrs.Add("current_password", "Current password is wrong")
+
+		return renderTwoFactor(c, http.StatusUnprocessableEntity, verrs, nil)
+	}
+
+	if err := u.DisableTOTP(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logLogin(c, models.LoginTwoFactorOff, u, u.Email)
+	c.Flash().Add("success", "Two-factor sign in is off")
+
+	return c.Redirect(302, "/account")
+}
+
+// renderTwoFactor shows the setup page, codes are only passed in right
+// after they're made
+func renderTwoFactor(c buffalo.Context, status int, verrs *validate.Errors, codes []string) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := currentUser(c)
+
+	c.Set("user", u)
+	c.Set("errors", verrs)
+	c.Set("enabled", u.TwoFactorEnabled())
+	c.Set("codes", codes)
+	c.Set("showCodes", len(codes) > 0)
+	c.Set("secret", "")
+	c.Set("qrCode", "")
+	c.Set("codesLeft", 0)
+
+	if u.TwoFactorEnabled() {
+		left, err := u.RecoveryCodesLeft(tx)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		c.Set("codesLeft", left)
+
+		return c.Render(status, r.HTML("account/two_factor.html"))
+	}
+
+	key, err := pendingKey(c, u)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	qr, err := qrDataURI(key)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("secret", key.Secret())
+	c.Set("qrCode", qr)
+
+	return c.Render(status, r.HTML("account/two_factor.html"))
+}
+
+// pendingKey is the secret being set up, made the first time the setup
+// page is shown and kept until it's turned on
+func pendingKey(c buffalo.Context, u *models.User) (*otp.Key, error) {
+	if url, ok := c.Session().Get(twoFactorPendingKey).(string); ok {
+		if key, err := otp.NewKeyFromURL(url); err == nil {
+			return key, nil
+		}
+	}
+
+	key, err := models.NewTOTPKey(u, totpIssuer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.Session().Set(twoFactorPendingKey, key.URL())
+
+	return key, nil
+}
+
+// qrDataURI draws the key as a QR code inline in the page, so the secret
+// never goes anywhere else
+func qrDataURI(key *otp.Key) (string, error) {
+	img, err := key.Image(200, 200)
+
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+
+	if err := png.Encode(&b, img); err != nil {
+		return "", err
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
@@ -0,0 +1,123 @@
+package actions
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/navionguy/quotewall/models"
+	"github.com/pquerna/otp/totp"
+)
+
+func Test_parseRoles(t *testing.T) {
+	tests := map[string]int{
+		"":             0,
+		"admin":        1,
+		"admin, staff": 2,
+		" , admin,,":   1,
+	}
+
+	for s, want := range tests {
+		if got := parseRoles(s); len(got) != want {
+			t.Errorf("parseRoles(%q) got %v, wanted %d roles", s, got, want)
+		}
+	}
+}
+
+// enableTwoFactor turns two-factor on for u so admin pages will let him in
+func (as *ActionSuite) enableTwoFactor(u *models.User) {
+	key, err := models.NewTOTPKey(u, totpIssuer)
+	as.NoError(err)
+
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	as.NoError(err)
+
+	on, err := u.EnableTOTP(models.DB, key.Secret(), code, time.Now())
+	as.NoError(err)
+	as.True(on)
+}
+
+func (as *ActionSuite) Test_TwoFactorSignIn() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	key, err := models.NewTOTPKey(u, totpIssuer)
+	as.NoError(err)
+
+	now := time.Now()
+	code, err := totp.GenerateCode(key.Secret(), now.Add(-30*time.Second))
+	as.NoError(err)
+
+	on, err := u.EnableTOTP(models.DB, key.Secret(), code, now.Add(-30*time.Second))
+	as.NoError(err)
+	as.True(on)
+
+	codes, err := u.NewRecoveryCodes(models.DB)
+	as.NoError(err)
+
+	// the password alone only gets as far as the code
+	res := as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "password"})
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/signin/two-factor", res.Location())
+
+	res = as.HTML("/account").Get()
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/signin", res.Location())
+
+	res = as.HTML("/signin/two-factor").Post(map[string]string{"Code": "000000"})
+	as.Equal(http.StatusUnauthorized, res.Code)
+
+	code, err = totp.GenerateCode(key.Secret(), now)
+	as.NoError(err)
+
+	// back to the page asked for before signing in
+	res = as.HTML("/signin/two-factor").Post(map[string]string{"Code": code})
+	as.Equal(http.StatusFound, res.Code)
+	as.Equal("/account/", res.Location())
+
+	res = as.HTML("/account").Get()
+	as.Equal(http.StatusOK, res.Code)
+
+	// a recovery code works in place of the app, once
+	as.HTML("/signout").Delete()
+
+	as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "password"})
+	res = as.HTML("/signin/two-factor").Post(map[string]string{"Code": codes[0]})
+	as.Equal(http.StatusFound, res.Code)
+
+	left, err := u.RecoveryCodesLeft(models.DB)
+	as.NoError(err)
+	as.Equal(models.RecoveryCodeCount-1, left)
+
+	events := models.LoginEvents{}
+	as.NoError(events.Recent(models.DB, 1))
+	as.Equal(models.LoginSignIn, events[0].Event)
+}
+
+func (as *ActionSuite) Test_TwoFactorLockout() {
+	u := &models.User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(models.DB)
+	as.NoError(err)
+	as.False(verrs.HasAny())
+
+	as.enableTwoFactor(u)
+
+	// the right password between guesses doesn't wipe out the bad codes
+	for i := 0; i < accountLockoutAfter; i++ {
+		res := as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "password"})
+		as.Equal(http.StatusFound, res.Code)
+		as.Equal("/signin/two-factor", res.Location())
+
+		res = as.HTML("/signin/two-factor").Post(map[string]string{"Code": "000000"})
+		as.Equal(http.StatusUnauthorized, res.Code)
+	}
+
+	res := as.HTML("/signin").Post(map[string]string{"Email": "mark@example.com", "Password": "password"})
+	as.Equal(http.StatusTooManyRequests, res.Code)
+
+	_, locked, err := models.LockedOut(models.DB, models.ScopeAccount, "mark@example.com", time.Now())
+	as.NoError(err)
+	as.True(locked)
+}
@@ -0,0 +1,219 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/navionguy/quotewall/mailers"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// userRow is one line of the user list
+type userRow struct {
+	User  models.User
+	Admin bool
+	Self  bool
+}
+
+// UsersList shows every account along with who can manage them
+// This function is mapped to the path GET /users
+func UsersList(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	users := models.Users{}
+
+	if err := tx.Order("email").All(&users); err != nil {
+		return errors.WithStack(err)
+	}
+
+	me := currentUser(c)
+	rows := []userRow{}
+
+	for _, u := range users {
+		admin, err := u.HasPermission(tx, models.PermissionAdmin)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		rows = append(rows, userRow{User: u, Admin: admin, Self: u.ID == me.ID})
+	}
+
+	c.Set("users", rows)
+	c.Set("invite", models.User{})
+
+	return c.Render(200, r.HTML("users/index.html"))
+}
+
+// UsersInvite makes an account for a new email address and mails him a
+// link to pick his password.  The link is shown too, in case the mail
+// doesn't make it.
+// This function is mapped to the path POST /users/invite
+func UsersInvite(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := &models.User{Email: strings.ToLower(strings.TrimSpace(c.Param("Email")))}
+
+	verrs, err := u.Invite(tx)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Flash().Add("danger", verrs.String())
+		return c.Redirect(302, "/users")
+	}
+
+	if c.Param("Admin") == "true" {
+		if err := u.Grant(tx, models.PermissionAdmin); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	raw, _, err := models.NewUserToken(tx, u, models.TokenInvite, inviteTokenTTL, "")
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	link := fmt.Sprintf("%s/invite/%s", siteURL(c), raw)
+
+	if err := mailers.SendInvite(u.Email, link, currentUser(c).Email); err != nil {
+		c.Logger().Errorf("invite to %s: %s", u.Email, err.Error())
+		c.Flash().Add("warning", "The invite email couldn't be sent, pass the link along yourself")
+	}
+
+	c.Flash().Add("success", "Invited "+u.Email+", the link is "+link)
+
+	return c.Redirect(302, "/users")
+}
+
+// UsersAdmin grants or revokes the admin permission.  Admins can't take
+// it away from themselves, so there is always one left.
+// This function is mapped to the path POST /users/{user_id}/admin
+func UsersAdmin(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := &models.User{}
+
+	if err := tx.Find(u, c.Param("user_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if u.ID == currentUser(c).ID {
+		return c.Error(http.StatusForbidden, errors.New("you can't change your own permissions"))
+	}
+
+	var err error
+	if c.Param("Admin") == "true" {
+		err = u.Grant(tx, models.PermissionAdmin)
+	} else {
+		err = u.Revoke(tx, models.PermissionAdmin)
+	}
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Permissions for "+u.Email+" were saved")
+
+	return c.Redirect(302, "/users")
+}
+
+// UsersSignOut signs a user out everywhere, for a lost laptop or a
+// shared screen
+// This function is mapped to the path POST /users/{user_id}/signout
+func UsersSignOut(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := &models.User{}
+
+	if err := tx.Find(u, c.Param("user_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := u.RevokeSessions(tx, uuid.Nil); err != nil {
+		return errors.WithStack(err)
+	}
+
+	logLogin(c, models.LoginRevoked, u, u.Email)
+	c.Flash().Add("success", u.Email+" was signed out everywhere")
+
+	return c.Redirect(302, "/users")
+}
+
+// UsersEvents shows the sign in log for everybody
+// This function is mapped to the path GET /users/events
+func UsersEvents(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	events := models.LoginEvents{}
+
+	if err := events.Recent(tx, loginLogSize*10); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("events", events)
+
+	return c.Render(200, r.HTML("users/events.html"))
+}
+
+// UsersDestroy removes an account.  Admins can't remove themselves.
+// This function is mapped to the path DELETE /users/{user_id}
+func UsersDestroy(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	u := &models.User{}
+
+	if err := tx.Find(u, c.Param("user_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if u.ID == currentUser(c).ID {
+		return c.Error(http.StatusForbidden, errors.New("you can't remove your own account"))
+	}
+
+	if err := u.Destroy(tx); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", u.Email+" was removed")
+
+	return c.Redirect(302, "/users")
+}
+
+// InviteAccept shows the form for picking a first password
+// This function is mapped to the path GET /invite/{token}
+func InviteAccept(c buffalo.Context) error {
+	return tokenForm(c, models.TokenInvite, "invite/accept.html")
+}
+
+// InviteAcceptCreate sets the first password and uses up the invite
+// This function is mapped to the path POST /invite/{token}
+func InviteAcceptCreate(c buffalo.Context) error {
+	return tokenPassword(c, models.TokenInvite, "invite/accept.html", "Welcome to the Quote Wall!")
+}
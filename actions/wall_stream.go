@@ -0,0 +1,339 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	rand "math/rand"
+)
+
+const streamPath = "/quickie/stream"
+const displayParam = "display" // name a display so admins can tell them apart
+const quoteEvent = "quote"
+
+// wallDisplay is one browser listening to the wall stream
+type wallDisplay struct {
+	Name      string
+	Remote    string
+	Connected time.Time
+	Showing   string // first phrase of the conversation on screen
+
+	kick chan bool // tells the display to move on right now
+}
+
+// wallHub keeps track of all the displays streaming the wall and lets
+// an admin push changes out to all of them at once.
+type wallHub struct {
+	sync.Mutex
+	displays map[*wallDisplay]bool
+	pinned   *uuid.UUID
+}
+
+var hub = newWallHub()
+
+func newWallHub() *wallHub {
+	return &wallHub{displays: make(map[*wallDisplay]bool)}
+}
+
+// join adds a display to the hub
+func (h *wallHub) join(d *wallDisplay) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.displays[d] = true
+}
+
+// leave removes a display that has gone away
+func (h *wallHub) leave(d *wallDisplay) {
+	h.Lock()
+	defer h.Unlock()
+
+	delete(h.displays, d)
+}
+
+// advance tells every display to show its next conversation now
+func (h *wallHub) advance() {
+	h.Lock()
+	defer h.Unlock()
+
+	for d := range h.displays {
+		// if he already has a kick waiting, one is enough
+		select {
+		case d.kick <- true:
+		default:
+		}
+	}
+}
+
+// pin puts the same conversation on every display until it is unpinned
+func (h *wallHub) pin(id uuid.UUID) {
+	h.Lock()
+	h.pinned = &id
+	h.Unlock()
+
+	h.advance()
+}
+
+// unpin lets the displays go back to their own shuffled lists
+func (h *wallHub) unpin() {
+	h.Lock()
+	h.pinned = nil
+	h.Unlock()
+
+	h.advance()
+}
+
+// pinnedID returns the pinned conversation, or nil if there isn't one
+func (h *wallHub) pinnedID() *uuid.UUID {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.pinned == nil {
+		return nil
+	}
+
+	id := *h.pinned
+	return &id
+}
+
+// showing records what the display is showing
+func (h *wallHub) showing(d *wallDisplay, phrase string) {
+	h.Lock()
+	defer h.Unlock()
+
+	d.Showing = phrase
+}
+
+// list returns a copy of the connected displays sorted by name
+func (h *wallHub) list() []wallDisplay {
+	h.Lock()
+	defer h.Unlock()
+
+	var displays []wallDisplay
+	for d := range h.displays {
+		displays = append(displays, wallDisplay{
+			Name:      d.Name,
+			Remote:    d.Remote,
+			Connected: d.Connected,
+			Showing:   d.Showing,
+		})
+	}
+
+	sort.Slice(displays, func(i, j int) bool {
+		if displays[i].Name == displays[j].Name {
+			return displays[i].Connected.Before(displays[j].Connected)
+		}
+		return displays[i].Name < displays[j].Name
+	})
+
+	return displays
+}
+
+// WallStream pushes the next conversation to a display using
+// Server-Sent Events.  He accepts all the same filters as QuickieQuote.
+// This function is mapped to the path GET /quickie/stream
+func (v ConversationsResource) WallStream(c buffalo.Context) error {
+	res := c.Response()
+	flusher, ok := res.(http.Flusher)
+
+	if !ok {
+		return c.Error(500, errors.New("streaming not supported"))
+	}
+
+	rq := newRequest(c)
+
+	if err := rq.getShuffleData(); err != nil {
+		return c.Error(404, err)
+	}
+
+	// the blob lives in memory instead of a cookie
+	var blob cookieBlob
+	if err := rq.chkParams(&blob); err != nil {
+		return c.Error(404, err)
+	}
+
+	if len(rq.rqParams) == 0 && curShuffle.Size > 0 {
+		blob.NextQuote = rand.Intn(curShuffle.Size) + 1
+	}
+
+	d := &wallDisplay{
+		Name:      c.Param(displayParam),
+		Remote:    c.Request().RemoteAddr,
+		Connected: time.Now(),
+		kick:      make(chan bool, 1),
+	}
+
+	hub.join(d)
+	defer hub.leave(d)
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	done := c.Request().Context().Done()
+	first := true
+
+	for {
+		refresh, err := rq.pushNext(res, &blob, d)
+
+		if err != nil {
+			// too late to send an error page, he will reconnect
+			c.Logger().Errorf("wall stream: %s", err.Error())
+			return nil
+		}
+		flusher.Flush()
+
+		// count the connection like a page load
+		if first {
+			rq.LogMetrics()
+			first = false
+		}
+
+		timer := time.NewTimer(refresh)
+
+		select {
+		case <-done:
+			timer.Stop()
+			return nil
+		case <-d.kick:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// pushNext writes the next conversation to the stream and returns how
+// long it should stay on the screen.
+func (rq *quickieRequest) pushNext(w io.Writer, blob *cookieBlob, d *wallDisplay) (time.Duration, error) {
+	page, err := rq.nextPage(blob)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(page.Conversation) > 0 {
+		hub.showing(d, page.Conversation[0].Quote)
+	}
+
+	jpage, err := json.Marshal(page)
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", quoteEvent, jpage)
+
+	if err != nil {
+		return 0, err
+	}
+
+	secs, err := strconv.Atoi(page.Refresh)
+
+	if err != nil || secs < 1 {
+		secs = 10
+	}
+
+	return time.Duration(secs) * time.Second, nil
+}
+
+// nextPage picks the next conversation for the display, honoring any
+// conversation an admin has pinned.
+func (rq *quickieRequest) nextPage(blob *cookieBlob) (*pageParams, error) {
+	id := hub.pinnedID()
+
+	if id == nil {
+		// make sure the deck gets reshuffled overnight
+		if err := rq.getShuffleData(); err != nil {
+			return nil, err
+		}
+
+		index := rq.nextIndex(blob)
+
+		if index == -1 {
+			page := setDefaultConversation()
+			page.applyProfile(rq.profile)
+			return page, nil
+		}
+
+		var err error
+		id, err = shuffledConversation(rq.db(), index)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conv, err := loadWallConversation(rq.db(), id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	metricLog.shown(rq.displayName(), conv.ID)
+	page := prepareConv(*conv, rq.profile, rq.screen())
+
+	return &page, nil
+}
+
+// displayName is what the stats call the display making the request, his
+// own name if he has one, otherwise the display profile he is using
+func (rq *quickieRequest) displayName() string {
+	if name := rq.c.Param(displayParam); len(name) > 0 {
+		return name
+	}
+
+	return rq.profile.Name
+}
+
+// randomPage picks a conversation for a one-off request that has no
+// cookie to remember where it was, like an image or an embed.
+func (rq *quickieRequest) randomPage() (*pageParams, error) {
+	if err := rq.getShuffleData(); err != nil {
+		return nil, err
+	}
+
+	var blob cookieBlob
+	if err := rq.chkParams(&blob); err != nil {
+		return nil, err
+	}
+
+	if len(rq.rqParams) == 0 && curShuffle.Size > 0 {
+		blob.NextQuote = rand.Intn(curShuffle.Size) + 1
+	} else if len(blob.FilteredList) > 0 {
+		blob.NextQuote = rand.Intn(len(blob.FilteredList))
+	}
+
+	return rq.nextPage(&blob)
+}
+
+// nextIndex moves through either the shuffled list, or the filtered
+// list if there are filters.  Returns -1 if there is nothing to show.
+func (rq *quickieRequest) nextIndex(blob *cookieBlob) int {
+	if len(rq.rqParams) == 0 {
+		if curShuffle.Size == 0 {
+			return -1
+		}
+		if blob.NextQuote < 1 {
+			blob.NextQuote = 1
+		}
+		return blob.incShuffleIndex()
+	}
+
+	if len(blob.FilteredList) == 0 {
+		return -1
+	}
+
+	return blob.incFilteredIndex()
+}
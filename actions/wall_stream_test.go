@@ -0,0 +1,121 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_WallHub(t *testing.T) {
+	h := newWallHub()
+
+	lobby := &wallDisplay{Name: "lobby", Connected: time.Now(), kick: make(chan bool, 1)}
+	lab := &wallDisplay{Name: "lab", Connected: time.Now(), kick: make(chan bool, 1)}
+
+	h.join(lobby)
+	h.join(lab)
+
+	displays := h.list()
+
+	if len(displays) != 2 || displays[0].Name != "lab" {
+		t.Fatalf("wallHub.list got %v, wanted lab then lobby\n", displays)
+	}
+
+	// two advances only leave one kick waiting
+	h.advance()
+	h.advance()
+
+	for _, d := range []*wallDisplay{lobby, lab} {
+		select {
+		case <-d.kick:
+		default:
+			t.Fatalf("wallHub.advance didn't kick %s\n", d.Name)
+		}
+
+		select {
+		case <-d.kick:
+			t.Fatalf("wallHub.advance kicked %s twice\n", d.Name)
+		default:
+		}
+	}
+
+	id := uuid.New()
+	h.pin(id)
+
+	if got := h.pinnedID(); got == nil || *got != id {
+		t.Fatalf("wallHub.pin got %v, wanted %s\n", got, id)
+	}
+
+	h.unpin()
+
+	if h.pinnedID() != nil {
+		t.Fatal("wallHub.unpin left a pinned conversation")
+	}
+
+	h.leave(lobby)
+
+	if len(h.list()) != 1 {
+		t.Fatal("wallHub.leave didn't remove the display")
+	}
+}
+
+func (as *ActionSuite) Test_DisplaysList() {
+	res := as.HTML("/displays").Get()
+
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Wall Displays")
+}
+
+func (as *ActionSuite) Test_DisplaysPin() {
+	as.LoadFixture("test authors")
+	as.LoadFixture("test conversations")
+	as.LoadFixture("test quotes")
+	defer hub.unpin()
+
+	draft := &models.Conversation{OccurredOn: time.Now()}
+	as.NoError(models.DB.Create(draft))
+
+	empty := &models.Conversation{OccurredOn: time.Now(), Publish: true}
+	as.NoError(models.DB.Create(empty))
+
+	// drafts and empty conversations stay off the public walls
+	for _, conv := range []*models.Conversation{draft, empty} {
+		res := as.HTML("/displays/pin?conversation_id=%s", conv.ID).Post(nil)
+
+		as.Equal(302, res.Code)
+		as.Nil(hub.pinnedID())
+	}
+
+	res := as.HTML("/displays/pin?conversation_id=c341d7cc-b5da-4c1a-a1ae-57b841d4864b").Post(nil)
+
+	as.Equal(302, res.Code)
+	as.NotNil(hub.pinnedID())
+
+	// the pin shows even where the filters match nothing
+	res = as.HTML("/quickie?speaker=Nobody").Get()
+
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "Dan Safstrom quit?")
+}
+
+func Test_PrepareConvEmpty(t *testing.T) {
+	page := prepareConv(models.Conversation{}, models.DefaultDisplayProfile(), screenSize{Width: 1920, Height: 1080})
+
+	if len(page.Conversation) == 0 || page.QuoteShare == 0 {
+		t.Fatalf("prepareConv of an empty conversation got %v, wanted the empty wall\n", page)
+	}
+}
+
+func (as *ActionSuite) Test_DisplayProfilesList() {
+	p := models.DefaultDisplayProfile()
+	p.Name = "lobby"
+	as.NoError(as.DB.Create(p))
+
+	res := as.HTML("/display_profiles").Get()
+
+	as.Equal(200, res.Code)
+	as.Contains(res.Body.String(), "lobby")
+	as.Contains(res.Body.String(), "/quickie?profile=lobby")
+}
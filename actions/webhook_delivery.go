@@ -0,0 +1,287 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const deliverWebhookJob = "deliver_webhook"
+const webhookMaxAttempts = 5
+const webhookFirstRetry = 30 * time.Second // each retry waits four times longer
+const webhookMaxResponse = 4 * 1024        // only enough of the reply to show in the log
+const signatureHeader = "X-Quotewall-Signature"
+const webhookLost = time.Minute // how late an attempt runs before it counts as lost
+
+// the receiver gets a few seconds to answer before it counts as a failure
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookConversation is what subscribers get told about a conversation
+type webhookConversation struct {
+	ID         uuid.UUID      `json:"id"`
+	URL        string         `json:"url"`
+	OccurredOn time.Time      `json:"occurred_on"`
+	Publish    bool           `json:"publish"`
+	Meeting    string         `json:"meeting,omitempty"`
+	Location   string         `json:"location,omitempty"`
+	Topic      string         `json:"topic,omitempty"`
+	Quotes     []webhookQuote `json:"quotes"`
+}
+
+type webhookQuote struct {
+	Phrase string    `json:"phrase"`
+	Author string    `json:"author"`
+	SaidOn time.Time `json:"said_on"`
+	Note   string    `json:"note,omitempty"`
+}
+
+// webhookAuthor is what subscribers get told about an author
+type webhookAuthor struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// fireConversation tells subscribers about a conversation.  The quotes
+// get reloaded so the authors are filled in, unless it is already gone.
+// The reload goes through the request's transaction, so it sees the change
+// that hasn't been committed yet.
+func fireConversation(c buffalo.Context, conv *models.Conversation, events ...string) {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		tx = models.DB
+	}
+
+	loaded := &models.Conversation{}
+
+	if err := tx.Eager("Quotes.Author").Eager("Quotes.Annotations").Find(loaded, conv.ID); err == nil {
+		conv = loaded
+	}
+
+	data := webhookConversation{
+		ID:         conv.ID,
+		URL:        fmt.Sprintf("%s/conversations/%s", siteURL(c), conv.ID),
+		OccurredOn: conv.OccurredOn,
+		Publish:    conv.Publish,
+		Meeting:    conv.Meeting,
+		Location:   conv.Location,
+		Topic:      conv.Topic,
+		Quotes:     []webhookQuote{},
+	}
+
+	for _, q := range conv.Quotes {
+		wq := webhookQuote{Phrase: q.Phrase, Author: q.Author.Name, SaidOn: q.SaidOn, Note: q.Annotations.Text()}
+		data.Quotes = append(data.Quotes, wq)
+	}
+
+	for _, event := range events {
+		fireWebhook(c, event, data)
+	}
+}
+
+// fireAuthor tells subscribers about an author
+func fireAuthor(c buffalo.Context, a *models.Author, event string) {
+	fireWebhook(c, event, webhookAuthor{ID: a.ID, Name: a.Name})
+}
+
+// fireWebhook logs a delivery for every subscription listening for the
+// event and hands them to the worker.  The deliveries are saved with the
+// change that caused them, and only queued once it commits, so a change
+// that gets rolled back never goes out and the worker always finds the
+// delivery.  A webhook that can't be queued never stops the change that
+// caused it.
+func fireWebhook(c buffalo.Context, event string, data interface{}) {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		tx = models.DB
+	}
+
+	subs := models.WebhookSubscriptions{}
+
+	if err := subs.ForEvent(tx, event); err != nil {
+		c.Logger().Errorf("webhook %s: %s", event, err.Error())
+		return
+	}
+
+	for _, s := range subs {
+		d, err := s.NewDelivery(tx, event, data)
+
+		if err != nil {
+			c.Logger().Errorf("webhook %s to %s: %s", event, s.URL, err.Error())
+			continue
+		}
+
+		url := s.URL
+		afterCommit(c, func() {
+			if err := queueDelivery(d.ID, 0); err != nil {
+				c.Logger().Errorf("webhook %s to %s: %s", event, url, err.Error())
+			}
+		})
+	}
+}
+
+// queueDelivery hands a delivery to the worker, after wait has passed
+func queueDelivery(id uuid.UUID, wait time.Duration) error {
+	job := worker.Job{
+		Queue:   "default",
+		Handler: deliverWebhookJob,
+		Args:    worker.Args{"delivery_id": id.String()},
+	}
+
+	if wait > 0 {
+		return app.Worker.PerformIn(job, wait)
+	}
+
+	return app.Worker.Perform(job)
+}
+
+// requeueWebhooks hands the worker every pending delivery whose attempt
+// is well past due.  The worker only keeps its queue in memory, so the
+// retries waiting when the server stopped are gone once it comes back.
+func requeueWebhooks(db *pop.Connection, now time.Time) (int, error) {
+	pending := models.WebhookDeliveries{}
+
+	if err := pending.Pending(db); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	queued := 0
+	for _, d := range pending {
+		if now.Before(webhookDue(d).Add(webhookLost)) {
+			continue
+		}
+
+		if err := queueDelivery(d.ID, 0); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+
+	return queued, nil
+}
+
+// webhookDue is when a pending delivery's next attempt should run
+func webhookDue(d models.WebhookDelivery) time.Time {
+	if d.Attempts == 0 {
+		return d.UpdatedAt
+	}
+
+	return d.UpdatedAt.Add(webhookBackoff(d.Attempts))
+}
+
+// deliverWebhook is the worker job that makes one delivery attempt and
+// records how it went.  Failures get queued up again until the attempts
+// run out.
+func deliverWebhook(args worker.Args) error {
+	id, _ := args["delivery_id"].(string)
+
+	d := &models.WebhookDelivery{}
+
+	if err := models.DB.Find(d, id); err != nil {
+		return errors.WithStack(err)
+	}
+
+	sub := &models.WebhookSubscription{}
+
+	if err := models.DB.Find(sub, d.SubscriptionID); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// a subscription turned off while deliveries were waiting drops them
+	if !sub.Active || d.Status != models.DeliveryPending {
+		return nil
+	}
+
+	d.Attempts++
+	d.StatusCode, d.LastError = 0, ""
+
+	code, err := sendWebhook(webhookClient, sub, d)
+	d.StatusCode = code
+
+	switch {
+	case err == nil:
+		d.Status = models.DeliveryDelivered
+		d.DeliveredAt = nulls.NewTime(time.Now())
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status = models.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.LastError = err.Error()
+	}
+
+	if uerr := models.DB.Update(d); uerr != nil {
+		return errors.WithStack(uerr)
+	}
+
+	if d.Status == models.DeliveryPending {
+		return queueDelivery(d.ID, webhookBackoff(d.Attempts))
+	}
+
+	return nil
+}
+
+// sendWebhook posts the delivery to the subscriber.  Anything other than
+// a 2xx answer counts as a failure.
+func sendWebhook(client *http.Client, sub *models.WebhookSubscription, d *models.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Quotewall-Webhook/1.0")
+	req.Header.Set("X-Quotewall-Event", d.Event)
+	req.Header.Set("X-Quotewall-Delivery", d.ID.String())
+	req.Header.Set(signatureHeader, webhookSignature(sub.Secret, body))
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	reply, _ := ioutil.ReadAll(io.LimitReader(res.Body, webhookMaxResponse))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("%s: %s", res.Status, truncate(string(reply), 200))
+	}
+
+	return res.StatusCode, nil
+}
+
+// webhookSignature signs the body with the subscription secret.
+// Receivers compute the same HMAC and compare it to the header.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is how long to wait after a failed attempt
+func webhookBackoff(attempts int) time.Duration {
+	wait := webhookFirstRetry
+
+	for i := 1; i < attempts; i++ {
+		wait *= 4
+	}
+
+	return wait
+}
@@ -0,0 +1,346 @@
+package actions
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/navionguy/quotewall/models"
+)
+
+func Test_webhookSignature(t *testing.T) {
+	got := webhookSignature("It's a secret to everybody", []byte(`{"event":"author.created"}`))
+
+	if got != webhookSignature("It's a secret to everybody", []byte(`{"event":"author.created"}`)) {
+		t.Errorf("signature isn't stable")
+	}
+
+	if got == webhookSignature("another secret", []byte(`{"event":"author.created"}`)) {
+		t.Errorf("secret didn't change the signature")
+	}
+
+	if len(got) != len("sha256=")+64 || got[:7] != "sha256=" {
+		t.Errorf("signature %s isn't sha256=<hex>", got)
+	}
+}
+
+func Test_webhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, 2 * time.Minute},
+		{3, 8 * time.Minute},
+		{4, 32 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) got %s, wanted %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func Test_sendWebhook(t *testing.T) {
+	sub := &models.WebhookSubscription{Secret: "0123456789abcdef0123456789abcdef"}
+	d := &models.WebhookDelivery{
+		ID:      uuid.Must(uuid.NewV4()),
+		Event:   models.EventConversationPublished,
+		Payload: `{"event":"conversation.published","data":{}}`,
+	}
+
+	secret := sub.Secret // what the receiver was told
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		if req.Header.Get(signatureHeader) != webhookSignature(secret, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if req.Header.Get("X-Quotewall-Event") != d.Event || req.Header.Get("X-Quotewall-Delivery") != d.ID.String() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sub.URL = srv.URL
+
+	code, err := sendWebhook(srv.Client(), sub, d)
+
+	if err != nil || code != http.StatusNoContent {
+		t.Errorf("good delivery got %d, %v", code, err)
+	}
+
+	sub.Secret = "not the secret the receiver knows"
+
+	code, err = sendWebhook(srv.Client(), sub, d)
+
+	if err == nil || code != http.StatusUnauthorized {
+		t.Errorf("badly signed delivery got %d, %v", code, err)
+	}
+}
+
+func (as *ActionSuite) Test_Webhooks() {
+	sub, err := models.NewWebhookSubscription()
+	as.NoError(err)
+	sub.URL = "https://chat.example.com/hooks/quotes"
+	sub.Events = models.EventAuthorCreated
+	as.NoError(as.DB.Create(sub))
+
+	d, err := sub.NewDelivery(models.DB, models.EventAuthorCreated, webhookAuthor{Name: "Shari"})
+	as.NoError(err)
+
+	res := as.HTML("/webhooks").Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), sub.URL)
+
+	res = as.HTML("/webhooks/%s", sub.ID).Get()
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Contains(res.Body.String(), d.ID.String())
+
+	res = as.HTML("/webhooks").Post(map[string]interface{}{
+		"URL":    "ftp://chat.example.com",
+		"Secret": sub.Secret,
+		"Events": "quote.eaten",
+	})
+
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+}
+
+// queuedJobs stands in for the worker and keeps what it was handed
+type queuedJobs struct {
+	worker.Worker
+	jobs []worker.Job
+}
+
+func (q *queuedJobs) Perform(job worker.Job) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func (as *ActionSuite) Test_WebhooksWaitForCommit() {
+	sub, err := models.NewWebhookSubscription()
+	as.NoError(err)
+	sub.URL = "https://chat.example.com/hooks/quotes"
+	sub.Events = models.EventAuthorCreated
+	as.NoError(as.DB.Create(sub))
+
+	queued := &queuedJobs{Worker: app.Worker}
+	app.Worker = queued
+	defer func() { app.Worker = queued.Worker }()
+
+	fire := func(status int) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			fireAuthor(c, &models.Author{Name: "Shari"}, models.EventAuthorCreated)
+			return c.Render(status, r.String("done"))
+		}
+	}
+
+	a := buffalo.New(buffalo.Options{})
+	a.Use(transaction())
+	a.GET("/rolled-back", fire(http.StatusUnprocessableEntity))
+	a.GET("/committed", fire(http.StatusOK))
+
+	// a change that gets rolled back tells no one
+	res := httptest.NewRecorder()
+	a.ServeHTTP(res, httptest.NewRequest("GET", "/rolled-back", nil))
+
+	as.Equal(http.StatusUnprocessableEntity, res.Code)
+	as.Len(queued.jobs, 0)
+
+	count, err := models.DB.Count(&models.WebhookDelivery{})
+	as.NoError(err)
+	as.Equal(0, count)
+
+	res = httptest.NewRecorder()
+	a.ServeHTTP(res, httptest.NewRequest("GET", "/committed", nil))
+
+	as.Equal(http.StatusOK, res.Code)
+	as.Len(queued.jobs, 1)
+	as.Equal(deliverWebhookJob, queued.jobs[0].Handler)
+
+	count, err = models.DB.Count(&models.WebhookDelivery{})
+	as.NoError(err)
+	as.Equal(1, count)
+}
+
+func (as *ActionSuite) Test_WebhooksSeeTheChange() {
+	sub, err := models.NewWebhookSubscription()
+	as.NoError(err)
+	sub.URL = "https://chat.example.com/hooks/quotes"
+	sub.Events = models.EventConversationCreated
+	as.NoError(as.DB.Create(sub))
+
+	author := &models.Author{Name: "Shari"}
+	as.NoError(as.DB.Create(author))
+
+	queued := &queuedJobs{Worker: app.Worker}
+	app.Worker = queued
+	defer func() { app.Worker = queued.Worker }()
+
+	a := buffalo.New(buffalo.Options{})
+	a.Use(transaction())
+	a.GET("/create", func(c buffalo.Context) error {
+		tx := c.Value("tx").(*pop.Connection)
+
+		now := time.Now()
+		conv := &models.Conversation{
+			OccurredOn: now,
+			Quotes:     models.Quotes{{Phrase: "It's a secret to everybody", SaidOn: now, AuthorID: author.ID}},
+		}
+
+		if _, err := models.NewPopRepository(tx).Conversations.Create(conv); err != nil {
+			return err
+		}
+
+		// only the id, the quotes have to come from the reload
+		fireConversation(c, &models.Conversation{ID: conv.ID}, models.EventConversationCreated)
+
+		return c.Render(http.StatusOK, r.String("done"))
+	})
+
+	res := httptest.NewRecorder()
+	a.ServeHTTP(res, httptest.NewRequest("GET", "/create", nil))
+	as.Equal(http.StatusOK, res.Code)
+
+	d := &models.WebhookDelivery{}
+	as.NoError(models.DB.First(d))
+	as.Contains(d.Payload, "It's a secret to everybody")
+	as.Contains(d.Payload, "Shari")
+}
+
+func (as *ActionSuite) Test_ConversationUpdateWebhooks() {
+	sub, err := models.NewWebhookSubscription()
+	as.NoError(err)
+	sub.URL = "https://chat.example.com/hooks/quotes"
+	sub.Events = models.EventConversationUpdated + "," + models.EventConversationPublished
+	as.NoError(as.DB.Create(sub))
+
+	author := &models.Author{Name: "Shari"}
+	as.NoError(models.DB.Create(author))
+
+	now := time.Now()
+	draft := &models.Conversation{OccurredOn: now, Quotes: models.Quotes{
+		{Phrase: "It's a secret", SaidOn: now, AuthorID: author.ID},
+		{Phrase: "to everybody", SaidOn: now, AuthorID: author.ID},
+	}}
+	_, err = models.NewPopRepository(models.DB).Conversations.Create(draft)
+	as.NoError(err)
+
+	before, err := models.DB.Count(&models.Conversation{})
+	as.NoError(err)
+
+	queued := &queuedJobs{Worker: app.Worker}
+	app.Worker = queued
+	defer func() { app.Worker = queued.Worker }()
+
+	form := models.Conversation{OccurredOn: now, Publish: true, Quotes: models.Quotes{
+		{Phrase: "It's a secret to everybody", SaidOn: now, Publish: true, AuthorID: author.ID},
+	}}
+	cvjson, err := form.MarshalConversation()
+	as.NoError(err)
+
+	res := as.HTML("/conversations/%s", draft.ID).Put(map[string]interface{}{
+		"cvjson": cvjson,
+		"option": "save",
+	})
+	as.Equal(http.StatusFound, res.Code)
+
+	// the edit went into the conversation, not a new one
+	after, err := models.DB.Count(&models.Conversation{})
+	as.NoError(err)
+	as.Equal(before, after)
+
+	saved, err := models.NewPopRepository(models.DB).Conversations.Find(draft.ID)
+	as.NoError(err)
+	as.True(saved.Publish)
+	as.Len(saved.Quotes, 1)
+	as.Equal("It's a secret to everybody", saved.Quotes[0].Phrase)
+
+	// and subscribers hear about that conversation
+	deliveries := models.WebhookDeliveries{}
+	as.NoError(models.DB.Order("event").All(&deliveries))
+	as.Len(deliveries, 2)
+
+	for i, event := range []string{models.EventConversationPublished, models.EventConversationUpdated} {
+		as.Equal(event, deliveries[i].Event)
+
+		payload := struct {
+			Data struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"data"`
+		}{}
+		as.NoError(json.Unmarshal([]byte(deliveries[i].Payload), &payload))
+		as.Equal(draft.ID, payload.Data.ID)
+	}
+}
+
+
+func (as *ActionSuite) Test_RequeueWebhooks() {
+	sub, err := models.NewWebhookSubscription()
+	as.NoError(err)
+	sub.URL = "https://chat.example.com/hooks/quotes"
+	sub.Events = models.EventAuthorCreated
+	as.NoError(as.DB.Create(sub))
+
+	off, err := models.NewWebhookSubscription()
+	as.NoError(err)
+	off.URL = "https://chat.example.com/hooks/off"
+	off.Events = models.EventAuthorCreated
+	as.NoError(as.DB.Create(off))
+	off.Active = false
+	as.NoError(models.DB.Update(off))
+
+	fresh, err := sub.NewDelivery(models.DB, models.EventAuthorCreated, webhookAuthor{Name: "Shari"})
+	as.NoError(err)
+
+	retrying, err := sub.NewDelivery(models.DB, models.EventAuthorCreated, webhookAuthor{Name: "George"})
+	as.NoError(err)
+	retrying.Attempts = 3
+	as.NoError(models.DB.Update(retrying))
+
+	delivered, err := sub.NewDelivery(models.DB, models.EventAuthorCreated, webhookAuthor{Name: "Test"})
+	as.NoError(err)
+	delivered.Status = models.DeliveryDelivered
+	as.NoError(models.DB.Update(delivered))
+
+	_, err = off.NewDelivery(models.DB, models.EventAuthorCreated, webhookAuthor{Name: "Dan"})
+	as.NoError(err)
+
+	queued := &queuedJobs{Worker: app.Worker}
+	app.Worker = queued
+	defer func() { app.Worker = queued.Worker }()
+
+	// nothing is overdue yet, the worker still has them
+	n, err := requeueWebhooks(models.DB, time.Now())
+	as.NoError(err)
+	as.Equal(0, n)
+
+	// a few minutes on, the first attempt never ran
+	n, err = requeueWebhooks(models.DB, time.Now().Add(2*time.Minute))
+	as.NoError(err)
+	as.Equal(1, n)
+	as.Equal(fresh.ID.String(), queued.jobs[0].Args["delivery_id"])
+
+	// and once its retry is overdue, the one waiting on a retry goes too
+	queued.jobs = nil
+	n, err = requeueWebhooks(models.DB, time.Now().Add(time.Hour))
+	as.NoError(err)
+	as.Equal(2, n)
+	as.Equal(retrying.ID.String(), queued.jobs[1].Args["delivery_id"])
+}
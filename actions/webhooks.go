@@ -0,0 +1,244 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+const deliveryLogSize = 50
+
+// Following naming logic is implemented in Buffalo:
+// Model: Singular (WebhookSubscription)
+// DB Table: Plural (webhook_subscriptions)
+// Resource: Plural (Webhooks)
+// Path: Plural (/webhooks)
+// View Template Folder: Plural (/templates/webhooks/)
+
+// WebhooksResource is the resource for the WebhookSubscription model
+type WebhooksResource struct {
+	buffalo.Resource
+}
+
+// List gets all WebhookSubscriptions. This function is mapped to the path
+// GET /webhooks
+func (v WebhooksResource) List(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	subs := &models.WebhookSubscriptions{}
+
+	if err := tx.Order("url").All(subs); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("webhooks", subs)
+
+	return c.Render(200, r.HTML("webhooks/index.html"))
+}
+
+// Show lists the recent deliveries for a WebhookSubscription. This
+// function is mapped to the path GET /webhooks/{webhook_id}
+func (v WebhooksResource) Show(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	sub := &models.WebhookSubscription{}
+
+	if err := tx.Find(sub, c.Param("webhook_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	deliveries := &models.WebhookDeliveries{}
+
+	if err := deliveries.Recent(tx, sub.ID, deliveryLogSize); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("webhook", sub)
+	c.Set("deliveries", deliveries)
+
+	return c.Render(200, r.HTML("webhooks/show.html"))
+}
+
+// New renders the form for creating a new WebhookSubscription.
+// This function is mapped to the path GET /webhooks/new
+func (v WebhooksResource) New(c buffalo.Context) error {
+	sub, err := models.NewWebhookSubscription()
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Set("webhook", sub)
+	c.Set("events", models.WebhookEvents)
+
+	return c.Render(200, r.HTML("webhooks/new.html"))
+}
+
+// Create adds a WebhookSubscription to the DB. This function is mapped to
+// the path POST /webhooks
+func (v WebhooksResource) Create(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	sub := &models.WebhookSubscription{}
+
+	// Bind sub to the html form elements
+	if err := c.Bind(sub); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndCreate(sub)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("webhook", sub)
+		c.Set("events", models.WebhookEvents)
+
+		// set the verification errors into the context and send back the webhook
+		c.Set("errors", verrs)
+
+		return c.Render(422, r.HTML("webhooks/new.html"))
+	}
+
+	c.Flash().Add("success", "Webhook was created successfully")
+
+	return c.Redirect(302, "/webhooks")
+}
+
+// Edit renders an edit form for a WebhookSubscription. This function is
+// mapped to the path GET /webhooks/{webhook_id}/edit
+func (v WebhooksResource) Edit(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	sub := &models.WebhookSubscription{}
+
+	if err := tx.Find(sub, c.Param("webhook_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	c.Set("webhook", sub)
+	c.Set("events", models.WebhookEvents)
+
+	return c.Render(200, r.HTML("webhooks/edit.html"))
+}
+
+// Update changes a WebhookSubscription in the DB. This function is mapped
+// to the path PUT /webhooks/{webhook_id}
+func (v WebhooksResource) Update(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	sub := &models.WebhookSubscription{}
+
+	if err := tx.Find(sub, c.Param("webhook_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	// Bind sub to the html form elements
+	if err := c.Bind(sub); err != nil {
+		return errors.WithStack(err)
+	}
+
+	verrs, err := tx.ValidateAndUpdate(sub)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		c.Set("webhook", sub)
+		c.Set("events", models.WebhookEvents)
+
+		// set the verification errors into the context and send back the webhook
+		c.Set("errors", verrs)
+
+		return c.Render(422, r.HTML("webhooks/edit.html"))
+	}
+
+	c.Flash().Add("success", "Webhook was updated successfully")
+
+	return c.Redirect(302, "/webhooks")
+}
+
+// Destroy deletes a WebhookSubscription and its delivery log from the DB.
+// This function is mapped to the path DELETE /webhooks/{webhook_id}
+func (v WebhooksResource) Destroy(c buffalo.Context) error {
+	// Get the DB connection from the context
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	sub := &models.WebhookSubscription{}
+
+	if err := tx.Find(sub, c.Param("webhook_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	if err := tx.Destroy(sub); err != nil {
+		return errors.WithStack(err)
+	}
+
+	c.Flash().Add("success", "Webhook was destroyed successfully")
+
+	return c.Redirect(302, "/webhooks")
+}
+
+// Redeliver sends a logged delivery again, with a fresh set of retries.
+// This function is mapped to the path
+// POST /webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver
+func (v WebhooksResource) Redeliver(c buffalo.Context) error {
+	tx, ok := c.Value("tx").(*pop.Connection)
+	if !ok {
+		return errors.WithStack(errors.New("no transaction found"))
+	}
+
+	d := &models.WebhookDelivery{}
+
+	if err := tx.Where("subscription_id = ?", c.Param("webhook_id")).Find(d, c.Param("delivery_id")); err != nil {
+		return c.Error(404, err)
+	}
+
+	d.Status = models.DeliveryPending
+	d.Attempts = 0
+
+	if err := tx.Update(d); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the worker reads it straight away, so it waits for the commit
+	id := d.ID
+	afterCommit(c, func() {
+		if err := queueDelivery(id, 0); err != nil {
+			c.Logger().Errorf("queueing webhook delivery %s: %s", id, err.Error())
+		}
+	})
+
+	c.Flash().Add("success", "Delivery was queued again")
+
+	return c.Redirect(302, fmt.Sprintf("/webhooks/%s", d.SubscriptionID))
+}
@@ -11,7 +11,10 @@ require (
 	github.com/gobuffalo/mw-forcessl v0.0.0-20200131175327-94b2bd771862
 	github.com/gobuffalo/mw-i18n v1.1.0
 	github.com/gobuffalo/mw-paramlogger v1.0.0
+	github.com/gobuffalo/nulls v0.2.0
+	github.com/gobuffalo/packd v1.0.0
 	github.com/gobuffalo/packr/v2 v2.8.0
+	github.com/gobuffalo/plush v3.8.3+incompatible
 	github.com/gobuffalo/pop/v5 v5.3.1
 	github.com/gobuffalo/suite v2.8.2+incompatible
 	github.com/gobuffalo/suite/v3 v3.0.0
@@ -22,7 +25,12 @@ require (
 	github.com/gorilla/sessions v1.2.1
 	github.com/markbates/grift v1.5.0
 	github.com/pkg/errors v0.9.1
+	github.com/pquerna/otp v1.3.0
+	github.com/prometheus/client_golang v1.5.1
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/sirupsen/logrus v1.5.0
 	github.com/stretchr/testify v1.6.1
 	github.com/unrolled/secure v1.0.8
 	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
+	golang.org/x/image v0.0.0-20201208152932-35266b937fa6
 )
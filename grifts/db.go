@@ -2,7 +2,6 @@ package grifts
 
 import (
 	"errors"
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -36,7 +35,7 @@ var _ = grift.Namespace("db", func() {
 		// look for my aruguement
 
 		for _, arg := range c.Args {
-			fmt.Printf("arg = %s\n", arg)
+			log.Debugf("arg = %s", arg)
 			parts := strings.Split(arg, ":")
 
 			if len(parts) == 2 && strings.Compare(parts[0], vParam) == 0 {
@@ -45,12 +44,14 @@ var _ = grift.Namespace("db", func() {
 					return err
 				}
 
-				setVerbosity(nv)
-				tracemsg(fmt.Sprintf("verbosity set to %d", verbosity), 1)
+				if err := setVerbosity(nv); err != nil {
+					return err
+				}
+				log.Infof("verbosity set to %d", nv)
 			}
 
 			if len(parts) == 2 && strings.Compare(parts[0], srcParam) == 0 {
-				tracemsg(fmt.Sprintf("seeding from file %s", parts[1]), 1)
+				log.Infof("seeding from file %s", parts[1])
 				defer seedQuoteDB(parts[1])
 			}
 		}
@@ -63,11 +64,11 @@ var _ = grift.Namespace("db", func() {
 		// Drop the archive into a json for the online quotewall
 
 		for _, arg := range c.Args {
-			fmt.Printf("arg = %s\n", arg)
+			log.Debugf("arg = %s", arg)
 			parts := strings.Split(arg, ":")
 
 			if len(parts) == 2 && strings.Compare(parts[0], destParam) == 0 {
-				tracemsg(fmt.Sprintf("exporting to file %s", parts[1]), 1)
+				log.Infof("exporting to file %s", parts[1])
 				exportArchive(parts[1])
 			}
 		}
@@ -0,0 +1,75 @@
+package grifts
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/markbates/grift/grift"
+	"github.com/navionguy/quotewall/actions"
+	"github.com/navionguy/quotewall/models"
+)
+
+const digestSpace = "digest"
+const subscribeCmd = "subscribe"
+const everyParam = "every"
+const sendCmd = "send"
+const forceParam = "force"
+
+var _ = grift.Namespace(digestSpace, func() {
+	grift.Desc(subscribeCmd, "Signs a user up for the digest email, example: buffalo task digest:subscribe email:emailaddr every:weekly")
+	grift.Add(subscribeCmd, func(c *grift.Context) error {
+		// Accepts two options
+		// email:emailaddr the users email address
+		// every:daily|weekly|off how often to send the digest
+
+		email, every := "", ""
+
+		for _, arg := range c.Args {
+			parts := strings.Split(arg, ":")
+
+			if len(parts) == 2 && parts[0] == emailParam {
+				email = parts[1]
+			}
+
+			if len(parts) == 2 && parts[0] == everyParam {
+				every = parts[1]
+			}
+		}
+
+		if len(email) == 0 || len(every) == 0 {
+			return errors.New("required parameter not supplied")
+		}
+
+		u := &models.User{}
+
+		if err := models.DB.Where("email = ?", strings.ToLower(email)).First(u); err != nil {
+			return err
+		}
+
+		if every == models.DigestOff {
+			return u.UnsubscribeDigest(models.DB)
+		}
+
+		return u.SubscribeDigest(models.DB, every)
+	})
+
+	grift.Desc(sendCmd, "Sends the digest emails that are due right now, example: buffalo task digest:send force:true")
+	grift.Add(sendCmd, func(c *grift.Context) error {
+		// Accepts one option
+		// force:true sends to every subscriber, due or not
+
+		force := false
+
+		for _, arg := range c.Args {
+			if arg == forceParam+":true" {
+				force = true
+			}
+		}
+
+		sent, err := actions.MailDigests(force)
+
+		log.Infof("sent %d digests", sent)
+
+		return err
+	})
+})
@@ -2,7 +2,6 @@ package grifts
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"strconv"
 
@@ -16,7 +15,7 @@ func exportArchive(dest string) error {
 	f, err := os.Create(dest)
 
 	if err != nil {
-		fmt.Printf("unable to create json file %s, error = %s\n", dest, err.Error())
+		log.Errorf("unable to create json file %s: %s", dest, err.Error())
 		return err
 	}
 
@@ -24,10 +23,10 @@ func exportArchive(dest string) error {
 
 	// Load the whole database
 
-	err = models.DB.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotation").All(&conversations)
+	err = models.DB.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").Eager("Quotes.Annotations.Author").All(&conversations)
 
 	if err != nil {
-		fmt.Printf("query db failed, %s\n", err.Error())
+		log.Errorf("query db failed: %s", err.Error())
 		return err
 	}
 
@@ -38,21 +37,24 @@ func exportArchive(dest string) error {
 	var arc archivetype
 
 	for _, cv := range conversations {
-		var nc conversationtype
+		nc := conversationtype{Meeting: cv.Meeting, Location: cv.Location, Topic: cv.Topic}
 
 		for _, qt := range cv.Quotes {
-			note := ""
-
-			if qt.Annotation != nil {
-				note = qt.Annotation.Note
+			nq := utterancestype{
+				Name:    qt.Author.Name,
+				Quote:   qt.Phrase,
+				Date:    CustomTime{Time: qt.SaidOn},
+				Publish: strconv.FormatBool(qt.Publish),
 			}
 
-			nq := utterancestype{
-				Name:       qt.Author.Name,
-				Quote:      qt.Phrase,
-				Date:       CustomTime{Time: qt.SaidOn},
-				Publish:    strconv.FormatBool(qt.Publish),
-				Annotation: note,
+			for _, a := range qt.Annotations {
+				n := notetype{Kind: a.Kind, Note: a.Note}
+
+				if a.Author != nil {
+					n.Name = a.Author.Name
+				}
+
+				nq.Notes = append(nq.Notes, n)
 			}
 
 			nc.Conversation = append(nc.Conversation, nq)
@@ -64,14 +66,14 @@ func exportArchive(dest string) error {
 	raw, err := json.MarshalIndent(arc, " ", "   ")
 
 	if err != nil {
-		fmt.Printf("marshal failed with %s\n", err.Error())
+		log.Errorf("marshal failed: %s", err.Error())
 		return err
 	}
 
 	_, err = f.Write(raw)
 
 	if err != nil {
-		fmt.Printf("write failed with %s\n", err.Error())
+		log.Errorf("write failed: %s", err.Error())
 	}
 
 	return err
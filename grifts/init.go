@@ -3,8 +3,12 @@ package grifts
 import (
 	"github.com/gobuffalo/buffalo"
 	"github.com/navionguy/quotewall/actions"
+	"github.com/navionguy/quotewall/logging"
 )
 
+// log is the grifts' logger, v: on db:seed turns it up
+var log = logging.For("grifts")
+
 func init() {
 	buffalo.Grifts(actions.App())
 }
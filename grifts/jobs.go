@@ -0,0 +1,43 @@
+package grifts
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/markbates/grift/grift"
+	"github.com/navionguy/quotewall/actions"
+)
+
+const jobsSpace = "jobs"
+const runCmd = "run"
+const nameParam = "name"
+
+var _ = grift.Namespace(jobsSpace, func() {
+	grift.Desc(runCmd, "Runs one of the scheduled jobs right now, example: buffalo task jobs:run name:backup")
+	grift.Add(runCmd, func(c *grift.Context) error {
+		// Accepts one option
+		// name:job reshuffle, rotate_requests, purge, digest or backup
+
+		name := ""
+
+		for _, arg := range c.Args {
+			parts := strings.Split(arg, ":")
+
+			if len(parts) == 2 && parts[0] == nameParam {
+				name = parts[1]
+			}
+		}
+
+		if len(name) == 0 {
+			return errors.New("required parameter not supplied")
+		}
+
+		ran, err := actions.RunJob(name)
+
+		if err == nil && !ran {
+			log.Warnf("%s is running somewhere else", name)
+		}
+
+		return err
+	})
+})
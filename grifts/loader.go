@@ -3,12 +3,12 @@ package grifts
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/navionguy/quotewall/logging"
 	"github.com/navionguy/quotewall/models"
 )
 
@@ -41,7 +41,7 @@ func (ct *CustomTime) UnmarshalJSON(b []byte) (err error) {
 	}
 
 	s := string(b)
-	fmt.Printf("unmarshaling %s\n", s)
+	log.Debugf("unmarshaling %s", s)
 
 	parts := strings.Split(s, "/")
 
@@ -52,7 +52,7 @@ func (ct *CustomTime) UnmarshalJSON(b []byte) (err error) {
 	ct.Time, err = time.Parse(ctLayout, string(b))
 
 	if err != nil {
-		fmt.Printf("unmarshal got %s\n", err)
+		log.Errorf("unmarshal got %s", err)
 	}
 	return
 }
@@ -72,10 +72,21 @@ type utterancestype struct {
 	Quote      string
 	Date       CustomTime
 	Publish    string
-	Annotation string
+	Annotation string     `json:",omitempty"` // older files had one note per quote
+	Notes      []notetype `json:",omitempty"`
+}
+
+// notetype is one annotation on a quote, Name is who wrote it
+type notetype struct {
+	Kind string
+	Note string
+	Name string `json:",omitempty"`
 }
 
 type conversationtype struct {
+	Meeting      string `json:",omitempty"`
+	Location     string `json:",omitempty"`
+	Topic        string `json:",omitempty"`
 	Conversation []utterancestype
 }
 
@@ -136,10 +147,13 @@ func createConversation(cv conversationtype) error {
 	conv := &models.Conversation{}
 	conv.OccurredOn = cv.Conversation[0].Date.Time
 	conv.Publish = (strings.Compare("true", strings.ToLower(cv.Conversation[0].Publish)) == 0)
-	tracemsg(fmt.Sprintf("creating conversation, publish = %v, src = %s", conv.Publish, cv.Conversation[0].Publish), 4)
+	conv.Meeting = cv.Meeting
+	conv.Location = cv.Location
+	conv.Topic = cv.Topic
+	log.Debugf("creating conversation, publish = %v, src = %s", conv.Publish, cv.Conversation[0].Publish)
 
 	if err := models.DB.Create(conv); err != nil {
-		fmt.Printf("CreateConversation got error %s\n", err)
+		log.Errorf("createConversation got error %s", err)
 		return err
 	}
 
@@ -162,7 +176,7 @@ func createConversation(cv conversationtype) error {
 // Everything else, he pulls out of the "utterance"
 //
 func createQuote(cv uuid.UUID, sequence int, qt utterancestype) error {
-	tracemsg(fmt.Sprintf("creating quote %s", qt.Quote), 4)
+	log.Debugf("creating quote %s", qt.Quote)
 
 	// find or create the ID for the author
 	authID, err := findOrCreateAuthor(qt.Name)
@@ -176,38 +190,65 @@ func createQuote(cv uuid.UUID, sequence int, qt utterancestype) error {
 	// create the quote with as much stuff as we know
 
 	aQuote := &models.Quote{
-		SaidOn:         qt.Date.Time,
-		Sequence:       sequence,
-		Phrase:         qt.Quote,
-		AuthorID:       authID,
-		Publish:        strings.Compare("true", strings.ToLower(qt.Publish)) == 0,
-		ConversationID: cv,
-		Annotation:     nil,
-		AnnotationID:   nil,
+		SaidOn:   qt.Date.Time,
+		Sequence: sequence,
+		Phrase:   qt.Quote,
+		AuthorID: authID,
+		Publish:  strings.Compare("true", strings.ToLower(qt.Publish)) == 0,
 	}
 
-	// if quote is annotated, find or create an ID for that
+	aQuote.Annotations, err = buildAnnotations(qt)
 
-	if len(qt.Annotation) > 0 {
-		aQuote.AnnotationID, err = findOrCreateAnnotation(qt.Annotation)
-
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("about to create")
+	verrs, err := models.NewPopRepository(models.DB).Quotes.Create(aQuote, cv)
 
-	err = models.DB.Create(aQuote)
+	if err == nil && verrs.HasAny() {
+		err = errors.New(verrs.Error())
+	}
 
 	if err != nil {
-		fmt.Printf("createQuote failed with error %s\n", err)
+		log.Errorf("createQuote failed with error %s", err)
 		return err
 	}
 
 	return nil
 }
 
+// buildAnnotations()
+//
+// Turns the notes on an utterance into annotations.  An old style
+// Annotation becomes the first note, as context.  Anyone named as
+// writing a note gets found or created like the authors are.
+//
+func buildAnnotations(qt utterancestype) (models.Annotations, error) {
+	var annotations models.Annotations
+
+	if len(qt.Annotation) > 0 {
+		annotations = append(annotations, models.Annotation{Kind: models.AnnotationContext, Note: qt.Annotation})
+	}
+
+	for _, n := range qt.Notes {
+		a := models.Annotation{Kind: n.Kind, Note: n.Note}
+
+		if len(n.Name) > 0 {
+			id, err := findOrCreateAuthor(n.Name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			a.AuthorID = &id
+		}
+
+		annotations = append(annotations, a)
+	}
+
+	return annotations, nil
+}
+
 // findOrCreateAuthor()
 //
 // Try to find the ID for the Author passed.  If you can't, create one.
@@ -220,30 +261,28 @@ func findOrCreateAuthor(author string) (uuid.UUID, error) {
 
 	// got him from the cache!
 	if id != uuid.Nil {
-		tracemsg(fmt.Sprintf("found author %s in the cache", author), 4)
+		log.Debugf("found author %s in the cache", author)
 		return id, nil
 	}
 
 	// try the database
 
-	authRecs := []models.Author{}
-	query := models.DB.Where(fmt.Sprintf("name = '%s'", author))
-	err := query.All(&authRecs)
+	found, err := models.NewPopRepository(models.DB).Authors.FindByName(author)
 
-	if err != nil {
-		fmt.Printf("Author query returns an error: %s.", err)
+	if err != nil && err != models.ErrNotFound {
+		log.Errorf("author query returned an error: %s", err)
 		return id, err
 	}
 
-	if len(authRecs) > 0 {
-		tracemsg(fmt.Sprintf("found author %s in database at %s", authRecs[0].Name, authRecs[0].ID), 4)
+	if err == nil {
+		log.Debugf("found author %s in database at %s", found.Name, found.ID)
 
 		// and add to the cache
 
-		authorCache[authRecs[0].Name] = authRecs[0].ID
-		tracemsg(fmt.Sprintf("added author %s to cache", authRecs[0].Name), 3)
+		authorCache[author] = found.ID
+		log.Debugf("added author %s to cache", author)
 
-		return authRecs[0].ID, nil
+		return found.ID, nil
 	}
 
 	id, err = createAuthor(author)
@@ -254,48 +293,11 @@ func findOrCreateAuthor(author string) (uuid.UUID, error) {
 	// and add to the cache
 
 	authorCache[author] = id
-	tracemsg(fmt.Sprintf("added new author %s to cache", author), 3)
+	log.Debugf("added new author %s to cache", author)
 
 	return id, nil
 }
 
-// findOrCreateAnnotation()
-//
-// Try to find the ID for the passed Annotation.  If you can't create one.
-//
-
-func findOrCreateAnnotation(annotation string) (*uuid.UUID, error) {
-	annotateRecs := []models.Annotation{}
-	query := models.DB.Where(fmt.Sprintf("note = '%s'", annotation))
-	err := query.All(&annotateRecs)
-
-	if err != nil {
-		fmt.Printf("annotation query returned an error: %s\n", err)
-		return nil, err
-	}
-
-	// if I found him, just return his id
-
-	if len(annotateRecs) > 0 {
-		id := annotateRecs[0].ID
-
-		return &id, nil
-	}
-
-	// create a new annotaton
-
-	id, err := createAnnotation(annotation)
-
-	if err != nil {
-		fmt.Printf("createAnnotation() returned an error: %s\n", err)
-		return nil, err
-	}
-
-	tracemsg(fmt.Sprintf("added annotation %s to database", annotation), 4)
-
-	return &id, nil
-}
-
 // createAuthor() - Create his record in the database
 //
 // Creates a ID value for the author and then writes a record into the database
@@ -309,33 +311,11 @@ func createAuthor(author string) (uuid.UUID, error) {
 
 	err := models.DB.Create(&rec)
 	if err != nil {
-		fmt.Printf("author create failed with %s\n", err)
+		log.Errorf("author create failed with %s", err)
 		return uuid.Nil, err
 	}
 
-	tracemsg(fmt.Sprintf("adding author %s with ID %s", rec.Name, rec.ID), 4)
-
-	return rec.ID, nil
-}
-
-// createAnnotation() -- Create an annotation record in the database
-//
-// Stores the annotation string into the database and returns its ID.
-//
-func createAnnotation(annotation string) (uuid.UUID, error) {
-
-	// create new object with the annotation text
-	rec := models.Annotation{
-		Note: annotation,
-	}
-
-	err := models.DB.Create(&rec)
-	if err != nil {
-		fmt.Printf("annotation create failed with %s\n", err)
-		return uuid.Nil, err
-	}
-
-	tracemsg(fmt.Sprintf("adding annotation %s with ID %s", rec.Note, rec.ID), 4)
+	log.Debugf("adding author %s with ID %s", rec.Name, rec.ID)
 
 	return rec.ID, nil
 }
@@ -352,33 +332,25 @@ func loadquotedata(filename string) error {
 		return e
 	}
 	json.Unmarshal(file, &quotes)
-	tracemsg(fmt.Sprintf("found %d quotes", len(quotes.Quotearchive.Conversations)), 1)
+	log.Infof("found %d quotes", len(quotes.Quotearchive.Conversations))
 
 	return nil
 }
 
-var verbosity int
-
-func init() {
-	verbosity = 0
-}
-
-// tracemsg()
+// setVerbosity()
 //
-// Decides to show, or not show, a trace message based on the message trace level
-// versus the specified verbosity
+// Turns the grifts logging up or down to match the v: level from the
+// command line.  1 and 2 show the progress, 3 and up every record.
 //
-func tracemsg(msg string, lvl int) {
-	if lvl <= verbosity {
-		fmt.Println(msg)
+func setVerbosity(v int) error {
+	level := "warning"
+
+	switch {
+	case v >= 3:
+		level = "debug"
+	case v >= 1:
+		level = "info"
 	}
-}
 
-// setVerbosity()
-//
-// Sets the verboisty level for tracing,
-// should be based on command line
-//
-func setVerbosity(v int) {
-	verbosity = v
+	return logging.SetLevel("grifts", level)
 }
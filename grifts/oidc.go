@@ -0,0 +1,62 @@
+package grifts
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/markbates/grift/grift"
+	"github.com/navionguy/quotewall/oidctest"
+)
+
+const oidcSpace = "oidc"
+const mockCmd = "mock"
+const addrParam = "addr"
+const clientParam = "client"
+const groupsParam = "groups"
+
+var _ = grift.Namespace(oidcSpace, func() {
+	grift.Desc(mockCmd, "Runs a mock OpenID Connect provider for trying out single sign-on, example: buffalo task oidc:mock email:emailaddr groups:admins,editors")
+	grift.Add(mockCmd, func(c *grift.Context) error {
+		// Accepts four options
+		// addr:host:port where to listen, 127.0.0.1:5556 if not given
+		// client:id the client id the app is set up with, quotewall if not given
+		// email:emailaddr who gets signed in
+		// groups:a,b the groups he is in
+
+		addr, client := "127.0.0.1:5556", "quotewall"
+		user := oidctest.User{Email: "mock@example.com", EmailVerified: true}
+
+		for _, arg := range c.Args {
+			parts := strings.SplitN(arg, ":", 2)
+
+			if len(parts) != 2 {
+				continue
+			}
+
+			switch parts[0] {
+			case addrParam:
+				addr = parts[1]
+			case clientParam:
+				client = parts[1]
+			case emailParam:
+				user.Email = parts[1]
+			case groupsParam:
+				user.Groups = strings.Split(parts[1], ",")
+			}
+		}
+
+		iss, err := oidctest.NewIssuer(client)
+
+		if err != nil {
+			return err
+		}
+
+		user.Subject = "mock-" + user.Email
+		iss.User = user
+		iss.URL = "http://" + addr
+
+		log.Infof("mock provider at %s, start the app with OIDC_ISSUER=%s OIDC_CLIENT_ID=%s", iss.URL, iss.URL, client)
+
+		return http.ListenAndServe(addr, iss)
+	})
+})
@@ -2,7 +2,6 @@ package grifts
 
 import (
 	"errors"
-	"fmt"
 	"strings"
 
 	"github.com/markbates/grift/grift"
@@ -15,16 +14,18 @@ const nameSpace = "user"
 const addCmd = "add"
 const emailParam = "email"
 const pwdParam = "pwd"
+const adminParam = "admin"
 
 const rmvCmd = "rmv"
 
 var _ = grift.Namespace(nameSpace, func() {
 	// "add" creates a new user in the database
-	grift.Desc(addCmd, "Adds a user account for working with quotes, example: buffalo task user:add email:emailaddr pwd:initialpassword")
+	grift.Desc(addCmd, "Adds a user account for working with quotes, example: buffalo task user:add email:emailaddr pwd:initialpassword admin:true")
 	grift.Add(addCmd, func(c *grift.Context) error {
-		// Accpets two options
+		// Accpets three options
 		// email:emailaddr the users email address
 		// pwd:initalpassword password to set on the account, users can change this
+		// admin:true lets the user manage the other accounts
 
 		if len(c.Args) == 0 {
 			return errors.New("no valid arguements to user:add")
@@ -33,11 +34,11 @@ var _ = grift.Namespace(nameSpace, func() {
 		u := models.User{}
 		u.Email = ""
 		u.Password = ""
+		admin := false
 
 		// look for my arguements
 
 		for _, arg := range c.Args {
-			fmt.Printf("arg = %s\n", arg)
 			parts := strings.Split(arg, ":")
 
 			if len(parts) == 2 && strings.Compare(parts[0], emailParam) == 0 {
@@ -47,6 +48,10 @@ var _ = grift.Namespace(nameSpace, func() {
 			if len(parts) == 2 && strings.Compare(parts[0], pwdParam) == 0 {
 				u.Password = parts[1]
 			}
+
+			if len(parts) == 2 && strings.Compare(parts[0], adminParam) == 0 {
+				admin = parts[1] == "true"
+			}
 		}
 
 		if len(u.Email) == 0 || len(u.Password) == 0 {
@@ -59,11 +64,19 @@ var _ = grift.Namespace(nameSpace, func() {
 		u.PasswordConfirmation = u.Password
 		verrs, err := u.Create(models.DB)
 
+		if err != nil {
+			return err
+		}
+
 		if verrs.HasAny() {
 			return errors.New("user parameters failed validation")
 		}
 
-		return err
+		if admin {
+			return u.Grant(models.DB, models.PermissionAdmin)
+		}
+
+		return nil
 	})
 
 	grift.Desc(rmvCmd, "Removes a user account from the quotes archive, example: buffalo task user:rmv email:emailaddr")
@@ -81,7 +94,6 @@ var _ = grift.Namespace(nameSpace, func() {
 		// look for my arguements
 
 		for _, arg := range c.Args {
-			fmt.Printf("arg = %s\n", arg)
 			parts := strings.Split(arg, ":")
 
 			if len(parts) == 2 && strings.Compare(parts[0], emailParam) == 0 {
@@ -101,6 +113,6 @@ var _ = grift.Namespace(nameSpace, func() {
 			return err
 		}
 
-		return models.DB.Destroy(u)
+		return u.Destroy(models.DB)
 	})
 })
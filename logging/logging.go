@@ -0,0 +1,239 @@
+// Package logging is the one logger the whole app writes to.  Every
+// package gets its own through For, so each one's level can be turned up
+// or down by itself while the app is running.
+//
+// LOG_FORMAT picks json or logfmt, LOG_LEVEL sets the level for every
+// package and LOG_LEVELS overrides it for some, like "models=debug,pop=warn".
+package logging
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/logger"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Default is the name Levels and SetLevel use for the level every package
+// starts out at
+const Default = "default"
+
+var (
+	mu        sync.Mutex
+	loggers   = map[string]*logrus.Logger{}
+	overrides = map[string]logrus.Level{}
+	base      = logrus.InfoLevel
+)
+
+var formatter logrus.Formatter = &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}
+var out io.Writer = os.Stdout
+
+func init() {
+	env := envy.Get("GO_ENV", "development")
+
+	// the SQL pop runs has always shown up while developing
+	levels := ""
+	if env == "development" {
+		levels = "pop=debug"
+	}
+
+	err := Configure(envy.Get("LOG_FORMAT", "logfmt"), envy.Get("LOG_LEVEL", "info"), envy.Get("LOG_LEVELS", levels))
+
+	if err != nil {
+		For("logging").Errorf("bad logging setup, using the defaults: %s", err.Error())
+	}
+}
+
+// Configure sets the format and levels, everything already handed out
+// picks up the change
+func Configure(format, level, levels string) error {
+	f, err := parseFormat(format)
+
+	if err != nil {
+		return err
+	}
+
+	lvl, err := logrus.ParseLevel(strings.TrimSpace(level))
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	per, err := parseLevels(levels)
+
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	formatter = f
+	base = lvl
+	overrides = per
+
+	for pkg, l := range loggers {
+		l.SetFormatter(formatter)
+		l.SetLevel(levelOf(pkg))
+	}
+
+	return nil
+}
+
+// For returns the logger for a package, every line it writes carries the
+// package's name
+func For(pkg string) *logrus.Entry {
+	return loggerFor(pkg).WithField("pkg", pkg)
+}
+
+// Buffalo wraps the package's logger up for buffalo.Options
+func Buffalo(pkg string) logger.FieldLogger {
+	return logger.Logrus{FieldLogger: For(pkg)}
+}
+
+// SetLevel changes a package's level.  Setting Default changes every
+// package that hasn't been given its own, and setting a package to
+// Default puts it back to following that.
+func SetLevel(pkg, level string) error {
+	level = strings.TrimSpace(level)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if level == Default && pkg != Default {
+		delete(overrides, pkg)
+	} else {
+		lvl, err := logrus.ParseLevel(level)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if pkg == Default {
+			base = lvl
+		} else {
+			overrides[pkg] = lvl
+		}
+	}
+
+	for name, l := range loggers {
+		l.SetLevel(levelOf(name))
+	}
+
+	return nil
+}
+
+// Level is what a package is logging at, as a Level
+type Level struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+	Own     bool   `json:"own"`
+}
+
+// Levels lists the default and every package that has logged so far, or
+// been given a level of its own
+func Levels() []Level {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := map[string]bool{}
+	for pkg := range loggers {
+		names[pkg] = true
+	}
+	for pkg := range overrides {
+		names[pkg] = true
+	}
+
+	list := []Level{}
+
+	for pkg := range names {
+		_, own := overrides[pkg]
+		list = append(list, Level{Package: pkg, Level: levelOf(pkg).String(), Own: own})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Package < list[j].Package })
+
+	return append([]Level{{Package: Default, Level: base.String(), Own: true}}, list...)
+}
+
+// SetOutput sends every package's logging to w
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	out = w
+
+	for _, l := range loggers {
+		l.SetOutput(w)
+	}
+}
+
+func loggerFor(pkg string) *logrus.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if l, ok := loggers[pkg]; ok {
+		return l
+	}
+
+	l := logrus.New()
+	l.SetOutput(out)
+	l.SetFormatter(formatter)
+	l.SetLevel(levelOf(pkg))
+	loggers[pkg] = l
+
+	return l
+}
+
+// levelOf needs mu held
+func levelOf(pkg string) logrus.Level {
+	if lvl, ok := overrides[pkg]; ok {
+		return lvl
+	}
+
+	return base
+}
+
+// parseFormat knows json and logfmt, which is logrus' text without colors
+func parseFormat(s string) (logrus.Formatter, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	case "logfmt", "text", "":
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	}
+
+	return nil, errors.Errorf("unknown log format %q", s)
+}
+
+// parseLevels reads a list like "models=debug, pop=warn"
+func parseLevels(s string) (map[string]logrus.Level, error) {
+	levels := map[string]logrus.Level{}
+
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); len(part) == 0 {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+			return nil, errors.Errorf("%q isn't package=level", part)
+		}
+
+		lvl, err := logrus.ParseLevel(strings.TrimSpace(kv[1]))
+
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		levels[strings.TrimSpace(kv[0])] = lvl
+	}
+
+	return levels, nil
+}
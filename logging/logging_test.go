@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_parseLevels(t *testing.T) {
+	levels, err := parseLevels(" models=debug, pop=warn,,")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(levels) != 2 || levels["models"].String() != "debug" || levels["pop"].String() != "warning" {
+		t.Errorf("got %v", levels)
+	}
+
+	for _, bad := range []string{"models", "=debug", "models=loud"} {
+		if _, err := parseLevels(bad); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+func Test_Levels(t *testing.T) {
+	var b bytes.Buffer
+	SetOutput(&b)
+	defer SetOutput(os.Stdout)
+
+	if err := Configure("json", "info", "test_quiet=error"); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure("logfmt", "info", "")
+
+	loud, quiet := For("test_loud"), For("test_quiet")
+
+	quiet.Info("hidden")
+	loud.WithField("request_id", "abc").Info("shown")
+
+	line := map[string]interface{}{}
+	if err := json.Unmarshal(b.Bytes(), &line); err != nil {
+		t.Fatalf("%q isn't one json line: %s", b.String(), err)
+	}
+
+	if line["msg"] != "shown" || line["pkg"] != "test_loud" || line["request_id"] != "abc" {
+		t.Errorf("got %v", line)
+	}
+
+	// turning the default down leaves a package with its own level alone
+	if err := SetLevel(Default, "error"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetLevel("test_quiet", "debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Reset()
+	loud.Info("hidden")
+	quiet.Debug("shown")
+
+	if strings.Count(b.String(), "\n") != 1 || !strings.Contains(b.String(), "test_quiet") {
+		t.Errorf("got %q", b.String())
+	}
+
+	if err := SetLevel("test_quiet", Default); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, l := range Levels() {
+		if l.Package == "test_quiet" && (l.Own || l.Level != "error") {
+			t.Errorf("test_quiet is still %v", l)
+		}
+	}
+
+	if err := SetLevel("test_quiet", "loud"); err == nil {
+		t.Error("set a made up level")
+	}
+}
@@ -0,0 +1,38 @@
+package mailers
+
+import (
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/pkg/errors"
+)
+
+// SendPasswordReset mails the link for picking a new password
+func SendPasswordReset(to, link string) error {
+	return sendAccountMail(to, "Reset your Quote Wall password", "password_reset", render.Data{"link": link})
+}
+
+// SendEmailConfirmation mails the link that finishes an email change to
+// the new address, which proves the user can read it
+func SendEmailConfirmation(to, link string) error {
+	return sendAccountMail(to, "Confirm your new Quote Wall email address", "confirm_email", render.Data{"link": link})
+}
+
+// SendInvite mails the link for setting up an invited account
+func SendInvite(to, link, invitedBy string) error {
+	return sendAccountMail(to, "You're invited to the Quote Wall", "invite", render.Data{"link": link, "invitedBy": invitedBy})
+}
+
+// sendAccountMail renders the named template in both text and HTML
+func sendAccountMail(to, subject, name string, data render.Data) error {
+	m := mail.NewMessage()
+
+	m.From = from
+	m.To = []string{to}
+	m.Subject = subject
+
+	if err := m.AddBodies(data, r.Plain(name+".plush.txt"), r.HTML(name+".plush.html")); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return smtp.Send(m)
+}
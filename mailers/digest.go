@@ -0,0 +1,77 @@
+package mailers
+
+import (
+	"fmt"
+
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/navionguy/quotewall/models"
+	"github.com/pkg/errors"
+)
+
+// Digest is everything that goes into one user's digest email
+type Digest struct {
+	User           models.User
+	Conversations  models.Conversations // published since the last digest
+	QuoteOfTheDay  *models.Conversation // nil if nothing has been picked
+	SiteURL        string
+	UnsubscribeURL string
+}
+
+// Empty is true when there is nothing worth sending
+func (d *Digest) Empty() bool {
+	return len(d.Conversations) == 0 && d.QuoteOfTheDay == nil
+}
+
+// Subject is the subject line for the digest
+func (d *Digest) Subject() string {
+	switch n := len(d.Conversations); n {
+	case 0:
+		return fmt.Sprintf("Your %s Quote Wall digest", d.User.Digest)
+	case 1:
+		return fmt.Sprintf("Your %s Quote Wall digest: 1 new conversation", d.User.Digest)
+	default:
+		return fmt.Sprintf("Your %s Quote Wall digest: %d new conversations", d.User.Digest, n)
+	}
+}
+
+// SendDigest mails the digest with both an HTML and a plain text body.
+// The List-Unsubscribe headers let mail programs show their own
+// unsubscribe button.
+func SendDigest(d Digest) error {
+	m := digestMessage(d)
+
+	if err := m.AddBodies(digestData(d), r.Plain("digest.plush.txt"), r.HTML("digest.plush.html")); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return smtp.Send(m)
+}
+
+func digestMessage(d Digest) mail.Message {
+	m := mail.NewMessage()
+
+	m.From = from
+	m.To = []string{d.User.Email}
+	m.Subject = d.Subject()
+	m.SetHeader("List-Unsubscribe", "<"+d.UnsubscribeURL+">")
+	m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+
+	return m
+}
+
+// digestData sets up the templates.  Plush can't test for a nil pointer,
+// so he gets told if there is a quote of the day.
+func digestData(d Digest) render.Data {
+	pick := models.Conversation{}
+	if d.QuoteOfTheDay != nil {
+		pick = *d.QuoteOfTheDay
+	}
+
+	return render.Data{
+		"digest":  d,
+		"picked":  d.QuoteOfTheDay != nil,
+		"pick":    pick,
+		"siteURL": d.SiteURL,
+	}
+}
@@ -0,0 +1,142 @@
+package mailers
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/navionguy/quotewall/models"
+)
+
+// smtpSink is just enough of an SMTP server to catch one message
+func smtpSink(t *testing.T) (string, string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := make(chan string, 1)
+
+	go func() {
+		defer ln.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rd := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 sink ready")
+
+		var data strings.Builder
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 sink")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				for {
+					l, err := rd.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msgs <- data.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	return addr.IP.String(), strconv.Itoa(addr.Port), msgs
+}
+
+func Test_SendDigest(t *testing.T) {
+	host, port, msgs := smtpSink(t)
+
+	sink, err := mail.NewSMTPSender(host, port, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := smtp
+	smtp = sink
+	defer func() { smtp = saved }()
+
+	said := time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)
+	conv := models.Conversation{Publish: true, Quotes: models.Quotes{
+		{Phrase: "Fish & chips\nare <great>", SaidOn: said, Author: models.Author{Name: "Shari"}},
+	}}
+
+	d := Digest{
+		User:           models.User{Email: "reader@example.com", Digest: models.DigestWeekly},
+		Conversations:  models.Conversations{conv},
+		QuoteOfTheDay:  &conv,
+		SiteURL:        "https://quotes.example.com",
+		UnsubscribeURL: "https://quotes.example.com/digest/unsubscribe/abc",
+	}
+
+	if err := SendDigest(d); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg string
+	select {
+	case msg = <-msgs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message reached the sink")
+	}
+
+	for _, want := range []string{
+		"To: reader@example.com",
+		"Subject: Your weekly Quote Wall digest: 1 new conversation",
+		"List-Unsubscribe: <https://quotes.example.com/digest/unsubscribe/abc>",
+		"Content-Type: text/plain",
+		"Content-Type: text/html",
+		"Fish & chips", // the text body isn't html escaped
+		"Fish &amp; chips",
+		"QUOTE OF THE DAY",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("digest is missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func Test_Digest_Subject(t *testing.T) {
+	d := Digest{User: models.User{Digest: models.DigestDaily}}
+
+	if got := d.Subject(); got != "Your daily Quote Wall digest" {
+		t.Errorf("Subject got %s", got)
+	}
+
+	if !d.Empty() {
+		t.Errorf("digest with nothing in it should be empty")
+	}
+
+	d.Conversations = models.Conversations{{}, {}}
+
+	if got := d.Subject(); got != "Your daily Quote Wall digest: 2 new conversations" {
+		t.Errorf("Subject got %s", got)
+	}
+}
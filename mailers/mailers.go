@@ -0,0 +1,38 @@
+package mailers
+
+import (
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/envy"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/navionguy/quotewall/logging"
+)
+
+var smtp mail.Sender
+var r *render.Engine
+
+// from is who the mail says it came from
+var from = envy.Get("SMTP_FROM", "quotewall@localhost")
+
+func init() {
+
+	// Pulling config from the env.  The defaults point at a local
+	// sink like MailHog, so nothing leaves the machine by accident.
+	port := envy.Get("SMTP_PORT", "1025")
+	host := envy.Get("SMTP_HOST", "localhost")
+	user := envy.Get("SMTP_USER", "")
+	password := envy.Get("SMTP_PASSWORD", "")
+
+	var err error
+	smtp, err = mail.NewSMTPSender(host, port, user, password)
+
+	if err != nil {
+		logging.For("mailers").Fatal(err)
+	}
+
+	r = render.New(render.Options{
+		HTMLLayout:   "layout.plush.html",
+		TemplatesBox: packr.New("app:mailers:templates", "../templates/mail"),
+		Helpers:      render.Helpers{},
+	})
+}
@@ -230,9 +230,13 @@ SET default_tablespace = '';
 
 CREATE TABLE public.annotations (
     id uuid NOT NULL,
-    note character varying(255) NOT NULL,
+    note text NOT NULL,
     created_at timestamp without time zone NOT NULL,
-    updated_at timestamp without time zone NOT NULL
+    updated_at timestamp without time zone NOT NULL,
+    kind character varying(255) DEFAULT 'context'::character varying NOT NULL,
+    sequence integer DEFAULT 0 NOT NULL,
+    quote_id uuid NOT NULL,
+    author_id uuid
 );
 
 
@@ -267,6 +271,23 @@ CREATE TABLE public.authors (
 
 ALTER TABLE public.authors OWNER TO postgres;
 
+--
+-- Name: conversation_shows; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.conversation_shows (
+    id uuid NOT NULL,
+    day date NOT NULL,
+    display character varying(255) DEFAULT ''::character varying NOT NULL,
+    conversation_id uuid NOT NULL,
+    count integer DEFAULT 0 NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.conversation_shows OWNER TO postgres;
+
 --
 -- Name: conversations; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -276,12 +297,138 @@ CREATE TABLE public.conversations (
     occurredon timestamp without time zone NOT NULL,
     publish boolean NOT NULL,
     created_at timestamp without time zone NOT NULL,
-    updated_at timestamp without time zone NOT NULL
+    updated_at timestamp without time zone NOT NULL,
+    meeting character varying(255) DEFAULT ''::character varying NOT NULL,
+    location character varying(255) DEFAULT ''::character varying NOT NULL,
+    topic character varying(255) DEFAULT ''::character varying NOT NULL
 );
 
 
 ALTER TABLE public.conversations OWNER TO postgres;
 
+--
+-- Name: daily_picks; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.daily_picks (
+    id uuid NOT NULL,
+    day date NOT NULL,
+    wall character varying(255) NOT NULL,
+    conversation_id uuid NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    speaker character varying(255) DEFAULT ''::character varying NOT NULL
+);
+
+
+ALTER TABLE public.daily_picks OWNER TO postgres;
+
+--
+-- Name: display_profiles; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.display_profiles (
+    id uuid NOT NULL,
+    name character varying(255) NOT NULL,
+    title character varying(255) NOT NULL,
+    refresh_min integer DEFAULT 10 NOT NULL,
+    refresh_max integer DEFAULT 10 NOT NULL,
+    chars_per_second integer DEFAULT 15 NOT NULL,
+    font_family character varying(255) NOT NULL,
+    font_min integer DEFAULT 64 NOT NULL,
+    font_max integer DEFAULT 64 NOT NULL,
+    text_color character varying(255) NOT NULL,
+    background_color character varying(255) NOT NULL,
+    speaker_color character varying(255) NOT NULL,
+    note_color character varying(255) NOT NULL,
+    show_speaker boolean DEFAULT true NOT NULL,
+    show_date boolean DEFAULT true NOT NULL,
+    show_note boolean DEFAULT true NOT NULL,
+    filters character varying(255) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL,
+    width integer DEFAULT 1920 NOT NULL,
+    height integer DEFAULT 1080 NOT NULL,
+    show_context boolean DEFAULT false NOT NULL
+);
+
+
+ALTER TABLE public.display_profiles OWNER TO postgres;
+
+--
+-- Name: embed_keys; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.embed_keys (
+    id uuid NOT NULL,
+    name character varying(255) NOT NULL,
+    key character varying(255) NOT NULL,
+    allowed_origins text DEFAULT ''::text NOT NULL,
+    filters character varying(255) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.embed_keys OWNER TO postgres;
+
+--
+-- Name: job_runs; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.job_runs (
+    id uuid NOT NULL,
+    name character varying(255) NOT NULL,
+    host character varying(255) DEFAULT ''::character varying NOT NULL,
+    status character varying(255) NOT NULL,
+    message text DEFAULT ''::text NOT NULL,
+    started_at timestamp without time zone NOT NULL,
+    finished_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.job_runs OWNER TO postgres;
+
+--
+-- Name: login_events; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.login_events (
+    id uuid NOT NULL,
+    user_id uuid,
+    email character varying(255) DEFAULT ''::character varying NOT NULL,
+    event character varying(255) NOT NULL,
+    remote_addr character varying(255) DEFAULT ''::character varying NOT NULL,
+    user_agent text DEFAULT ''::text NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.login_events OWNER TO postgres;
+
+
+--
+-- Name: login_failures; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.login_failures (
+    id uuid NOT NULL,
+    scope character varying(255) NOT NULL,
+    key character varying(255) NOT NULL,
+    failures integer DEFAULT 0 NOT NULL,
+    last_failed_at timestamp without time zone NOT NULL,
+    locked_until timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.login_failures OWNER TO postgres;
+
+
 --
 -- Name: permissions; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -305,9 +452,8 @@ CREATE TABLE public.quotes (
     id uuid NOT NULL,
     saidon timestamp without time zone NOT NULL,
     sequence integer NOT NULL,
-    phrase character varying(255) NOT NULL,
+    phrase text NOT NULL,
     publish boolean NOT NULL,
-    annotation_id uuid,
     author_id uuid NOT NULL,
     conversation_id uuid NOT NULL,
     created_at timestamp without time zone NOT NULL,
@@ -317,6 +463,23 @@ CREATE TABLE public.quotes (
 
 ALTER TABLE public.quotes OWNER TO postgres;
 
+--
+-- Name: recovery_codes; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.recovery_codes (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    code_hash character varying(255) NOT NULL,
+    used_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.recovery_codes OWNER TO postgres;
+
+
 --
 -- Name: requests; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -365,6 +528,44 @@ CREATE TABLE public.schema_migration (
 
 ALTER TABLE public.schema_migration OWNER TO postgres;
 
+--
+-- Name: user_sessions; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.user_sessions (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    remote_addr character varying(255) DEFAULT ''::character varying NOT NULL,
+    user_agent text DEFAULT ''::text NOT NULL,
+    last_seen_at timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.user_sessions OWNER TO postgres;
+
+
+--
+-- Name: user_tokens; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.user_tokens (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    purpose character varying(255) NOT NULL,
+    token_hash character varying(255) NOT NULL,
+    email character varying(255) DEFAULT ''::character varying NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    used_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.user_tokens OWNER TO postgres;
+
 --
 -- Name: users; Type: TABLE; Schema: public; Owner: postgres
 --
@@ -374,12 +575,58 @@ CREATE TABLE public.users (
     email character varying(255) NOT NULL,
     password_hash character varying(255) NOT NULL,
     created_at timestamp without time zone NOT NULL,
-    updated_at timestamp without time zone NOT NULL
+    updated_at timestamp without time zone NOT NULL,
+    digest character varying(255) DEFAULT 'off'::character varying NOT NULL,
+    digest_token character varying(255) DEFAULT ''::character varying NOT NULL,
+    digest_sent_at timestamp without time zone,
+    oidc_issuer character varying(255) DEFAULT ''::character varying NOT NULL,
+    oidc_subject character varying(255) DEFAULT ''::character varying NOT NULL,
+    totp_secret character varying(255) DEFAULT ''::character varying NOT NULL,
+    totp_enabled_at timestamp without time zone,
+    totp_last_step bigint DEFAULT 0 NOT NULL
 );
 
 
 ALTER TABLE public.users OWNER TO postgres;
 
+--
+-- Name: webhook_deliveries; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.webhook_deliveries (
+    id uuid NOT NULL,
+    subscription_id uuid NOT NULL,
+    event character varying(255) NOT NULL,
+    payload text NOT NULL,
+    status character varying(255) DEFAULT 'pending'::character varying NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    status_code integer DEFAULT 0 NOT NULL,
+    last_error text DEFAULT ''::text NOT NULL,
+    delivered_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.webhook_deliveries OWNER TO postgres;
+
+--
+-- Name: webhook_subscriptions; Type: TABLE; Schema: public; Owner: postgres
+--
+
+CREATE TABLE public.webhook_subscriptions (
+    id uuid NOT NULL,
+    url character varying(255) NOT NULL,
+    secret character varying(255) NOT NULL,
+    events text DEFAULT ''::text NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    updated_at timestamp without time zone NOT NULL
+);
+
+
+ALTER TABLE public.webhook_subscriptions OWNER TO postgres;
+
 --
 -- Name: requests id; Type: DEFAULT; Schema: public; Owner: postgres
 --
@@ -403,6 +650,14 @@ ALTER TABLE ONLY public.authors
     ADD CONSTRAINT authors_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: conversation_shows conversation_shows_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.conversation_shows
+    ADD CONSTRAINT conversation_shows_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: conversations conversations_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -411,6 +666,54 @@ ALTER TABLE ONLY public.conversations
     ADD CONSTRAINT conversations_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: daily_picks daily_picks_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.daily_picks
+    ADD CONSTRAINT daily_picks_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: display_profiles display_profiles_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.display_profiles
+    ADD CONSTRAINT display_profiles_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: embed_keys embed_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.embed_keys
+    ADD CONSTRAINT embed_keys_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: job_runs job_runs_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.job_runs
+    ADD CONSTRAINT job_runs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: login_events login_events_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.login_events
+    ADD CONSTRAINT login_events_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: login_failures login_failures_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.login_failures
+    ADD CONSTRAINT login_failures_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: permissions permissions_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -427,6 +730,14 @@ ALTER TABLE ONLY public.quotes
     ADD CONSTRAINT quotes_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: recovery_codes recovery_codes_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.recovery_codes
+    ADD CONSTRAINT recovery_codes_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: requests requests_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -435,6 +746,22 @@ ALTER TABLE ONLY public.requests
     ADD CONSTRAINT requests_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: user_sessions user_sessions_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: user_tokens user_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.user_tokens
+    ADD CONSTRAINT user_tokens_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: users users_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
 --
@@ -443,6 +770,92 @@ ALTER TABLE ONLY public.users
     ADD CONSTRAINT users_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: webhook_deliveries webhook_deliveries_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: webhook_subscriptions webhook_subscriptions_pkey; Type: CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.webhook_subscriptions
+    ADD CONSTRAINT webhook_subscriptions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: annotations_quote_id_sequence_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX annotations_quote_id_sequence_idx ON public.annotations USING btree (quote_id, sequence);
+
+
+--
+-- Name: authors_name_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX authors_name_idx ON public.authors USING btree (lower((name)::text));
+
+
+--
+-- Name: conversation_shows_day_display_conversation_id_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX conversation_shows_day_display_conversation_id_idx ON public.conversation_shows USING btree (day, display, conversation_id);
+
+
+--
+-- Name: daily_picks_day_wall_speaker_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX daily_picks_day_wall_speaker_idx ON public.daily_picks USING btree (day, wall, speaker);
+
+
+--
+-- Name: display_profiles_name_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX display_profiles_name_idx ON public.display_profiles USING btree (name);
+
+
+--
+-- Name: embed_keys_key_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX embed_keys_key_idx ON public.embed_keys USING btree (key);
+
+
+--
+-- Name: job_runs_name_started_at_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX job_runs_name_started_at_idx ON public.job_runs USING btree (name, started_at);
+
+
+--
+-- Name: login_events_user_id_created_at_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX login_events_user_id_created_at_idx ON public.login_events USING btree (user_id, created_at);
+
+
+--
+-- Name: login_failures_scope_key_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX login_failures_scope_key_idx ON public.login_failures USING btree (scope, key);
+
+
+--
+-- Name: recovery_codes_user_id_code_hash_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX recovery_codes_user_id_code_hash_idx ON public.recovery_codes USING btree (user_id, code_hash);
+
+
 --
 -- Name: schema_migration_version_idx; Type: INDEX; Schema: public; Owner: postgres
 --
@@ -450,6 +863,41 @@ ALTER TABLE ONLY public.users
 CREATE UNIQUE INDEX schema_migration_version_idx ON public.schema_migration USING btree (version);
 
 
+--
+-- Name: user_sessions_user_id_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX user_sessions_user_id_idx ON public.user_sessions USING btree (user_id);
+
+
+--
+-- Name: user_tokens_token_hash_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE UNIQUE INDEX user_tokens_token_hash_idx ON public.user_tokens USING btree (token_hash);
+
+
+--
+-- Name: users_digest_token_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX users_digest_token_idx ON public.users USING btree (digest_token);
+
+
+--
+-- Name: users_oidc_issuer_oidc_subject_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX users_oidc_issuer_oidc_subject_idx ON public.users USING btree (oidc_issuer, oidc_subject);
+
+
+--
+-- Name: webhook_deliveries_subscription_id_created_at_idx; Type: INDEX; Schema: public; Owner: postgres
+--
+
+CREATE INDEX webhook_deliveries_subscription_id_created_at_idx ON public.webhook_deliveries USING btree (subscription_id, created_at);
+
+
 --
 -- Name: author_counts _RETURN; Type: RULE; Schema: public; Owner: postgres
 --
@@ -466,19 +914,51 @@ CREATE OR REPLACE VIEW public.author_counts AS
 
 
 --
--- Name: permissions permissions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+-- Name: annotations annotations_author_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
 
-ALTER TABLE ONLY public.permissions
-    ADD CONSTRAINT permissions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE RESTRICT DEFERRABLE INITIALLY DEFERRED;
+ALTER TABLE ONLY public.annotations
+    ADD CONSTRAINT annotations_author_id_fkey FOREIGN KEY (author_id) REFERENCES public.authors(id);
 
 
 --
--- Name: quotes quotes_annotation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+-- Name: annotations annotations_quote_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
 
-ALTER TABLE ONLY public.quotes
-    ADD CONSTRAINT quotes_annotation_id_fkey FOREIGN KEY (annotation_id) REFERENCES public.annotations(id);
+ALTER TABLE ONLY public.annotations
+    ADD CONSTRAINT annotations_quote_id_fkey FOREIGN KEY (quote_id) REFERENCES public.quotes(id) ON DELETE CASCADE;
+
+
+--
+-- Name: conversation_shows conversation_shows_conversation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.conversation_shows
+    ADD CONSTRAINT conversation_shows_conversation_id_fkey FOREIGN KEY (conversation_id) REFERENCES public.conversations(id) ON DELETE CASCADE;
+
+
+--
+-- Name: daily_picks daily_picks_conversation_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.daily_picks
+    ADD CONSTRAINT daily_picks_conversation_id_fkey FOREIGN KEY (conversation_id) REFERENCES public.conversations(id) ON DELETE CASCADE;
+
+
+--
+-- Name: login_events login_events_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.login_events
+    ADD CONSTRAINT login_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE SET NULL;
+
+
+--
+-- Name: permissions permissions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.permissions
+    ADD CONSTRAINT permissions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE RESTRICT DEFERRABLE INITIALLY DEFERRED;
 
 
 --
@@ -498,6 +978,37 @@ ALTER TABLE ONLY public.quotes
 
 
 --
--- PostgreSQL database dump complete
+-- Name: recovery_codes recovery_codes_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
 --
 
+ALTER TABLE ONLY public.recovery_codes
+    ADD CONSTRAINT recovery_codes_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: user_sessions user_sessions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.user_sessions
+    ADD CONSTRAINT user_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: user_tokens user_tokens_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.user_tokens
+    ADD CONSTRAINT user_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+
+--
+-- Name: webhook_deliveries webhook_deliveries_subscription_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: postgres
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_subscription_id_fkey FOREIGN KEY (subscription_id) REFERENCES public.webhook_subscriptions(id) ON DELETE CASCADE;
+
+
+--
+-- PostgreSQL database dump complete
+--
\ No newline at end of file
@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gobuffalo/pop/v5"
@@ -11,12 +12,33 @@ import (
 	"github.com/gofrs/uuid"
 )
 
-// Annotation holds
+// The kinds of annotation a quote can have
+const (
+	AnnotationContext     = "context"     // what was going on when it was said
+	AnnotationExplanation = "explanation" // what it meant, for anyone who wasn't there
+	AnnotationAside       = "aside"       // an editor's comment
+)
+
+// AnnotationKinds is every kind of annotation, in the order the form
+// offers them
+var AnnotationKinds = []string{AnnotationContext, AnnotationExplanation, AnnotationAside}
+
+// Annotation is one note on a quote.  A quote can have as many as it
+// needs, each of them can say who wrote it.
 type Annotation struct {
 	ID        uuid.UUID `json:"-" db:"id"`
 	CreatedAt time.Time `json:"-" db:"created_at"`
 	UpdatedAt time.Time `json:"-" db:"updated_at"`
-	Note      string    `json:"note" db:"note" form:"Annotation"`
+	Sequence  int       `json:"-" db:"sequence"`
+	Kind      string    `json:"kind" db:"kind"`
+	Note      string    `json:"note" db:"note"`
+
+	// Relationships
+	Author *Author `json:"author,omitempty" belongs_to:"author" db:"-"`
+
+	// Foreign keys
+	QuoteID  uuid.UUID  `json:"-" db:"quote_id"`
+	AuthorID *uuid.UUID `json:"author_id,omitempty" db:"author_id"`
 }
 
 // String is not required by pop and may be deleted
@@ -34,12 +56,25 @@ func (a Annotations) String() string {
 	return string(ja)
 }
 
+// Text runs the notes together, a paragraph each, for places that only
+// have room for one block of text
+func (a Annotations) Text() string {
+	var notes []string
+
+	for _, an := range a {
+		notes = append(notes, an.Note)
+	}
+
+	return strings.Join(notes, "\n")
+}
+
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 // This method is not required and may be deleted.
 func (a *Annotation) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.StringIsPresent{Field: a.Note, Name: "Note"},
-		&validators.StringLengthInRange{Field: a.Note, Name: "Note", Min: 0, Max: 255, Message: "length must be <255"},
+		&validators.StringLengthInRange{Field: a.Note, Name: "Note", Min: 0, Max: MaxTextLength, Message: fmt.Sprintf("length must be <%d", MaxTextLength)},
+		&validators.StringInclusion{Field: a.Kind, Name: "Kind", List: AnnotationKinds, Message: fmt.Sprintf("kind must be one of %s", strings.Join(AnnotationKinds, ", "))},
 	), nil
 }
 
@@ -48,52 +83,3 @@ func (a *Annotation) Validate(tx *pop.Connection) (*validate.Errors, error) {
 func (a *Annotation) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
-
-// FindByNote tries to find the note already saved in the
-// annotations table.
-// If it does, it returns the full annotation record.
-// If it does not, it returns an annotation object that
-// holds the note, but has the ID set to uuid.NULL
-func (a *Annotation) FindByNote() error {
-
-	annoRecs := []Annotation{}
-	query := DB.Where(fmt.Sprintf("note = '%s'", a.Note))
-	err := query.All(&annoRecs)
-
-	if err != nil {
-		return err
-	}
-
-	if len(annoRecs) > 0 {
-		a.ID = annoRecs[0].ID
-		return nil // found him!
-	}
-
-	a.ID = uuid.Nil
-
-	return nil
-}
-
-// CheckID makes sure that if there is an annotation, the db record for it exists
-// creating it if needed
-func (a *Annotation) CheckID(db *pop.Connection) (*validate.Errors, error) {
-
-	var ve validate.Errors
-
-	if a == nil {
-		return &ve, nil
-	}
-
-	if a.ID != uuid.Nil {
-		return &ve, nil
-	}
-
-	verrs, err := db.ValidateAndCreate(a)
-
-	if err != nil || verrs.HasAny() {
-		return verrs, err
-	}
-
-	// he created
-	return &ve, nil
-}
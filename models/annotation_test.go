@@ -1,97 +1,102 @@
 package models
 
 import (
-	"fmt"
+	"strings"
+	"testing"
 
 	"github.com/gobuffalo/uuid"
 )
 
-const validAnnotation = "First Timer!"
-const invalidAnnotation = "*^*&^*&^*^*^&"
+// the first quote in "test quotes" has both fixture annotations
+const annotatedQuote = "5e0c1a1e-8b1d-4c3e-9a55-0d6d3f1b7c21"
 
-func (ms *ModelSuite) Test_Annotation_FindByNote() {
+func Test_Annotation_Validate(t *testing.T) {
 	tests := []struct {
 		test string
+		kind string
 		note string
-		exp  bool
-		jsn  string
-		ajsn string
+		verr bool
 	}{
-		{test: "Valid", note: validAnnotation, exp: true, jsn: "{\"note\":\"First Timer!\"}", ajsn: "[{\"note\":\"First Timer!\"}]"},
-		{test: "Invalid", note: invalidAnnotation, exp: false},
+		{test: "Context", kind: AnnotationContext, note: "First Timer!", verr: false},
+		{test: "Explanation", kind: AnnotationExplanation, note: "He meant the old name.", verr: false},
+		{test: "Aside", kind: AnnotationAside, note: "Editor: he was wrong.", verr: false},
+		{test: "No Note", kind: AnnotationContext, note: "", verr: true},
+		{test: "Too Long", kind: AnnotationContext, note: strings.Repeat("x", MaxTextLength+1), verr: true},
+		{test: "Unknown Kind", kind: "gossip", note: "Heard it from Bob.", verr: true},
+		{test: "No Kind", kind: "", note: "Heard it from Bob.", verr: true},
 	}
-	ms.LoadFixture("test annotations")
 
 	for _, tt := range tests {
-		ano := Annotation{
-			Note: tt.note,
-		}
-
-		err := ano.FindByNote()
+		a := Annotation{Kind: tt.kind, Note: tt.note}
+		verrs, err := a.Validate(nil)
 
 		if err != nil {
-			ms.Fail("annotation FindByNote database error", err.Error)
+			t.Fatalf("Annotation.Validate(%s) got error %s", tt.test, err.Error())
 		}
 
-		gotAno := (ano.ID != uuid.Nil)
-		ms.EqualValuesf(gotAno, tt.exp, "Annotation_FindByNote(%s) got %t, wanted %t\n", tt.test, gotAno, tt.exp)
-
-		if tt.exp {
-			// should have a valid Annotation
-			// let's test his string functions
-			jsn := ano.String()
-			ms.EqualValuesf(jsn, tt.jsn, "Annotation_String(%s) got %t, wanted %t\n", tt.test, jsn, tt.jsn)
-
-			var anoArr Annotations
-			anoArr = append(anoArr, ano)
-			ajsn := anoArr.String()
-
-			ms.EqualValuesf(ajsn, tt.ajsn, "[]Annotation_String(%s) got %t, wanted %t\n", tt.test, ajsn, tt.ajsn)
-
+		if verrs.HasAny() != tt.verr {
+			t.Errorf("Annotation.Validate(%s) got %v, wanted errors %t", tt.test, verrs, tt.verr)
 		}
 	}
 }
 
-func (ms *ModelSuite) Test_Annotation_CheckID() {
-	valid := validAnnotation
-	newAnno := "Something original"
-	invAnno := ""
-
+func Test_Annotations_Text(t *testing.T) {
 	tests := []struct {
 		test string
-		note *string
-		exp  bool
-		verr bool
+		inp  Annotations
+		want string
 	}{
-		{test: "NoNote", note: nil, exp: false, verr: false},
-		{test: "Valid", note: &valid, exp: true, verr: false},
-		{test: "New", note: &newAnno, exp: true, verr: false},
-		{test: "Invalid", note: &invAnno, exp: false, verr: true},
+		{test: "None", inp: nil, want: ""},
+		{test: "One", inp: Annotations{{Note: "First Timer!"}}, want: "First Timer!"},
+		{test: "Several", inp: Annotations{{Note: "At the retro"}, {Note: "He meant it.\nReally."}}, want: "At the retro\nHe meant it.\nReally."},
 	}
-	ms.LoadFixture("test annotations")
 
 	for _, tt := range tests {
-		ano := &Annotation{}
-
-		if tt.note != nil {
-			ano.Note = *tt.note
-			err := ano.FindByNote()
-			fmt.Printf("Finding %s\n", ano.Note)
-
-			if err != nil {
-				ms.Fail("annotation FindByNote database error", err.Error)
-			}
-			fmt.Printf("got uuid %s, note %s\n", ano.ID.String(), ano.Note)
-		} else {
-			ano = nil
+		if got := tt.inp.Text(); got != tt.want {
+			t.Errorf("Annotations.Text(%s) got %q, wanted %q", tt.test, got, tt.want)
 		}
+	}
+}
 
-		verrs, err := ano.CheckID(ms.DB)
+func Test_Quote_ClaimAnnotations(t *testing.T) {
+	q := Quote{ID: uuid.Must(uuid.NewV4()), Annotations: Annotations{
+		{Note: "no kind given"},
+		{Kind: AnnotationAside, Note: "an aside"},
+	}}
 
-		ms.EqualValuesf(verrs.HasAny(), tt.verr, "Annotation_CheckID(%s) got %t, wanted %t", tt.test, verrs.HasAny(), tt.verr)
+	q.claimAnnotations()
 
-		if err != nil {
-			ms.Failf("Annotation_CheckID(%s) got error %s", tt.test, err.Error())
+	for i, a := range q.Annotations {
+		if a.QuoteID != q.ID || a.Sequence != i {
+			t.Errorf("annotation %d got quote %s sequence %d", i, a.QuoteID, a.Sequence)
 		}
 	}
+
+	if q.Annotations[0].Kind != AnnotationContext || q.Annotations[1].Kind != AnnotationAside {
+		t.Errorf("claimAnnotations got kinds %s and %s", q.Annotations[0].Kind, q.Annotations[1].Kind)
+	}
+}
+
+func (ms *ModelSuite) Test_Annotation_ForQuote() {
+	loadFixtureData(ms)
+	ms.LoadFixture("test quotes")
+
+	annotations, err := NewPopRepository(ms.DB).Annotations.ForQuote(uuid.FromStringOrNil(annotatedQuote))
+	ms.NoError(err)
+	ms.Len(annotations, 2)
+
+	ms.Equal("Note about the quote", annotations[0].Note)
+	ms.Equal(AnnotationContext, annotations[0].Kind)
+	ms.Nil(annotations[0].Author)
+
+	ms.Equal("First Timer!", annotations[1].Note)
+	ms.Equal(AnnotationAside, annotations[1].Kind)
+	ms.NotNil(annotations[1].Author)
+	ms.Equal("George P. Burdell", annotations[1].Author.Name)
+
+	ms.Contains(annotations[1].String(), `"kind":"aside"`)
+
+	annotations, err = NewPopRepository(ms.DB).Annotations.ForQuote(uuid.Must(uuid.NewV4()))
+	ms.NoError(err)
+	ms.Len(annotations, 0)
 }
@@ -2,9 +2,6 @@ package models
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
-	"strings"
 	"time"
 
 	"github.com/gobuffalo/pop/v5"
@@ -49,10 +46,32 @@ func (a Authors) String() string {
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 // This method is not required and may be deleted.
 func (a *Author) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	var err error
 	return validate.Validate(
 		&validators.StringIsPresent{Field: a.Name, Name: "Name"},
 		&validators.StringLengthInRange{Field: a.Name, Name: "Name", Min: 1, Max: 255, Message: "length must be 1-255"},
-	), nil
+		// one author per name, whatever the case
+		&validators.FuncValidator{
+			Field:   a.Name,
+			Name:    "Name",
+			Message: "%s is already taken",
+			Fn: func() bool {
+				if tx == nil {
+					return true
+				}
+				var b bool
+				q := tx.Where("LOWER(name) = LOWER(?)", a.Name)
+				if a.ID != uuid.Nil {
+					q = q.Where("id != ?", a.ID)
+				}
+				b, err = q.Exists(&Author{})
+				if err != nil {
+					return false
+				}
+				return !b
+			},
+		},
+	), err
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
@@ -79,72 +98,36 @@ func (a Author) SelectLabel() string {
 
 // FindByID pulls up the author record based on ID
 func (a *Author) FindByID() error {
-	authRecs := []Author{}
-	query := DB.Where(fmt.Sprintf("id = '%s'", a.ID))
-	err := query.All(&authRecs)
+	found, err := NewPopRepository(DB).Authors.Find(a.ID)
 
 	if err != nil {
 		return err
 	}
 
-	if len(authRecs) == 0 {
-		return errors.New("author ID not found in db")
-	}
-
-	*a = authRecs[0]
+	*a = *found
 
 	return nil
 }
 
-// FindByName check for an author by name
+// FindByName check for an author by name, ignoring case
 func (a *Author) FindByName() error {
-	// name can't be empty
-	if len(a.Name) == 0 {
-		return errors.New("author name can't be blank")
-	}
-
-	// Break the passed name down into pieces
-	parts := strings.Split(a.Name, " ")
-
-	authRecs := []Author{}
-	query := DB.Where(fmt.Sprintf("name ILIKE '%%%s%%' AND name ILIKE '%%%s%%'", parts[0], parts[len(parts)-1]))
-	err := query.All(&authRecs)
+	found, err := NewPopRepository(DB).Authors.FindByName(a.Name)
 
 	if err != nil {
 		return err
 	}
 
-	if len(authRecs) == 0 {
-		return errors.New("author name not found in db")
-	}
-
-	*a = authRecs[0]
+	*a = *found
 
 	return nil
 }
 
 // Create adds a new speaker to the authors table
 func (a *Author) Create() (*validate.Errors, error) {
-	verr, err := DB.ValidateAndCreate(a)
-
-	if verr.HasAny() || (err != nil) {
-		return verr, err
-	}
-
-	err = a.FindByName()
-
-	return verr, err
+	return NewPopRepository(DB).Authors.Create(a)
 }
 
 // Update modifies an already saved author
 func (a *Author) Update() (*validate.Errors, error) {
-	verr, err := DB.ValidateAndUpdate(a)
-
-	if verr.HasAny() || (err != nil) {
-		return verr, err
-	}
-
-	err = a.FindByName()
-
-	return verr, err
+	return NewPopRepository(DB).Authors.Update(a)
 }
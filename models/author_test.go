@@ -43,7 +43,8 @@ func (ms *ModelSuite) Test_Author_Create() {
 		expJSON string
 		arrJSON string
 	}{
-		{test: "Good Name", name: "Shari Freeman", expErr: false, expJSON: "{\"name\":\"Shari Freeman\"}", arrJSON: "[{\"name\":\"Shari Freeman\"}]"},
+		{test: "Good Name", name: "Sean O'Brien", expErr: false, expJSON: "{\"name\":\"Sean O'Brien\"}", arrJSON: "[{\"name\":\"Sean O'Brien\"}]"},
+		{test: "Taken Name", name: "shari freeman", expErr: true},
 		{test: "Blank Name", expErr: true},
 	}
 
@@ -82,6 +83,8 @@ func (ms *ModelSuite) Test_Author_FindByName() {
 		expErr bool
 	}{
 		{test: "Good Name", name: "Shari Freeman", expErr: false},
+		{test: "Other Case", name: "shari FREEMAN", expErr: false},
+		{test: "Part of a Name", name: "Shari", expErr: true},
 		{test: "Bad Name", name: "Alfred E. Neuman", expErr: true},
 		{test: "Blank Name", name: "", expErr: true},
 	}
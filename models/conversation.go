@@ -2,7 +2,6 @@ package models
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -22,6 +21,11 @@ type Conversation struct {
 	OccurredOn time.Time `json:"occurredon" db:"occurredon"`
 	Publish    bool      `json:"publish" db:"publish"`
 
+	// where it happened and what it was about, all optional
+	Meeting  string `json:"meeting" db:"meeting"`
+	Location string `json:"location" db:"location"`
+	Topic    string `json:"topic" db:"topic"`
+
 	// Relationships
 	Quotes Quotes `has_many:"quotes" orderby:"sequence" db:"-"`
 }
@@ -32,6 +36,20 @@ func (c Conversation) String() string {
 	return string(jc)
 }
 
+// Context puts the meeting, location and topic on one line, leaving out
+// any that weren't filled in
+func (c Conversation) Context() string {
+	var parts []string
+
+	for _, p := range []string{c.Meeting, c.Location, c.Topic} {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+
+	return strings.Join(parts, " · ")
+}
+
 // Conversations is not required by pop and may be deleted
 type Conversations []Conversation
 
@@ -47,6 +65,9 @@ func (c *Conversation) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.TimeIsPresent{Field: c.OccurredOn, Name: "SaidOn"},
 		&validators.TimeIsBeforeTime{FirstTime: c.OccurredOn, SecondTime: time.Now().AddDate(0, 0, 1), FirstName: "Said on", SecondName: "Tomorrow"},
+		&validators.StringLengthInRange{Field: c.Meeting, Name: "Meeting", Min: 0, Max: 255, Message: "length must be <255"},
+		&validators.StringLengthInRange{Field: c.Location, Name: "Location", Min: 0, Max: 255, Message: "length must be <255"},
+		&validators.StringLengthInRange{Field: c.Topic, Name: "Topic", Min: 0, Max: 255, Message: "length must be <255"},
 	), nil
 }
 
@@ -62,110 +83,14 @@ func (c *Conversation) ValidateUpdate(tx *pop.Connection) (*validate.Errors, err
 	return validate.NewErrors(), nil
 }
 
-const tempError string = "NoErr"
-
 // Create creates a new conversation.
 func (c *Conversation) Create() (*validate.Errors, error) {
-	var verrs *validate.Errors
-
-	// start a transaction for the whole conversation
-	err := DB.Transaction(func(db *pop.Connection) error {
-		var err error
-
-		// create the conversation record
-		verrs, err = db.ValidateAndCreate(c)
-
-		if err != nil {
-			return err
-		}
-
-		if verrs.HasAny() {
-			return errors.New(tempError) // force rollback of the transaction
-		}
-
-		// loop through all the quotes and add them
-		for i, quote := range c.Quotes {
-			quote.Sequence = i
-
-			verrs, err = quote.Create(db, c.ID)
-			if err != nil {
-				return err
-			}
-
-			if verrs.HasAny() {
-				return errors.New(tempError) // this is just to get pop to rollback the transaction
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		if strings.Compare(tempError, err.Error()) == 0 {
-			return verrs, nil
-		}
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	// if nothing went south, all is good
-	return verrs, nil
+	return NewPopRepository(DB).Conversations.Create(c)
 }
 
 // Update re-saves an already created conversation
 func (c *Conversation) Update() (*validate.Errors, error) {
-	var verrs *validate.Errors
-
-	// start a transaction for the whole conversation
-	err := DB.Transaction(func(db *pop.Connection) error {
-		var err error
-
-		// update the conversation record
-		verrs, err = db.ValidateAndUpdate(c)
-
-		if err != nil {
-			return err
-		}
-
-		if verrs.HasAny() {
-			return errors.New(tempError) // force rollback of the transaction
-		}
-
-		// loop through all the quotes and add them
-		for i, quote := range c.Quotes {
-			quote.Sequence = i
-
-			if quote.ID == uuid.Nil {
-				verrs, err = quote.Create(db, c.ID)
-			} else {
-				verrs, err = quote.Update(db, c.ID)
-			}
-			if err != nil {
-				return err
-			}
-
-			if verrs.HasAny() {
-				return errors.New(tempError) // this is just to get pop to rollback the transaction
-			}
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		if strings.Compare(tempError, err.Error()) == 0 {
-			return verrs, nil
-		}
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	// if nothing went south, all is good
-	return verrs, nil
+	return NewPopRepository(DB).Conversations.Update(c)
 }
 
 // MarshalConversation the passed conversation
@@ -189,7 +114,7 @@ func (c *Conversation) ExtractConversationFromJSON(cvjson string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println(cvjson)
+	log.Debugf("conversation json is %d bytes", len(cvjson))
 
 	return c.Unmarshal([]byte(cvjson))
 }
@@ -203,3 +128,54 @@ func (c *Conversation) Unmarshal(cvjson []byte) error {
 	}
 	return nil
 }
+
+// AnniversaryClause builds a WHERE clause that matches the column against
+// the month and day of the passed day, widened by window days on either
+// side.  Only dates before this year's window match, so it only picks up
+// anniversaries from previous years.  dialect is the database's, as
+// db.Dialect.Name() gives it.
+func AnniversaryClause(dialect string, column string, day time.Time, window int) string {
+	if window < 0 {
+		window = 0
+	}
+
+	var days []string
+	for i := -window; i <= window; i++ {
+		d := day.AddDate(0, 0, i)
+		days = append(days, fmt.Sprintf("(%s = %d AND %s = %d)", datePart(dialect, "MONTH", column), int(d.Month()), datePart(dialect, "DAY", column), d.Day()))
+	}
+
+	cutoff := day.AddDate(0, 0, -window)
+
+	return fmt.Sprintf("(%s) AND %s < '%s'", strings.Join(days, " OR "), column, cutoff.Format("2006-01-02"))
+}
+
+// OccurredOnThisDay loads the published conversations that occurred on
+// the month and day of the passed day in previous years.
+func (c *Conversations) OccurredOnThisDay(db *pop.Connection, day time.Time, window int) error {
+	return db.Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").
+		Where("publish = TRUE").
+		Where(AnniversaryClause(db.Dialect.Name(), "occurredon", day, window)).
+		Order("occurredon DESC").
+		All(c)
+}
+
+// RandomBySpeaker picks one of the published conversations that the
+// named speaker took part in.
+func (c *Conversation) RandomBySpeaker(db *pop.Connection, name string) error {
+	return db.RawQuery(`SELECT c.* FROM conversations c
+		WHERE c.publish = TRUE AND EXISTS (
+			SELECT 1 FROM quotes q JOIN authors a ON a.id = q.author_id
+			WHERE q.conversation_id = c.id AND LOWER(a.name) LIKE LOWER(?))
+		ORDER BY random() LIMIT 1`, "%"+name+"%").First(c)
+}
+
+// PublishedSince loads the published conversations added after since,
+// oldest first
+func (c *Conversations) PublishedSince(db *pop.Connection, since time.Time) error {
+	return db.Eager("Quotes").Eager("Quotes.Author").
+		Where("publish = TRUE").
+		Where("created_at > ?", since).
+		Order("created_at").
+		All(c)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+)
+
+// ConversationShow counts how many times a display showed a conversation
+// on a day, it's what the stats page ranks displays and conversations by
+type ConversationShow struct {
+	ID             uuid.UUID `json:"-" db:"id"`
+	CreatedAt      time.Time `json:"-" db:"created_at"`
+	UpdatedAt      time.Time `json:"-" db:"updated_at"`
+	Day            time.Time `json:"day" db:"day"`
+	Display        string    `json:"display" db:"display"`
+	ConversationID uuid.UUID `json:"conversation_id" db:"conversation_id"`
+	Count          int       `json:"count" db:"count"`
+}
+
+// String is not required by pop and may be deleted
+func (c ConversationShow) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// ConversationShows is not required by pop and may be deleted
+type ConversationShows []ConversationShow
+
+// String is not required by pop and may be deleted
+func (c ConversationShows) String() string {
+	jc, _ := json.Marshal(c)
+	return string(jc)
+}
+
+// RecordShow adds one to the count for the display and conversation today
+func RecordShow(db *pop.Connection, display string, conversationID uuid.UUID, now time.Time) error {
+	return db.RawQuery(`INSERT INTO conversation_shows (id, created_at, updated_at, day, display, conversation_id, count)
+		VALUES (?, ?, ?, ?, ?, ?, 1)
+		ON CONFLICT (day, display, conversation_id) DO UPDATE SET count = conversation_shows.count + 1, updated_at = EXCLUDED.updated_at`,
+		uuid.Must(uuid.NewV4()), now, now, now.Format("2006-01-02"), display, conversationID).Exec()
+}
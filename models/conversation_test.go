@@ -56,7 +56,7 @@ func (ms *ModelSuite) Test_CreateConversation() {
 		{3, 0, true},
 		{0, 3, true},
 	}
-	authors, _, _ := loadFixtureData(ms) // re-use from quote_test.go
+	authors, _ := loadFixtureData(ms) // re-use from quote_test.go
 
 	for _, tt := range tests {
 
@@ -104,7 +104,7 @@ func (ms *ModelSuite) Test_UpdateConversation() {
 		{0, 3, true},
 	}
 
-	authors, _, conversations := loadFixtureData(ms)
+	authors, conversations := loadFixtureData(ms)
 	ms.LoadFixture("test quotes")
 
 	quotes := []Quote{}
@@ -143,7 +143,7 @@ func (ms *ModelSuite) Test_UpdateConversation() {
 }
 
 func (ms *ModelSuite) Test_UpdateConversationAddingQuote() {
-	authors, _, conversations := loadFixtureData(ms)
+	authors, conversations := loadFixtureData(ms)
 	ms.LoadFixture("test quotes")
 
 	quotes := []Quote{}
@@ -186,20 +186,24 @@ func Test_Marshal(t *testing.T) {
 	}
 
 	note := Annotation{
-		Note: "A snide comment",
+		Kind:     AnnotationAside,
+		Note:     "A snide comment",
+		AuthorID: &auth.ID,
 	}
 
 	q := Quote{
-		Phrase:     "A shiny new quote.",
-		Publish:    true,
-		SaidOn:     time.Now(),
-		AuthorID:   auth.ID,
-		Sequence:   0,
-		Annotation: &note,
+		Phrase:      "A shiny new quote.",
+		Publish:     true,
+		SaidOn:      time.Now(),
+		AuthorID:    auth.ID,
+		Sequence:    0,
+		Annotations: Annotations{note},
 	}
 	conversation := Conversation{
 		Publish:    true,
 		OccurredOn: time.Now(),
+		Meeting:    "Sprint Review",
+		Topic:      "Naming",
 	}
 	conversation.Quotes = append(conversation.Quotes, q)
 
@@ -240,7 +244,17 @@ func Test_Marshal(t *testing.T) {
 		t.Fatal("Quote Author changed!")
 	}
 
-	if strings.Compare(cv2.Quotes[0].Annotation.Note, conversation.Quotes[0].Annotation.Note) != 0 {
+	if cv2.Meeting != conversation.Meeting || cv2.Topic != conversation.Topic {
+		t.Fatal("Conversation context changed!")
+	}
+
+	if len(cv2.Quotes[0].Annotations) != 1 {
+		t.Fatal("Quote Annotation count changed!")
+	}
+
+	got := cv2.Quotes[0].Annotations[0]
+
+	if got.Note != note.Note || got.Kind != note.Kind || got.AuthorID == nil || *got.AuthorID != auth.ID {
 		t.Fatal("Quote Annotation changed!")
 	}
 
@@ -273,16 +287,76 @@ func Test_Marshal(t *testing.T) {
 					"updated_at":"0001-01-01T00:00:00Z",
 					"name":""
 				},
-				"Annotation":{
-					"id":"00000000-0000-0000-0000-000000000000",
-					"created_at":"0001-01-01T00:00:00Z",
-					"updated_at":"0001-01-01T00:00:00Z",
-					"note":"A snide comment"
-				},
+				"annotations":[{
+					"kind":"aside",
+					"note":"A snide comment",
+					"author_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+				}],
 				"conversation_id":"00000000-0000-0000-0000-000000000000",
-				"author_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
-				"annotation_id":null
+				"author_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"
 			}]
 		}
 	*/
 }
+
+func Test_ConversationContext(t *testing.T) {
+	tests := []struct {
+		test string
+		conv Conversation
+		want string
+	}{
+		{test: "None", conv: Conversation{}, want: ""},
+		{test: "All", conv: Conversation{Meeting: "Sprint Review", Location: "Room 4", Topic: "Naming"}, want: "Sprint Review · Room 4 · Naming"},
+		{test: "Some", conv: Conversation{Location: " Room 4 ", Topic: "Naming"}, want: "Room 4 · Naming"},
+		{test: "Blank", conv: Conversation{Meeting: "  ", Topic: "Naming"}, want: "Naming"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.conv.Context(); got != tt.want {
+			t.Errorf("Context(%s) got %q, wanted %q", tt.test, got, tt.want)
+		}
+	}
+}
+
+func Test_AnniversaryClause(t *testing.T) {
+	day := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		test   string
+		window int
+		days   int
+		parts  []string
+	}{
+		{test: "Exact", window: 0, days: 1, parts: []string{"EXTRACT(MONTH FROM occurredon) = 1 AND EXTRACT(DAY FROM occurredon) = 1", "occurredon < '2021-01-01'"}},
+		{test: "Window", window: 2, days: 5, parts: []string{"EXTRACT(MONTH FROM occurredon) = 12 AND EXTRACT(DAY FROM occurredon) = 30", "EXTRACT(DAY FROM occurredon) = 3", "occurredon < '2020-12-30'"}},
+		{test: "Negative", window: -4, days: 1, parts: []string{"EXTRACT(DAY FROM occurredon) = 1)", "occurredon < '2021-01-01'"}},
+	}
+
+	rq := require.New(t)
+
+	for _, tt := range tests {
+		clause := AnniversaryClause(dialectPostgres, "occurredon", day, tt.window)
+
+		for _, part := range tt.parts {
+			rq.Containsf(clause, part, "AnniversaryClause(%s) missing %s", tt.test, part)
+		}
+
+		rq.Equalf(tt.days, strings.Count(clause, " OR ")+1, "AnniversaryClause(%s) has wrong number of days", tt.test)
+	}
+}
+
+func (ms *ModelSuite) Test_OccurredOnThisDay() {
+	_, conversations := loadFixtureData(ms)
+
+	// move one conversation back a year so it becomes an anniversary
+	anniv := conversations[0]
+	anniv.OccurredOn = time.Now().AddDate(-1, 0, 0)
+	ms.NoError(ms.DB.Update(&anniv))
+
+	found := Conversations{}
+	err := found.OccurredOnThisDay(ms.DB, time.Now(), 0)
+
+	ms.NoError(err)
+	ms.Len(found, 1)
+	ms.Equal(anniv.ID, found[0].ID)
+}
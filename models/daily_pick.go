@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// DailyPick records which conversation was the "quote of the day"
+// on a particular wall, and for a speaker if the pick was limited to
+// one.  Once a day has been picked it never changes, even if
+// conversations get added or removed later that day.
+type DailyPick struct {
+	ID        uuid.UUID `json:"-" db:"id"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+	Day       time.Time `json:"day" db:"day"`
+	Wall      string    `json:"wall" db:"wall"`
+	Speaker   string    `json:"speaker,omitempty" db:"speaker"` // blank when anyone could be picked
+
+	// Relationships
+	Conversation Conversation `json:"conversation" belongs_to:"conversation" db:"-"`
+
+	// Foreign keys
+	ConversationID uuid.UUID `json:"-" db:"conversation_id"`
+}
+
+// String is not required by pop and may be deleted
+func (d DailyPick) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// DailyPicks is not required by pop and may be deleted
+type DailyPicks []DailyPick
+
+// String is not required by pop and may be deleted
+func (d DailyPicks) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (d *DailyPick) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.TimeIsPresent{Field: d.Day, Name: "Day"},
+		&validators.StringIsPresent{Field: d.Wall, Name: "Wall"},
+		&validators.StringLengthInRange{Field: d.Wall, Name: "Wall", Min: 1, Max: 255, Message: "length must be 1-255"},
+		&validators.StringLengthInRange{Field: d.Speaker, Name: "Speaker", Min: 0, Max: 255, Message: "length must be 0-255"},
+		&validators.FuncValidator{
+			Field:   d.ConversationID.String(),
+			Name:    "ConversationID",
+			Message: "dailypick.ConversationID %s is NIL",
+			Fn: func() bool {
+				return !(d.ConversationID == uuid.Nil)
+			},
+		},
+	), nil
+}
+
+// FindByDay looks for the pick already made for the wall and speaker on
+// the day.  If there isn't one, the ID is left as uuid.Nil
+func (d *DailyPick) FindByDay(db *pop.Connection) error {
+	picks := DailyPicks{}
+	err := db.Where("day = ? AND wall = ? AND speaker = ?", d.Day.Format("2006-01-02"), d.Wall, d.Speaker).All(&picks)
+
+	if err != nil {
+		return err
+	}
+
+	if len(picks) == 0 {
+		d.ID = uuid.Nil
+		return nil
+	}
+
+	*d = picks[0]
+
+	return nil
+}
+
+// Claim records the pick.  If another request already recorded a pick
+// for the same day, wall and speaker, his pick wins and gets loaded
+// instead.
+func (d *DailyPick) Claim(db *pop.Connection) (*validate.Errors, error) {
+	verrs, err := d.Validate(db)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	now := time.Now()
+	err = db.RawQuery("INSERT INTO daily_picks (id, created_at, updated_at, day, wall, speaker, conversation_id) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (day, wall, speaker) DO NOTHING",
+		uuid.Must(uuid.NewV4()), now, now, d.Day.Format("2006-01-02"), d.Wall, d.Speaker, d.ConversationID).Exec()
+
+	if err != nil {
+		return verrs, err
+	}
+
+	return verrs, d.FindByDay(db)
+}
+
+// History loads the picks made for the wall and speaker on the days
+// before the passed day, most recent first.
+func (d *DailyPicks) History(db *pop.Connection, wall, speaker string, day time.Time, days int) error {
+	return db.Eager("Conversation.Quotes.Author").
+		Where("wall = ? AND speaker = ? AND day < ?", wall, speaker, day.Format("2006-01-02")).
+		Order("day DESC").
+		Limit(days).
+		All(d)
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/uuid"
+)
+
+func (ms *ModelSuite) Test_DailyPick_Claim() {
+	_, conversations := loadFixtureData(ms)
+
+	day := time.Date(2020, time.October, 8, 0, 0, 0, 0, time.UTC)
+
+	pick := &DailyPick{Day: day, Wall: "test", ConversationID: conversations[0].ID}
+	verrs, err := pick.Claim(ms.DB)
+
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.NotEqual(uuid.Nil, pick.ID)
+
+	// a second claim for the same day gets the first pick back
+	late := &DailyPick{Day: day, Wall: "test", ConversationID: conversations[1].ID}
+	verrs, err = late.Claim(ms.DB)
+
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal(pick.ID, late.ID)
+	ms.Equal(conversations[0].ID, late.ConversationID)
+
+	// but a different wall gets his own pick
+	other := &DailyPick{Day: day, Wall: "other", ConversationID: conversations[1].ID}
+	_, err = other.Claim(ms.DB)
+
+	ms.NoError(err)
+	ms.NotEqual(pick.ID, other.ID)
+
+	// and so does a pick limited to one speaker
+	shari := &DailyPick{Day: day, Wall: "test", Speaker: "shari", ConversationID: conversations[1].ID}
+	_, err = shari.Claim(ms.DB)
+
+	ms.NoError(err)
+	ms.NotEqual(pick.ID, shari.ID)
+	ms.Equal(conversations[1].ID, shari.ConversationID)
+
+	var history DailyPicks
+	err = history.History(ms.DB, "test", "", day.AddDate(0, 0, 1), 7)
+
+	ms.NoError(err)
+	ms.Len(history, 1)
+	ms.Equal(pick.ID, history[0].ID)
+}
+
+func (ms *ModelSuite) Test_DailyPick_Validate() {
+	pick := &DailyPick{}
+	verrs, err := pick.Validate(ms.DB)
+
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+}
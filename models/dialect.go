@@ -0,0 +1,304 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/pkg/errors"
+)
+
+// The archive runs on Postgres.  SQLite works too, for development and
+// tests without a database server, built with "-tags sqlite".  Where the
+// two differ, the SQL for each lives here.  Postgres does the shuffle,
+// the request metrics and the requests table rotation in stored
+// functions, SQLite doesn't have those so they're done in Go.
+
+// the dialect names pop uses
+const dialectPostgres = "postgres"
+const dialectSQLite = "sqlite3"
+
+// IsSQLite says if db is SQLite rather than Postgres
+func IsSQLite(db *pop.Connection) bool {
+	return db.Dialect.Name() == dialectSQLite
+}
+
+// datePart is the SQL for the number of the month or day in a date,
+// part is MONTH or DAY
+func datePart(dialect, part, column string) string {
+	if dialect != dialectSQLite {
+		return fmt.Sprintf("EXTRACT(%s FROM %s)", part, column)
+	}
+
+	format := "%d"
+	if part == "MONTH" {
+		format = "%m"
+	}
+
+	return fmt.Sprintf("CAST(strftime('%s', %s) AS INTEGER)", format, column)
+}
+
+// ShuffleDeck deals out the published conversations in a new random
+// order into shuffled_conversations, and returns how many there are
+func ShuffleDeck(db *pop.Connection) (int, error) {
+	if !IsSQLite(db) {
+		count := struct {
+			Size sql.NullInt64 `db:"size"`
+		}{}
+
+		err := db.RawQuery("SELECT shuffle_deck() AS size").First(&count)
+
+		return int(count.Size.Int64), errors.WithStack(err)
+	}
+
+	ids := []struct {
+		ID uuid.UUID `db:"id"`
+	}{}
+
+	if err := db.RawQuery("SELECT id FROM conversations WHERE publish = TRUE").All(&ids); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	stmts := []string{
+		"DROP TABLE IF EXISTS shuffled_conversations",
+		`CREATE TABLE shuffled_conversations (
+			id char(36) NOT NULL REFERENCES conversations (id) ON DELETE RESTRICT DEFERRABLE INITIALLY DEFERRED,
+			sequence INTEGER NOT NULL PRIMARY KEY)`,
+		"DROP TABLE IF EXISTS shuffle_marker",
+		"CREATE TABLE shuffle_marker (day TEXT NOT NULL, size INTEGER NOT NULL, tag TEXT NOT NULL)",
+	}
+
+	for _, s := range stmts {
+		if err := db.RawQuery(s).Exec(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+
+	// the sequence starts at one like the Postgres version
+	for i, c := range ids {
+		if err := db.RawQuery("INSERT INTO shuffled_conversations (sequence, id) VALUES (?, ?)", i+1, c.ID).Exec(); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+
+	// the day comes from the database's clock, the same as CURRENT_DATE
+	err := db.RawQuery("INSERT INTO shuffle_marker (day, size, tag) VALUES (date('now'), ?, ?)", len(ids), uuid.Must(uuid.NewV4()).String()).Exec()
+
+	return len(ids), errors.WithStack(err)
+}
+
+// ShuffledOn is the day the deck was last shuffled.  Postgres keeps it as
+// the comment on shuffled_conversations.
+func ShuffledOn(db *pop.Connection) (time.Time, error) {
+	day := struct {
+		Day sql.NullString `db:"day"`
+	}{}
+
+	q := "SELECT OBJ_DESCRIPTION('public.shuffled_conversations'::regclass) AS day"
+	if IsSQLite(db) {
+		q = "SELECT day FROM shuffle_marker"
+	}
+
+	if err := db.RawQuery(q).First(&day); err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+
+	t, err := time.Parse("2006-01-02", strings.Trim(day.Day.String, "\""))
+
+	return t, errors.WithStack(err)
+}
+
+// ServerTimeUTC is what the database's clock says, in UTC
+func ServerTimeUTC(db *pop.Connection) (time.Time, error) {
+	now := struct {
+		Now string `db:"now"`
+	}{}
+
+	q := "SELECT CURRENT_TIMESTAMP AT TIME ZONE 'UTC' AS now"
+	if IsSQLite(db) {
+		q = "SELECT strftime('%Y-%m-%dT%H:%M:%S', 'now') AS now"
+	}
+
+	if err := db.RawQuery(q).First(&now); err != nil {
+		return time.Time{}, errors.WithStack(err)
+	}
+
+	// drop the fraction of a second, and the zone Postgres tacks on
+	s := strings.TrimSuffix(strings.Split(now.Now, ".")[0], "Z")
+	t, err := time.Parse("2006-01-02T15:04:05", s)
+
+	return t, errors.WithStack(err)
+}
+
+// maxBucket is where a requests row is full, the largest integer column
+const maxBucket = 2147483647
+
+// LogMetric adds a request that took ms milliseconds to today's row of
+// the requests table, starting a new row each day or when it fills up
+func LogMetric(db *pop.Connection, ms int64) error {
+	if !IsSQLite(db) {
+		return errors.WithStack(db.RawQuery("SELECT * FROM log_metric( ? );", fmt.Sprint(ms)).Exec())
+	}
+
+	last := struct {
+		ID        int64     `db:"id"`
+		Count     int64     `db:"count"`
+		ExecMS    int64     `db:"exec_ms"`
+		CreatedAt time.Time `db:"created_at"`
+	}{}
+
+	now := time.Now()
+	err := db.RawQuery("SELECT id, count, exec_ms, created_at FROM requests ORDER BY id DESC LIMIT 1").First(&last)
+
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return errors.WithStack(err)
+	}
+
+	if err == nil && last.CreatedAt.YearDay() == now.YearDay() && maxBucket-last.ExecMS >= ms && maxBucket-last.Count >= 5 {
+		return errors.WithStack(db.RawQuery("UPDATE requests SET count = count + 1, exec_ms = exec_ms + ?, updated_at = ? WHERE id = ?", ms, now, last.ID).Exec())
+	}
+
+	return errors.WithStack(db.RawQuery("INSERT INTO requests (count, exec_ms, created_at, updated_at) VALUES (1, ?, ?, ?)", ms, now, now).Exec())
+}
+
+// RotateRequests renames the requests table after the span of time it
+// covers and starts a new one
+func RotateRequests(db *pop.Connection) error {
+	if !IsSQLite(db) {
+		return errors.WithStack(db.RawQuery("SELECT new_requests_table();").Exec())
+	}
+
+	span := struct {
+		First dbTime `db:"first"`
+		Last  dbTime `db:"last"`
+	}{}
+
+	err := db.RawQuery(`SELECT
+		(SELECT updated_at FROM requests ORDER BY id ASC LIMIT 1) AS first,
+		(SELECT updated_at FROM requests ORDER BY id DESC LIMIT 1) AS last`).First(&span)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	name := "requests" + span.First.Format("2006-01-02 15:04:05.999999") + " to " + span.Last.Format("2006-01-02 15:04:05.999999")
+
+	stmts := []string{
+		"ALTER TABLE requests RENAME TO " + quoteIdent(name),
+		`CREATE TABLE requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			count INTEGER NOT NULL,
+			exec_ms INTEGER NOT NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL)`,
+	}
+
+	for _, s := range stmts {
+		if err := db.RawQuery(s).Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// sqliteLocks stands in for advisory locks on SQLite, where there is
+// only ever the one instance
+var sqliteLocks = struct {
+	sync.Mutex
+	held map[string]bool
+}{held: map[string]bool{}}
+
+// trySQLiteLock takes the lock called name if it's free, the returned
+// func gives it back
+func trySQLiteLock(name string) (func(), bool) {
+	sqliteLocks.Lock()
+	defer sqliteLocks.Unlock()
+
+	if sqliteLocks.held[name] {
+		return nil, false
+	}
+
+	sqliteLocks.held[name] = true
+
+	return func() {
+		sqliteLocks.Lock()
+		delete(sqliteLocks.held, name)
+		sqliteLocks.Unlock()
+	}, true
+}
+
+// dbTime scans a date or time that SQLite hands back as text when it
+// comes out of an expression rather than a column
+type dbTime struct {
+	time.Time
+}
+
+// the layouts SQLite's date functions write
+var dbTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Scan implements sql.Scanner
+func (t *dbTime) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	}
+
+	return errors.Errorf("can't scan %T into a time", src)
+}
+
+func (t *dbTime) parse(s string) error {
+	for _, layout := range dbTimeLayouts {
+		if v, err := time.Parse(layout, s); err == nil {
+			t.Time = v
+			return nil
+		}
+	}
+
+	return errors.Errorf("can't read %q as a time", s)
+}
+
+// TruncateAll empties every table but the migration table, for tests.
+// pop's own empties SQLite's tables in whatever order they come and trips
+// over the foreign keys, so they're turned off while it runs.
+func TruncateAll(db *pop.Connection) error {
+	if !IsSQLite(db) {
+		return errors.WithStack(db.TruncateAll())
+	}
+
+	tables := []struct {
+		Name string `db:"name"`
+	}{}
+
+	if err := db.RawQuery("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> ?", db.MigrationTableName()).All(&tables); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// one Exec so it all goes down the same connection
+	stmts := []string{"PRAGMA foreign_keys = OFF"}
+	for _, t := range tables {
+		stmts = append(stmts, "DELETE FROM "+quoteIdent(t.Name))
+	}
+	stmts = append(stmts, "PRAGMA foreign_keys = ON")
+
+	return errors.WithStack(db.RawQuery(strings.Join(stmts, "; ")).Exec())
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DatePart(t *testing.T) {
+	tests := []struct {
+		dialect string
+		part    string
+		want    string
+	}{
+		{dialect: dialectPostgres, part: "MONTH", want: "EXTRACT(MONTH FROM saidon)"},
+		{dialect: dialectPostgres, part: "DAY", want: "EXTRACT(DAY FROM saidon)"},
+		{dialect: dialectSQLite, part: "MONTH", want: "CAST(strftime('%m', saidon) AS INTEGER)"},
+		{dialect: dialectSQLite, part: "DAY", want: "CAST(strftime('%d', saidon) AS INTEGER)"},
+	}
+
+	for _, tt := range tests {
+		if got := datePart(tt.dialect, tt.part, "saidon"); got != tt.want {
+			t.Errorf("datePart(%s, %s) got %s, wanted %s", tt.dialect, tt.part, got, tt.want)
+		}
+	}
+}
+
+func Test_DBTimeScan(t *testing.T) {
+	want := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		test string
+		src  interface{}
+		want time.Time
+	}{
+		{test: "Time", src: want, want: want},
+		{test: "Driver", src: "2021-03-04 05:06:07+00:00", want: want},
+		{test: "Bytes", src: []byte("2021-03-04 05:06:07"), want: want},
+		{test: "Date", src: "2021-03-04", want: time.Date(2021, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{test: "Null", src: nil, want: time.Time{}},
+	}
+
+	rq := require.New(t)
+
+	for _, tt := range tests {
+		var got dbTime
+
+		rq.NoErrorf(got.Scan(tt.src), "Scan(%s)", tt.test)
+		rq.Truef(tt.want.Equal(got.Time), "Scan(%s) got %v, wanted %v", tt.test, got.Time, tt.want)
+	}
+
+	var got dbTime
+
+	rq.Error(got.Scan("yesterday"))
+	rq.Error(got.Scan(42))
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// DisplayProfile holds the settings for one screen showing the quickie
+// wall.  A screen picks his profile by name with ?profile=name
+type DisplayProfile struct {
+	ID        uuid.UUID `json:"-" db:"id"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+	Name      string    `json:"name" db:"name" form:"Name"`
+	Title     string    `json:"title" db:"title" form:"Title"`
+
+	// how long a conversation stays up, scaled by its length
+	RefreshMin     int `json:"refresh_min" db:"refresh_min" form:"RefreshMin"`
+	RefreshMax     int `json:"refresh_max" db:"refresh_max" form:"RefreshMax"`
+	CharsPerSecond int `json:"chars_per_second" db:"chars_per_second" form:"CharsPerSecond"`
+
+	// font used for the quotes, the size shrinks as the quotes get longer
+	// so the conversation fits on a screen Width x Height pixels
+	FontFamily string `json:"font_family" db:"font_family" form:"FontFamily"`
+	FontMin    int    `json:"font_min" db:"font_min" form:"FontMin"`
+	FontMax    int    `json:"font_max" db:"font_max" form:"FontMax"`
+	Width      int    `json:"width" db:"width" form:"Width"`
+	Height     int    `json:"height" db:"height" form:"Height"`
+
+	// color theme
+	TextColor       string `json:"text_color" db:"text_color" form:"TextColor"`
+	BackgroundColor string `json:"background_color" db:"background_color" form:"BackgroundColor"`
+	SpeakerColor    string `json:"speaker_color" db:"speaker_color" form:"SpeakerColor"`
+	NoteColor       string `json:"note_color" db:"note_color" form:"NoteColor"`
+
+	// which fields to show under each quote
+	ShowSpeaker bool `json:"show_speaker" db:"show_speaker" form:"ShowSpeaker"`
+	ShowDate    bool `json:"show_date" db:"show_date" form:"ShowDate"`
+	ShowNote    bool `json:"show_note" db:"show_note" form:"ShowNote"`
+
+	// the meeting, location and topic, above the conversation
+	ShowContext bool `json:"show_context" db:"show_context" form:"ShowContext"`
+
+	// quickie filters, written like a query string "speaker=Shari&max-age=365"
+	Filters string `json:"filters" db:"filters" form:"Filters"`
+}
+
+// DefaultDisplayProfile is what the wall looks like when no profile is picked
+func DefaultDisplayProfile() *DisplayProfile {
+	return &DisplayProfile{
+		Name:            "default",
+		Title:           "Quote Wall Quickie",
+		RefreshMin:      10,
+		RefreshMax:      30,
+		CharsPerSecond:  15,
+		FontFamily:      "Bodoni MT",
+		FontMin:         24,
+		FontMax:         64,
+		Width:           1920,
+		Height:          1080,
+		TextColor:       "black",
+		BackgroundColor: "white",
+		SpeakerColor:    "blue",
+		NoteColor:       "red",
+		ShowSpeaker:     true,
+		ShowDate:        true,
+		ShowNote:        true,
+	}
+}
+
+// String is not required by pop and may be deleted
+func (d DisplayProfile) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// DisplayProfiles is not required by pop and may be deleted
+type DisplayProfiles []DisplayProfile
+
+// String is not required by pop and may be deleted
+func (d DisplayProfiles) String() string {
+	jd, _ := json.Marshal(d)
+	return string(jd)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (d *DisplayProfile) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	var err error
+	return validate.Validate(
+		&validators.StringIsPresent{Field: d.Name, Name: "Name"},
+		&validators.StringLengthInRange{Field: d.Name, Name: "Name", Min: 1, Max: 255, Message: "length must be 1-255"},
+		&validators.StringLengthInRange{Field: d.Title, Name: "Title", Min: 0, Max: 255, Message: "length must be <255"},
+		&validators.IntIsGreaterThan{Field: d.RefreshMin, Name: "RefreshMin", Compared: 0, Message: "refresh must be at least one second"},
+		&validators.IntIsGreaterThan{Field: d.RefreshMax, Name: "RefreshMax", Compared: d.RefreshMin - 1, Message: "maximum refresh must be >= minimum"},
+		&validators.IntIsGreaterThan{Field: d.CharsPerSecond, Name: "CharsPerSecond", Compared: 0, Message: "reading speed must be > 0"},
+		&validators.IntIsGreaterThan{Field: d.FontMin, Name: "FontMin", Compared: 0, Message: "font size must be > 0"},
+		&validators.IntIsGreaterThan{Field: d.FontMax, Name: "FontMax", Compared: d.FontMin - 1, Message: "maximum font must be >= minimum"},
+		&validators.IntIsGreaterThan{Field: d.Width, Name: "Width", Compared: 0, Message: "screen width must be > 0"},
+		&validators.IntIsGreaterThan{Field: d.Height, Name: "Height", Compared: 0, Message: "screen height must be > 0"},
+		&validators.FuncValidator{
+			Field:   d.Filters,
+			Name:    "Filters",
+			Message: "%s is not a valid list of filters",
+			Fn: func() bool {
+				_, perr := url.ParseQuery(d.Filters)
+				return perr == nil
+			},
+		},
+		// names pick the profile, so they have to be unique
+		&validators.FuncValidator{
+			Field:   d.Name,
+			Name:    "Name",
+			Message: "%s is already taken",
+			Fn: func() bool {
+				var b bool
+				q := tx.Where("name = ?", d.Name)
+				if d.ID != uuid.Nil {
+					q = q.Where("id != ?", d.ID)
+				}
+				b, err = q.Exists(d)
+				if err != nil {
+					return false
+				}
+				return !b
+			},
+		},
+	), err
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (d *DisplayProfile) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (d *DisplayProfile) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// FindByName loads the profile with the passed name
+func (d *DisplayProfile) FindByName(db *pop.Connection) error {
+	if len(d.Name) == 0 {
+		return errors.New("profile name can't be blank")
+	}
+
+	profs := DisplayProfiles{}
+	err := db.Where("name = ?", d.Name).All(&profs)
+
+	if err != nil {
+		return err
+	}
+
+	if len(profs) == 0 {
+		return errors.New("display profile not found in db")
+	}
+
+	*d = profs[0]
+
+	return nil
+}
+
+// FilterValues returns the profile filters ready to merge with a request
+func (d *DisplayProfile) FilterValues() url.Values {
+	vals, err := url.ParseQuery(d.Filters)
+
+	if err != nil {
+		return url.Values{}
+	}
+
+	return vals
+}
+
+// RefreshFor works out how many seconds a conversation of chars
+// characters should stay on the screen.
+func (d *DisplayProfile) RefreshFor(chars int) int {
+	secs := d.RefreshMin
+	if d.CharsPerSecond > 0 {
+		secs = chars / d.CharsPerSecond
+	}
+
+	if secs < d.RefreshMin {
+		secs = d.RefreshMin
+	}
+	if secs > d.RefreshMax {
+		secs = d.RefreshMax
+	}
+
+	return secs
+}
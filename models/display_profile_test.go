@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+)
+
+func Test_DisplayProfile_RefreshFor(t *testing.T) {
+	p := DefaultDisplayProfile()
+	p.RefreshMin = 8
+	p.RefreshMax = 30
+	p.CharsPerSecond = 10
+
+	tests := []struct {
+		chars int
+		want  int
+	}{
+		{0, 8},
+		{50, 8},
+		{150, 15},
+		{300, 30},
+		{5000, 30},
+	}
+
+	for _, tt := range tests {
+		if got := p.RefreshFor(tt.chars); got != tt.want {
+			t.Errorf("RefreshFor(%d) got %d, wanted %d", tt.chars, got, tt.want)
+		}
+	}
+}
+
+func Test_DisplayProfile_FilterValues(t *testing.T) {
+	p := DefaultDisplayProfile()
+	p.Filters = "speaker=Shari&max-age=365"
+
+	vals := p.FilterValues()
+
+	if vals.Get("speaker") != "Shari" || vals.Get("max-age") != "365" {
+		t.Errorf("FilterValues got %v", vals)
+	}
+
+	p.Filters = "bad=%zz"
+
+	if len(p.FilterValues()) != 0 {
+		t.Error("FilterValues didn't ignore a bad filter string")
+	}
+}
+
+func (ms *ModelSuite) Test_DisplayProfile_Validate() {
+	p := DefaultDisplayProfile()
+	p.Name = "lobby"
+
+	verrs, err := ms.DB.ValidateAndCreate(p)
+
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	// the name is taken now
+	dup := DefaultDisplayProfile()
+	dup.Name = "lobby"
+
+	verrs, err = dup.Validate(ms.DB)
+
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+
+	// but he can still save himself
+	verrs, err = p.Validate(ms.DB)
+
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	bad := DefaultDisplayProfile()
+	bad.Name = "bad"
+	bad.RefreshMax = bad.RefreshMin - 1
+	bad.Filters = "bad=%zz"
+
+	verrs, err = bad.Validate(ms.DB)
+
+	ms.NoError(err)
+	ms.NotNil(verrs.Get("refresh_max"))
+	ms.NotNil(verrs.Get("filters"))
+}
+
+func (ms *ModelSuite) Test_DisplayProfile_FindByName() {
+	p := DefaultDisplayProfile()
+	p.Name = "lab"
+	p.Title = "Lab Wall"
+
+	ms.NoError(ms.DB.Create(p))
+
+	found := &DisplayProfile{Name: "lab"}
+
+	ms.NoError(found.FindByName(ms.DB))
+	ms.Equal("Lab Wall", found.Title)
+
+	missing := &DisplayProfile{Name: "nobody"}
+
+	ms.Error(missing.FindByName(ms.DB))
+}
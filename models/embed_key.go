@@ -0,0 +1,185 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// AnyOrigin lets an embed be used from any site
+const AnyOrigin = "*"
+
+// EmbedKey lets another intranet page show quotes.  Every embed gets his
+// own key so it can be limited to the sites that should be using it, and
+// to the quotes that site should see.
+type EmbedKey struct {
+	ID        uuid.UUID `json:"-" db:"id"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+	Name      string    `json:"name" db:"name" form:"Name"`
+	Key       string    `json:"key" db:"key" form:"-"`
+
+	// sites allowed to use the embed, one origin per line like
+	// "https://dashboard.example.com", or * for anywhere
+	AllowedOrigins string `json:"allowed_origins" db:"allowed_origins" form:"AllowedOrigins"`
+
+	// quickie filters, written like a query string "speaker=Shari&max-age=365"
+	Filters string `json:"filters" db:"filters" form:"Filters"`
+}
+
+// NewEmbedKey returns an embed with a freshly generated key
+func NewEmbedKey() (*EmbedKey, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return &EmbedKey{Key: hex.EncodeToString(b)}, nil
+}
+
+// String is not required by pop and may be deleted
+func (e EmbedKey) String() string {
+	je, _ := json.Marshal(e)
+	return string(je)
+}
+
+// EmbedKeys is not required by pop and may be deleted
+type EmbedKeys []EmbedKey
+
+// String is not required by pop and may be deleted
+func (e EmbedKeys) String() string {
+	je, _ := json.Marshal(e)
+	return string(je)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (e *EmbedKey) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	var err error
+	return validate.Validate(
+		&validators.StringIsPresent{Field: e.Name, Name: "Name"},
+		&validators.StringLengthInRange{Field: e.Name, Name: "Name", Min: 1, Max: 255, Message: "length must be 1-255"},
+		&validators.StringLengthInRange{Field: e.Key, Name: "Key", Min: 16, Max: 255, Message: "key is too short"},
+		&validators.FuncValidator{
+			Field:   e.AllowedOrigins,
+			Name:    "AllowedOrigins",
+			Message: "%s must be a list of origins like https://example.com",
+			Fn: func() bool {
+				for _, o := range e.OriginList() {
+					if o == AnyOrigin {
+						continue
+					}
+					u, perr := url.Parse(o)
+					if perr != nil || len(u.Scheme) == 0 || len(u.Host) == 0 || len(strings.Trim(u.Path, "/")) > 0 {
+						return false
+					}
+				}
+				return true
+			},
+		},
+		&validators.FuncValidator{
+			Field:   e.Filters,
+			Name:    "Filters",
+			Message: "%s is not a valid list of filters",
+			Fn: func() bool {
+				_, perr := url.ParseQuery(e.Filters)
+				return perr == nil
+			},
+		},
+		// the key finds the embed, so it has to be unique
+		&validators.FuncValidator{
+			Field:   e.Key,
+			Name:    "Key",
+			Message: "%s is already in use",
+			Fn: func() bool {
+				var b bool
+				q := tx.Where("key = ?", e.Key)
+				if e.ID != uuid.Nil {
+					q = q.Where("id != ?", e.ID)
+				}
+				b, err = q.Exists(e)
+				if err != nil {
+					return false
+				}
+				return !b
+			},
+		},
+	), err
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (e *EmbedKey) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (e *EmbedKey) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// FindByKey loads the embed with the passed key
+func (e *EmbedKey) FindByKey(db *pop.Connection) error {
+	if len(e.Key) == 0 {
+		return errors.New("embed key can't be blank")
+	}
+
+	keys := EmbedKeys{}
+	err := db.Where("key = ?", e.Key).All(&keys)
+
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return errors.New("embed key not found in db")
+	}
+
+	*e = keys[0]
+
+	return nil
+}
+
+// OriginList splits the allowed origins into a list
+func (e *EmbedKey) OriginList() []string {
+	var origins []string
+
+	for _, o := range strings.Fields(strings.Replace(e.AllowedOrigins, ",", " ", -1)) {
+		origins = append(origins, strings.TrimSuffix(o, "/"))
+	}
+
+	return origins
+}
+
+// AllowsOrigin checks if a site is allowed to use the embed
+func (e *EmbedKey) AllowsOrigin(origin string) bool {
+	for _, o := range e.OriginList() {
+		if o == AnyOrigin || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterValues returns the embed filters ready to lock onto a request
+func (e *EmbedKey) FilterValues() url.Values {
+	vals, err := url.ParseQuery(e.Filters)
+
+	if err != nil {
+		return url.Values{}
+	}
+
+	return vals
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"testing"
+)
+
+func Test_EmbedKey_AllowsOrigin(t *testing.T) {
+	e := &EmbedKey{AllowedOrigins: "https://dash.example.com/\nhttp://wiki.example.com, http://localhost:8080"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://dash.example.com", true},
+		{"HTTPS://DASH.EXAMPLE.COM", true},
+		{"http://wiki.example.com", true},
+		{"http://localhost:8080", true},
+		{"http://dash.example.com", false},
+		{"https://evil.example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := e.AllowsOrigin(tt.origin); got != tt.want {
+			t.Errorf("AllowsOrigin(%s) got %t, wanted %t", tt.origin, got, tt.want)
+		}
+	}
+
+	e.AllowedOrigins = AnyOrigin
+
+	if !e.AllowsOrigin("https://anywhere.example.com") {
+		t.Error("AllowsOrigin didn't honor *")
+	}
+}
+
+func Test_NewEmbedKey(t *testing.T) {
+	a, err := NewEmbedKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := NewEmbedKey()
+
+	if len(a.Key) != 32 || a.Key == b.Key {
+		t.Errorf("NewEmbedKey got %s and %s", a.Key, b.Key)
+	}
+}
+
+func (ms *ModelSuite) Test_EmbedKey_Validate() {
+	e, err := NewEmbedKey()
+	ms.NoError(err)
+
+	e.Name = "team dashboard"
+	e.AllowedOrigins = "https://dash.example.com"
+	e.Filters = "speaker=Shari"
+
+	verrs, err := ms.DB.ValidateAndCreate(e)
+
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	found := &EmbedKey{Key: e.Key}
+	ms.NoError(found.FindByKey(ms.DB))
+	ms.Equal("team dashboard", found.Name)
+
+	// same key again
+	dup := &EmbedKey{Name: "copy", Key: e.Key}
+	verrs, err = dup.Validate(ms.DB)
+
+	ms.NoError(err)
+	ms.NotNil(verrs.Get("key"))
+
+	bad, _ := NewEmbedKey()
+	bad.Name = "bad"
+	bad.AllowedOrigins = "dash.example.com https://dash.example.com/page"
+
+	verrs, err = bad.Validate(ms.DB)
+
+	ms.NoError(err)
+	ms.NotNil(verrs.Get("allowed_origins"))
+}
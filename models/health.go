@@ -0,0 +1,135 @@
+package models
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/packd"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// migrationsBox holds the migrations the binary was built with, so it can
+// tell whether the database has caught up with them
+var migrationsBox = packr.New("app:models:migrations", "../migrations")
+
+// ArchiveCounts is how big the archive is
+type ArchiveCounts struct {
+	Conversations int `json:"conversations"`
+	Quotes        int `json:"quotes"`
+	Authors       int `json:"authors"`
+	Annotations   int `json:"annotations"`
+}
+
+// Ping checks that the database answers
+func Ping(db *pop.Connection) error {
+	return errors.WithStack(db.RawQuery("SELECT 1").Exec())
+}
+
+// SchemaVersion is the newest migration the database has run
+func SchemaVersion(db *pop.Connection) (string, error) {
+	v := struct {
+		Version nulls.String `db:"version"`
+	}{}
+
+	err := db.RawQuery("SELECT MAX(version) AS version FROM " + quoteIdent(db.MigrationTableName())).First(&v)
+
+	return v.Version.String, errors.WithStack(err)
+}
+
+// PendingMigrations lists the versions built into the binary that the
+// database hasn't run yet
+func PendingMigrations(db *pop.Connection) ([]string, error) {
+	rows := []struct {
+		Version string `db:"version"`
+	}{}
+
+	if err := db.RawQuery("SELECT version FROM " + quoteIdent(db.MigrationTableName())).All(&rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	applied := map[string]bool{}
+	for _, r := range rows {
+		applied[r.Version] = true
+	}
+
+	return pendingVersions(migrationsBox, applied)
+}
+
+// pendingVersions finds the up migrations in box that aren't applied
+func pendingVersions(box packd.Walkable, applied map[string]bool) ([]string, error) {
+	pending := []string{}
+
+	err := box.Walk(func(p string, f packd.File) error {
+		m, err := pop.ParseMigrationFilename(filepath.Base(p))
+
+		// anything else in the folder, like schema.sql, isn't a migration
+		if err != nil || m == nil || m.Direction != "up" {
+			return nil
+		}
+
+		if !applied[m.Version] {
+			pending = append(pending, m.Version)
+		}
+
+		return nil
+	})
+
+	sort.Strings(pending)
+
+	return pending, errors.WithStack(err)
+}
+
+// Migrate runs the migrations the database hasn't had yet.  Postgres
+// gets migrated with "buffalo pop migrate", SQLite databases are set up
+// by the app itself.
+func Migrate(db *pop.Connection) error {
+	// write ahead logging lets the login failures and metrics, which go
+	// outside the request's transaction, get written while it's open
+	if IsSQLite(db) {
+		if err := db.RawQuery("PRAGMA journal_mode = WAL").Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	mig, err := pop.NewMigrationBox(migrationsBox, db)
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(mig.Up())
+}
+
+// ShuffleSize is how many conversations are in today's shuffle
+func ShuffleSize(db *pop.Connection) (int, error) {
+	n, err := db.Count("shuffled_conversations")
+
+	return n, errors.WithStack(err)
+}
+
+// CountArchive counts up what is in the archive
+func CountArchive(db *pop.Connection) (ArchiveCounts, error) {
+	ac := ArchiveCounts{}
+	var err error
+
+	if ac.Conversations, err = db.Count(&Conversation{}); err != nil {
+		return ac, errors.WithStack(err)
+	}
+
+	if ac.Quotes, err = db.Count(&Quote{}); err != nil {
+		return ac, errors.WithStack(err)
+	}
+
+	if ac.Authors, err = db.Count(&Author{}); err != nil {
+		return ac, errors.WithStack(err)
+	}
+
+	if ac.Annotations, err = db.Count(&Annotation{}); err != nil {
+		return ac, errors.WithStack(err)
+	}
+
+	return ac, nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gobuffalo/packd"
+)
+
+func Test_pendingVersions(t *testing.T) {
+	box := packd.NewMemoryBox()
+	box.AddString("20201008123554_quotewall.up.fizz", "")
+	box.AddString("20201008123554_quotewall.down.fizz", "")
+	box.AddString("20261019200000_job_runs.up.fizz", "")
+	box.AddString("20261019190000_conversation_shows.up.fizz", "")
+	box.AddString("schema.sql", "")
+
+	got, err := pendingVersions(box, map[string]bool{"20201008123554": true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"20261019190000", "20261019200000"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func (ms *ModelSuite) Test_CountArchive() {
+	loadFixtureData(ms)
+	ms.LoadFixture("test quotes")
+
+	ac, err := CountArchive(ms.DB)
+	ms.NoError(err)
+
+	quotes, err := ms.DB.Count(&Quote{})
+	ms.NoError(err)
+	ms.Equal(quotes, ac.Quotes)
+	ms.True(ac.Conversations > 0)
+	ms.True(ac.Authors > 0)
+
+	size, err := ShuffleSize(ms.DB)
+	ms.NoError(err)
+	ms.Equal(0, size)
+}
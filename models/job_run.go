@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/pkg/errors"
+)
+
+// how a job run ended up
+const (
+	JobRunning = "running"
+	JobOK      = "ok"
+	JobFailed  = "failed"
+)
+
+// JobRun is one run of a scheduled job, on whichever instance got the lock
+type JobRun struct {
+	ID         uuid.UUID  `json:"-" db:"id"`
+	CreatedAt  time.Time  `json:"-" db:"created_at"`
+	UpdatedAt  time.Time  `json:"-" db:"updated_at"`
+	Name       string     `json:"name" db:"name"`
+	Host       string     `json:"host" db:"host"`
+	Status     string     `json:"status" db:"status"`
+	Message    string     `json:"message" db:"message"`
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt nulls.Time `json:"finished_at" db:"finished_at"`
+}
+
+// String is not required by pop and may be deleted
+func (j JobRun) String() string {
+	jj, _ := json.Marshal(j)
+	return string(jj)
+}
+
+// JobRuns is not required by pop and may be deleted
+type JobRuns []JobRun
+
+// String is not required by pop and may be deleted
+func (j JobRuns) String() string {
+	jj, _ := json.Marshal(j)
+	return string(jj)
+}
+
+// Duration is how long the run took, or has taken so far
+func (j JobRun) Duration() time.Duration {
+	if !j.FinishedAt.Valid {
+		return time.Since(j.StartedAt).Round(time.Second)
+	}
+
+	return j.FinishedAt.Time.Sub(j.StartedAt).Round(time.Millisecond)
+}
+
+// StartJobRun records that the job has started
+func StartJobRun(db *pop.Connection, name, host string, now time.Time) (*JobRun, error) {
+	j := &JobRun{Name: name, Host: host, Status: JobRunning, StartedAt: now}
+
+	return j, errors.WithStack(db.Create(j))
+}
+
+// Finish records how the run went
+func (j *JobRun) Finish(db *pop.Connection, message string, err error, now time.Time) error {
+	j.Status = JobOK
+	j.Message = message
+	j.FinishedAt = nulls.NewTime(now)
+
+	if err != nil {
+		j.Status = JobFailed
+		j.Message = err.Error()
+	}
+
+	return db.UpdateColumns(j, "status", "message", "finished_at", "updated_at")
+}
+
+// Recent loads the latest runs of every job, newest first
+func (j *JobRuns) Recent(db *pop.Connection, limit int) error {
+	return db.Order("started_at DESC").Limit(limit).All(j)
+}
+
+// Latest loads the last run of each job
+func (j *JobRuns) Latest(db *pop.Connection) error {
+	return db.RawQuery(`SELECT * FROM job_runs j WHERE started_at = (
+		SELECT MAX(started_at) FROM job_runs WHERE name = j.name) ORDER BY name`).All(j)
+}
+
+// WithAdvisoryLock runs fn only if no one else holds the lock called name.
+// The lock is a Postgres advisory lock, so it works across every instance
+// sharing the database, and it goes away with the transaction even if the
+// instance dies part way through.  SQLite only ever has the one instance,
+// the lock there is held in memory.
+func WithAdvisoryLock(db *pop.Connection, name string, fn func() error) (bool, error) {
+	ran := false
+
+	if IsSQLite(db) {
+		unlock, ok := trySQLiteLock(name)
+
+		if !ok {
+			return false, nil
+		}
+		defer unlock()
+
+		return true, fn()
+	}
+
+	err := db.Transaction(func(tx *pop.Connection) error {
+		lock := struct {
+			Locked bool `db:"locked"`
+		}{}
+
+		if err := tx.RawQuery("SELECT pg_try_advisory_xact_lock(?) AS locked", lockKey(name)).First(&lock); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if !lock.Locked {
+			return nil
+		}
+
+		ran = true
+
+		return fn()
+	})
+
+	return ran, err
+}
+
+// lockKey turns a lock name into the number Postgres locks on
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("quotewall:" + name))
+
+	return int64(h.Sum64())
+}
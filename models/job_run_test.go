@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+func (ms *ModelSuite) Test_JobRun() {
+	start := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+
+	first, err := StartJobRun(ms.DB, "backup", "one", start)
+	ms.NoError(err)
+	ms.NoError(first.Finish(ms.DB, "", errors.New("disk full"), start.Add(time.Minute)))
+
+	second, err := StartJobRun(ms.DB, "backup", "two", start.AddDate(0, 0, 1))
+	ms.NoError(err)
+	ms.NoError(second.Finish(ms.DB, "wrote it", nil, start.AddDate(0, 0, 1).Add(2*time.Second)))
+
+	ms.Equal(time.Minute, first.Duration())
+
+	latest := JobRuns{}
+	ms.NoError(latest.Latest(ms.DB))
+	ms.Len(latest, 1)
+	ms.Equal("two", latest[0].Host)
+	ms.Equal(JobOK, latest[0].Status)
+	ms.Equal("wrote it", latest[0].Message)
+
+	recent := JobRuns{}
+	ms.NoError(recent.Recent(ms.DB, 10))
+	ms.Len(recent, 2)
+	ms.Equal(JobFailed, recent[1].Status)
+	ms.Equal("disk full", recent[1].Message)
+}
+
+func (ms *ModelSuite) Test_WithAdvisoryLock() {
+	inner := true
+
+	ran, err := WithAdvisoryLock(ms.DB, "purge", func() error {
+		var err error
+		inner, err = WithAdvisoryLock(ms.DB, "purge", func() error { return nil })
+
+		return err
+	})
+
+	ms.NoError(err)
+	ms.True(ran)
+	ms.False(inner)
+
+	// the lock is gone with the transaction
+	ran, err = WithAdvisoryLock(ms.DB, "purge", func() error { return nil })
+	ms.NoError(err)
+	ms.True(ran)
+}
+
+func (ms *ModelSuite) Test_Purge() {
+	now := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC)
+
+	old, err := StartJobRun(ms.DB, "purge", "one", now.Add(-JobRunRetention-time.Hour))
+	ms.NoError(err)
+	ms.NoError(old.Finish(ms.DB, "", nil, old.StartedAt))
+
+	_, err = StartJobRun(ms.DB, "purge", "stuck", now.Add(-JobRunRetention-time.Hour))
+	ms.NoError(err)
+
+	_, err = StartJobRun(ms.DB, "purge", "new", now.Add(-time.Hour))
+	ms.NoError(err)
+
+	purged, err := Purge(ms.DB, now)
+	ms.NoError(err)
+	ms.Equal(1, purged["job_runs"])
+	ms.Contains(purged, "user_sessions")
+
+	count, err := ms.DB.Count(&JobRun{})
+	ms.NoError(err)
+	ms.Equal(2, count)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// what happened
+const (
+	LoginSignIn          = "signin"
+	LoginFailed          = "signin_failed"
+	LoginLockedOut       = "locked_out"
+	LoginSignOut         = "signout"
+	LoginRevoked         = "session_revoked"
+	LoginPasswordChanged = "password_changed"
+	LoginPasswordReset   = "password_reset"
+	LoginTwoFactorOn     = "two_factor_enabled"
+	LoginTwoFactorOff    = "two_factor_disabled"
+	LoginTwoFactorFailed = "two_factor_failed"
+	LoginRecoveryUsed    = "recovery_code_used"
+)
+
+// LoginEvent is one line in the sign in log.  Failures for unknown
+// addresses are kept too, with no user.
+type LoginEvent struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	UserID     nulls.UUID `json:"user_id" db:"user_id"`
+	Email      string     `json:"email" db:"email"`
+	Event      string     `json:"event" db:"event"`
+	RemoteAddr string     `json:"remote_addr" db:"remote_addr"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+}
+
+// String is not required by pop and may be deleted
+func (l LoginEvent) String() string {
+	jl, _ := json.Marshal(l)
+	return string(jl)
+}
+
+// LoginEvents is not required by pop and may be deleted
+type LoginEvents []LoginEvent
+
+// String is not required by pop and may be deleted
+func (l LoginEvents) String() string {
+	jl, _ := json.Marshal(l)
+	return string(jl)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (l *LoginEvent) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: l.Event, Name: "Event"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (l *LoginEvent) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (l *LoginEvent) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// LogLoginEvent adds to the sign in log.  u can be nil when the email
+// doesn't belong to anybody.
+func LogLoginEvent(db *pop.Connection, event string, u *User, email, remote, agent string) error {
+	l := &LoginEvent{
+		Email:      strings.ToLower(strings.TrimSpace(email)),
+		Event:      event,
+		RemoteAddr: remote,
+		UserAgent:  agent,
+	}
+
+	if u != nil {
+		l.UserID = nulls.NewUUID(u.ID)
+		l.Email = u.Email
+	}
+
+	return db.Create(l)
+}
+
+// ForUser loads the newest events for the user
+func (l *LoginEvents) ForUser(db *pop.Connection, userID uuid.UUID, limit int) error {
+	return db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).All(l)
+}
+
+// Recent loads the newest events for everybody
+func (l *LoginEvents) Recent(db *pop.Connection, limit int) error {
+	return db.Order("created_at DESC").Limit(limit).All(l)
+}
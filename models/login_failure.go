@@ -0,0 +1,162 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// what a run of failures is counted against
+const (
+	ScopeAccount = "account" // keyed by email address
+	ScopeIP      = "ip"      // keyed by remote address
+	ScopeReset   = "reset"   // password reset requests, keyed by remote address
+)
+
+// LockoutBase is how long the first lockout lasts, each failure after
+// that doubles it up to LockoutMax
+const LockoutBase = time.Minute
+
+// LockoutMax caps how long a lockout can get
+const LockoutMax = time.Hour
+
+// FailureWindow is how long a quiet spell has to be before the count
+// starts over
+const FailureWindow = 24 * time.Hour
+
+// LoginFailure counts the failed sign ins for an account or address
+type LoginFailure struct {
+	ID           uuid.UUID  `json:"id" db:"id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	Scope        string     `json:"scope" db:"scope"`
+	Key          string     `json:"key" db:"key"`
+	Failures     int        `json:"failures" db:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at" db:"last_failed_at"`
+	LockedUntil  nulls.Time `json:"locked_until" db:"locked_until"`
+}
+
+// String is not required by pop and may be deleted
+func (l LoginFailure) String() string {
+	jl, _ := json.Marshal(l)
+	return string(jl)
+}
+
+// LoginFailures is not required by pop and may be deleted
+type LoginFailures []LoginFailure
+
+// String is not required by pop and may be deleted
+func (l LoginFailures) String() string {
+	jl, _ := json.Marshal(l)
+	return string(jl)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (l *LoginFailure) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringInclusion{Field: l.Scope, Name: "Scope", List: []string{ScopeAccount, ScopeIP, ScopeReset}},
+		&validators.StringIsPresent{Field: l.Key, Name: "Key"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (l *LoginFailure) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (l *LoginFailure) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// LockoutDuration is how long to lock out after the given number of
+// failures in a row.  Nothing happens until threshold is reached.
+func LockoutDuration(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	wait := LockoutBase
+
+	for i := threshold; i < failures; i++ {
+		wait *= 2
+		if wait >= LockoutMax {
+			return LockoutMax
+		}
+	}
+
+	return wait
+}
+
+// LockedOut says if the scope and key are locked out at now, and until
+// when
+func LockedOut(db *pop.Connection, scope, key string, now time.Time) (time.Time, bool, error) {
+	l := &LoginFailure{}
+
+	err := db.Where("scope = ? AND key = ?", scope, key).First(l)
+
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, errors.WithStack(err)
+	}
+
+	if l.LockedUntil.Valid && now.Before(l.LockedUntil.Time) {
+		return l.LockedUntil.Time, true, nil
+	}
+
+	return time.Time{}, false, nil
+}
+
+// RecordLoginFailure counts one more failure for the scope and key and
+// locks it out once threshold is reached.  It should be given a
+// connection outside the request transaction, since a failed sign in
+// rolls that back.
+func RecordLoginFailure(db *pop.Connection, scope, key string, threshold int, now time.Time) (*LoginFailure, error) {
+	l := &LoginFailure{Scope: scope, Key: key}
+
+	if err := db.Where("scope = ? AND key = ?", scope, key).First(l); err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return nil, errors.WithStack(err)
+	}
+
+	// a quiet spell wipes the slate
+	if now.Sub(l.LastFailedAt) > FailureWindow {
+		l.Failures = 0
+	}
+
+	l.Failures++
+	l.LastFailedAt = now
+	l.LockedUntil = nulls.Time{}
+
+	if wait := LockoutDuration(l.Failures, threshold); wait > 0 {
+		l.LockedUntil = nulls.NewTime(now.Add(wait))
+	}
+
+	verrs, err := db.ValidateAndSave(l)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return nil, errors.New(verrs.String())
+	}
+
+	return l, nil
+}
+
+// ClearLoginFailures forgets the failures for the scope and key
+func ClearLoginFailures(db *pop.Connection, scope, key string) error {
+	return db.RawQuery("DELETE FROM login_failures WHERE scope = ? AND key = ?", scope, key).Exec()
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_LockoutDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, LockoutBase},
+		{6, 2 * LockoutBase},
+		{8, 8 * LockoutBase},
+		{50, LockoutMax},
+	}
+
+	for _, tt := range tests {
+		if got := LockoutDuration(tt.failures, 5); got != tt.want {
+			t.Errorf("LockoutDuration(%d) got %s, wanted %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func (ms *ModelSuite) Test_RecordLoginFailure() {
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		_, err := RecordLoginFailure(ms.DB, ScopeAccount, "mark@example.com", 3, now)
+		ms.NoError(err)
+	}
+
+	_, locked, err := LockedOut(ms.DB, ScopeAccount, "mark@example.com", now)
+	ms.NoError(err)
+	ms.False(locked)
+
+	l, err := RecordLoginFailure(ms.DB, ScopeAccount, "mark@example.com", 3, now)
+	ms.NoError(err)
+	ms.Equal(3, l.Failures)
+
+	until, locked, err := LockedOut(ms.DB, ScopeAccount, "mark@example.com", now)
+	ms.NoError(err)
+	ms.True(locked)
+	ms.WithinDuration(now.Add(LockoutBase), until, time.Second)
+
+	// the address is counted separately
+	_, locked, err = LockedOut(ms.DB, ScopeIP, "mark@example.com", now)
+	ms.NoError(err)
+	ms.False(locked)
+
+	// a quiet day starts the count over
+	l, err = RecordLoginFailure(ms.DB, ScopeAccount, "mark@example.com", 3, now.Add(FailureWindow+time.Minute))
+	ms.NoError(err)
+	ms.Equal(1, l.Failures)
+	ms.False(l.LockedUntil.Valid)
+
+	ms.NoError(ClearLoginFailures(ms.DB, ScopeAccount, "mark@example.com"))
+
+	count, err := ms.DB.Count(&LoginFailure{})
+	ms.NoError(err)
+	ms.Equal(0, count)
+}
+
+func (ms *ModelSuite) Test_UserSession_FindActive() {
+	u := &User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	s, err := NewUserSession(ms.DB, u, "127.0.0.1", "test", time.Hour)
+	ms.NoError(err)
+
+	found := &UserSession{}
+	ms.NoError(found.FindActive(ms.DB, s.ID.String(), u.ID.String(), time.Now()))
+	ms.Error(found.FindActive(ms.DB, s.ID.String(), u.ID.String(), time.Now().Add(2*time.Hour)))
+
+	ms.NoError(u.RevokeSession(ms.DB, s.ID.String()))
+	ms.Error(found.FindActive(ms.DB, s.ID.String(), u.ID.String(), time.Now()))
+}
@@ -1,22 +1,64 @@
 package models
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/pop/v5"
+	poplog "github.com/gobuffalo/pop/v5/logging"
+	"github.com/navionguy/quotewall/logging"
 )
 
 // DB is a connection to your database to be used
 // throughout your application.
 var DB *pop.Connection
 
+// log is the models package's logger
+var log = logging.For("models")
+
 func init() {
+	// the SQL pop runs comes out at debug, turn it on with LOG_LEVELS=pop=debug
+	pop.SetLogger(popLogger)
+
 	var err error
 	env := envy.Get("GO_ENV", "development")
+	if len(pop.Connections) == 0 {
+		if err = pop.LoadConfigFile(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if c, ok := pop.Connections[env]; ok && IsSQLite(c) {
+		// each request's transaction takes the write lock as it starts, so
+		// it can't find out half way through that someone else wrote first.
+		// The rest wait their turn for the busy timeout.
+		c.Dialect.Details().Options["_txlock"] = "immediate"
+	}
+
 	DB, err = pop.Connect(env)
 	if err != nil {
 		log.Fatal(err)
 	}
-	pop.Debug = env == "development"
+}
+
+var popLog = logging.For("pop")
+
+// popLogger sends what pop logs to the "pop" logger
+func popLogger(lvl poplog.Level, s string, args ...interface{}) {
+	switch lvl {
+	case poplog.SQL:
+		if len(args) > 0 {
+			popLog.WithField("args", fmt.Sprintf("%v", args)).Debug(s)
+		} else {
+			popLog.Debug(s)
+		}
+	case poplog.Debug:
+		popLog.Debugf(s, args...)
+	case poplog.Info:
+		popLog.Infof(s, args...)
+	case poplog.Warn:
+		popLog.Warnf(s, args...)
+	default:
+		popLog.Errorf(s, args...)
+	}
 }
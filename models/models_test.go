@@ -12,6 +12,13 @@ type ModelSuite struct {
 }
 
 func Test_ModelSuite(t *testing.T) {
+	// buffalo test loads the schema into Postgres, SQLite migrates here
+	if IsSQLite(DB) {
+		if err := Migrate(DB); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	model, err := suite.NewModelWithFixtures(packr.New("app:models:test:fixtures", "../fixtures"))
 	if err != nil {
 		t.Fatal(err)
@@ -22,3 +29,13 @@ func Test_ModelSuite(t *testing.T) {
 	}
 	suite.Run(t, as)
 }
+
+// SetupTest empties the tables with TruncateAll, pop's own doesn't work on
+// SQLite
+func (ms *ModelSuite) SetupTest() {
+	db := ms.DB
+	ms.DB = nil
+	ms.Model.SetupTest()
+	ms.DB = db
+	ms.NoError(TruncateAll(ms.DB))
+}
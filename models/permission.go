@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// PermissionAdmin lets a user manage the other user accounts
+const PermissionAdmin = "admin"
+
+// Permission grants a user a named role
+type Permission struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Name      string    `json:"name" db:"name"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+}
+
+// String is not required by pop and may be deleted
+func (p Permission) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// Permissions is not required by pop and may be deleted
+type Permissions []Permission
+
+// String is not required by pop and may be deleted
+func (p Permissions) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (p *Permission) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: p.Name, Name: "Name"},
+		&validators.UUIDIsPresent{Field: p.UserID, Name: "UserID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (p *Permission) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (p *Permission) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// HasPermission checks if the user was granted the named role
+func (u *User) HasPermission(tx *pop.Connection, name string) (bool, error) {
+	return tx.Where("user_id = ? AND name = ?", u.ID, name).Exists(&Permission{})
+}
+
+// PermissionNames lists the roles the user was granted
+func (u *User) PermissionNames(tx *pop.Connection) ([]string, error) {
+	perms := Permissions{}
+
+	if err := tx.Where("user_id = ?", u.ID).Order("name").All(&perms); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, p := range perms {
+		names = append(names, p.Name)
+	}
+
+	return names, nil
+}
+
+// Grant gives the user the named role, if he doesn't have it already
+func (u *User) Grant(tx *pop.Connection, name string) error {
+	has, err := u.HasPermission(tx, name)
+
+	if err != nil || has {
+		return err
+	}
+
+	return tx.Create(&Permission{Name: name, UserID: u.ID})
+}
+
+// Revoke takes the named role away from the user
+func (u *User) Revoke(tx *pop.Connection, name string) error {
+	return tx.RawQuery("DELETE FROM permissions WHERE user_id = ? AND name = ?", u.ID, name).Exec()
+}
+
+// SyncPermissions makes the user's roles out of managed match granted.
+// Roles outside managed are left alone, so ones handed out by hand
+// survive.
+func (u *User) SyncPermissions(tx *pop.Connection, managed, granted []string) error {
+	want := map[string]bool{}
+	for _, g := range granted {
+		want[g] = true
+	}
+
+	for _, name := range managed {
+		var err error
+		if want[name] {
+			err = u.Grant(tx, name)
+		} else {
+			err = u.Revoke(tx, name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/pkg/errors"
+)
+
+// how long things hang around before the purge job clears them out
+const LoginEventRetention = 90 * 24 * time.Hour
+const DeliveryRetention = 30 * 24 * time.Hour
+const JobRunRetention = 30 * 24 * time.Hour
+
+// purgeRule is one table's worth of rows that can go
+type purgeRule struct {
+	table string
+	where string
+	args  func(now time.Time) []interface{}
+}
+
+var purgeRules = []purgeRule{
+	{"user_sessions", "expires_at <= ?", func(now time.Time) []interface{} {
+		return []interface{}{now}
+	}},
+	{"user_tokens", "expires_at <= ?", func(now time.Time) []interface{} {
+		return []interface{}{now}
+	}},
+	{"login_failures", "last_failed_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", func(now time.Time) []interface{} {
+		return []interface{}{now.Add(-FailureWindow), now}
+	}},
+	{"login_events", "created_at <= ?", func(now time.Time) []interface{} {
+		return []interface{}{now.Add(-LoginEventRetention)}
+	}},
+	{"webhook_deliveries", "created_at <= ? AND status <> ?", func(now time.Time) []interface{} {
+		return []interface{}{now.Add(-DeliveryRetention), DeliveryPending}
+	}},
+	{"job_runs", "started_at <= ? AND status <> ?", func(now time.Time) []interface{} {
+		return []interface{}{now.Add(-JobRunRetention), JobRunning}
+	}},
+}
+
+// Purge clears out everything that has expired or gotten too old to
+// matter, returning how many rows went from each table
+func Purge(tx *pop.Connection, now time.Time) (map[string]int, error) {
+	purged := map[string]int{}
+
+	for _, p := range purgeRules {
+		n, err := tx.RawQuery("DELETE FROM "+p.table+" WHERE "+p.where, p.args(now)...).ExecWithCount()
+
+		if err != nil {
+			return purged, errors.Wrap(err, p.table)
+		}
+
+		purged[p.table] = n
+	}
+
+	return purged, nil
+}
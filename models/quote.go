@@ -3,14 +3,34 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/uuid"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
 )
 
+// MaxTextLength is the most characters a phrase or a note can have, set
+// with MAX_TEXT_LENGTH.  The columns are text, the limit is only there
+// so a runaway paste doesn't end up on the wall.
+var MaxTextLength = parseMaxTextLength(envy.Get("MAX_TEXT_LENGTH", "4000"))
+
+// parseMaxTextLength reads MAX_TEXT_LENGTH, anything that isn't a
+// positive number gets the default
+func parseMaxTextLength(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+
+	if err != nil || n < 1 {
+		return 4000
+	}
+
+	return n
+}
+
 // Quote holds what one person said
 type Quote struct {
 	ID        uuid.UUID `json:"-" db:"id"`
@@ -24,12 +44,11 @@ type Quote struct {
 	// Relationships
 	Conversation Conversation `json:"-" belongs_to:"conversation" db:"-"`
 	Author       Author       `belongs_to:"author" db:"-"`
-	Annotation   *Annotation  `belongs_to:"annotation" db:"-"`
+	Annotations  Annotations  `json:"annotations" has_many:"annotations" order_by:"sequence asc" db:"-"`
 
 	// Foreign keys
-	ConversationID uuid.UUID  `json:"-" db:"conversation_id"`
-	AuthorID       uuid.UUID  `json:"author_id" db:"author_id"`
-	AnnotationID   *uuid.UUID `json:"-" db:"annotation_id"`
+	ConversationID uuid.UUID `json:"-" db:"conversation_id"`
+	AuthorID       uuid.UUID `json:"author_id" db:"author_id"`
 }
 
 // String is not required by pop and may be deleted
@@ -57,7 +76,7 @@ func (q *Quote) Validate(tx *pop.Connection) (*validate.Errors, error) {
 		&validators.IntIsGreaterThan{Field: q.Sequence, Name: "sequence", Compared: -1, Message: "sequence must be >= 0"},
 
 		&validators.StringIsPresent{Field: q.Phrase, Name: "Phrase"},
-		&validators.StringLengthInRange{Field: q.Phrase, Name: "Phrase", Min: 1, Max: 255, Message: "length must be <255"},
+		&validators.StringLengthInRange{Field: q.Phrase, Name: "Phrase", Min: 1, Max: MaxTextLength, Message: fmt.Sprintf("length must be 1-%d", MaxTextLength)},
 
 		&validators.FuncValidator{
 			Field:   q.AuthorID.String(),
@@ -82,46 +101,49 @@ func (q *Quote) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
 
-// Create saves a quote pointing at the conversation
-func (q *Quote) Create(db *pop.Connection, id uuid.UUID) (*validate.Errors, error) {
-	fmt.Println("Quote.Create()")
-	verrs, err := q.Annotation.CheckID(db)
-
-	if err != nil || verrs.HasAny() {
-		return verrs, err
-	}
-
-	q.Author.ID = q.AuthorID
-	fmt.Printf("author = %s (%v)\n", q.Author.Name, q.Author.ID)
-	err = q.Author.FindByID()
-
-	if err != nil {
-		return verrs, err
+// claimAnnotations points the quote's annotations at it, in order.  An
+// annotation that doesn't say what kind it is gives context.
+func (q *Quote) claimAnnotations() {
+	for i := range q.Annotations {
+		a := &q.Annotations[i]
+		a.QuoteID = q.ID
+		a.Sequence = i
+
+		if len(a.Kind) == 0 {
+			a.Kind = AnnotationContext
+		}
 	}
+}
 
-	// save the ConversationID into the quote
-	q.ConversationID = id
-
-	// add the quote
-	verrs, err = db.ValidateAndCreate(q)
-
-	return verrs, err
+// Create saves a quote pointing at the conversation
+func (q *Quote) Create(db *pop.Connection, id uuid.UUID) (*validate.Errors, error) {
+	return NewPopRepository(db).Quotes.Create(q, id)
 }
 
 // Update saves a quote pointing at the conversation
 func (q *Quote) Update(db *pop.Connection, id uuid.UUID) (*validate.Errors, error) {
+	return NewPopRepository(db).Quotes.Update(q, id)
+}
 
-	verrs, err := q.Annotation.CheckID(db)
-
-	if err != nil || verrs.HasAny() {
-		return verrs, err
-	}
-
-	// save the ConversationID into the quote
-	q.ConversationID = id
-
-	// add the quote
-	verrs, err = db.ValidateAndUpdate(q)
+// SaidOnThisDay loads the published quotes that were said on the same
+// month and day as the passed day, but in an earlier year.
+func (q *Quotes) SaidOnThisDay(db *pop.Connection, day time.Time) error {
+	return db.Eager("Author").Eager("Annotations").Q().
+		InnerJoin("conversations", "conversations.id = quotes.conversation_id").
+		Where("conversations.publish = TRUE AND quotes.publish = TRUE").
+		Where(AnniversaryClause(db.Dialect.Name(), "quotes.saidon", day, 0)).
+		Order("quotes.saidon DESC").
+		All(q)
+}
 
-	return verrs, err
+// Search loads up to limit published quotes with words somewhere in
+// the phrase, newest first.
+func (q *Quotes) Search(db *pop.Connection, words string, limit int) error {
+	return db.Eager("Author").Q().
+		InnerJoin("conversations", "conversations.id = quotes.conversation_id").
+		Where("conversations.publish = TRUE AND quotes.publish = TRUE").
+		Where("LOWER(quotes.phrase) LIKE LOWER(?)", "%"+words+"%").
+		Order("quotes.saidon DESC").
+		Limit(limit).
+		All(q)
 }
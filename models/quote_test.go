@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -47,17 +48,62 @@ func Test_Quote(t *testing.T) {
 	}
 }
 
-// loadFixtureData loads three of the four tables created in the "test quotes" scenario
+func Test_QuoteLength(t *testing.T) {
+	author := uuid.Must(uuid.NewV4())
+	long := strings.Repeat("Some of our best exchanges are longer.\n\n", 20)
+
+	tests := []struct {
+		test   string
+		phrase string
+		verr   bool
+	}{
+		{test: "Paragraphs", phrase: long, verr: false},
+		{test: "At the Limit", phrase: strings.Repeat("x", MaxTextLength), verr: false},
+		{test: "Too Long", phrase: strings.Repeat("x", MaxTextLength+1), verr: true},
+	}
+
+	for _, tt := range tests {
+		q := Quote{SaidOn: time.Now(), Phrase: tt.phrase, AuthorID: author}
+		verrs, err := q.Validate(nil)
+
+		if err != nil {
+			t.Fatalf("Quote.Validate(%s) got error %s", tt.test, err.Error())
+		}
+
+		if verrs.HasAny() != tt.verr {
+			t.Errorf("Quote.Validate(%s) got %v, wanted errors %t", tt.test, verrs, tt.verr)
+		}
+	}
+}
+
+func Test_ParseMaxTextLength(t *testing.T) {
+	tests := []struct {
+		inp  string
+		want int
+	}{
+		{"10000", 10000},
+		{" 500 ", 500},
+		{"", 4000},
+		{"0", 4000},
+		{"lots", 4000},
+	}
+
+	for _, tt := range tests {
+		if got := parseMaxTextLength(tt.inp); got != tt.want {
+			t.Errorf("parseMaxTextLength(%q) got %d, wanted %d", tt.inp, got, tt.want)
+		}
+	}
+}
+
+// loadFixtureData loads the tables the "test quotes" scenario points at
 //
 // Not much code needed to load the data, but lots of steps to check for any failures to
 // get the data I expect.  Not all the tests use the loaded data.
-func loadFixtureData(ms *ModelSuite) ([]Author, []Annotation, []Conversation) {
+func loadFixtureData(ms *ModelSuite) ([]Author, []Conversation) {
 	ms.LoadFixture("test authors")
-	ms.LoadFixture("test annotations")
 	ms.LoadFixture("test conversations")
 
 	authors := []Author{}
-	annotations := []Annotation{}
 	conversations := []Conversation{}
 
 	err := ms.DB.All(&authors)
@@ -70,16 +116,6 @@ func loadFixtureData(ms *ModelSuite) ([]Author, []Annotation, []Conversation) {
 		ms.FailNow("no authors found", "no test authors")
 	}
 
-	err = ms.DB.All(&annotations)
-
-	if err != nil {
-		ms.FailNow("error getting annotations", err.Error())
-	}
-
-	if len(annotations) == 0 {
-		ms.FailNow("no annotations found", "no test annotations")
-	}
-
 	err = ms.DB.All(&conversations)
 
 	if err != nil {
@@ -90,13 +126,13 @@ func loadFixtureData(ms *ModelSuite) ([]Author, []Annotation, []Conversation) {
 		ms.FailNow("no conversations found", "no test conversations")
 	}
 
-	return authors, annotations, conversations
+	return authors, conversations
 }
 
 // CreateQuote tries to create a simple quote
 func (ms *ModelSuite) Test_CreateQuote() {
 
-	authors, _, conversations := loadFixtureData(ms)
+	authors, conversations := loadFixtureData(ms)
 
 	quote := Quote{
 		SaidOn:   time.Now(),
@@ -117,17 +153,20 @@ func (ms *ModelSuite) Test_CreateQuote() {
 	}
 }
 
-// CreateQuoteWithAnnnotation adds a known annotation to the quote
-func (ms *ModelSuite) Test_CreateQuoteWithAnnotation() {
-	authors, annotations, conversations := loadFixtureData(ms)
+// CreateQuoteWithAnnotations adds a context note and an editor's aside
+func (ms *ModelSuite) Test_CreateQuoteWithAnnotations() {
+	authors, conversations := loadFixtureData(ms)
 
 	quote := Quote{
-		SaidOn:     time.Now(),
-		Sequence:   0,
-		Phrase:     "A test quote.",
-		Publish:    true,
-		Annotation: &annotations[0],
-		AuthorID:   authors[0].ID,
+		SaidOn:   time.Now(),
+		Sequence: 0,
+		Phrase:   "A test quote.",
+		Publish:  true,
+		AuthorID: authors[0].ID,
+		Annotations: Annotations{
+			{Note: "During the all hands"},
+			{Kind: AnnotationAside, Note: "He was right.", AuthorID: &authors[1].ID},
+		},
 	}
 
 	verrs, err := quote.Create(ms.DB, conversations[0].ID)
@@ -139,74 +178,63 @@ func (ms *ModelSuite) Test_CreateQuoteWithAnnotation() {
 	if verrs.HasAny() {
 		ms.Fail("CreateQuote validation fail", verrs.String())
 	}
-}
-
-func (ms *ModelSuite) Test_CreateQuoteWithNewAnnotation() {
-	authors, _, conversations := loadFixtureData(ms)
-
-	annotation := &Annotation{Note: "blahblahblah"}
-
-	quote := Quote{
-		SaidOn:     time.Now(),
-		Sequence:   0,
-		Phrase:     "A test quote.",
-		Publish:    true,
-		Annotation: annotation,
-		AuthorID:   authors[0].ID,
-	}
-
-	verrs, err := quote.Create(ms.DB, conversations[0].ID)
-
-	if err != nil {
-		fmt.Printf("err = %s\n", err.Error())
-		ms.Fail("CreateQuote failed", err.Error())
-	}
 
-	if verrs.HasAny() {
-		ms.Fail("CreateQuote validation fail", verrs.String())
-	}
+	annotations, err := NewPopRepository(ms.DB).Annotations.ForQuote(quote.ID)
+	ms.NoError(err)
+	ms.Len(annotations, 2)
+	ms.Equal(AnnotationContext, annotations[0].Kind)
+	ms.Equal("He was right.", annotations[1].Note)
+	ms.Equal(authors[1].Name, annotations[1].Author.Name)
 }
 
 func (ms *ModelSuite) Test_CreateQuoteWithInvalidAnnotation() {
-	authors, annotations, conversations := loadFixtureData(ms)
-
-	annotations[0].Note = ""
-	annotations[0].ID = uuid.Nil
+	authors, conversations := loadFixtureData(ms)
 
-	quote := Quote{
-		SaidOn:     time.Now(),
-		Sequence:   0,
-		Phrase:     "A test quote.",
-		Publish:    true,
-		Annotation: &annotations[0],
-		AuthorID:   authors[0].ID,
+	tests := []struct {
+		test       string
+		annotation Annotation
+	}{
+		{test: "No Note", annotation: Annotation{Kind: AnnotationContext}},
+		{test: "Unknown Kind", annotation: Annotation{Kind: "gossip", Note: "Heard it from Bob."}},
 	}
 
-	verrs, err := quote.Create(ms.DB, conversations[0].ID)
+	for _, tt := range tests {
+		quote := Quote{
+			SaidOn:      time.Now(),
+			Sequence:    0,
+			Phrase:      "A test quote.",
+			Publish:     true,
+			Annotations: Annotations{tt.annotation},
+			AuthorID:    authors[0].ID,
+		}
 
-	if err != nil {
-		ms.Fail("CreateQuote failed", err.Error())
-	}
+		verrs, err := quote.Create(ms.DB, conversations[0].ID)
 
-	if !verrs.HasAny() {
-		ms.Fail("CreateQuote failed", "validation failed to catch empty note")
+		if err != nil {
+			ms.Fail("CreateQuote failed", err.Error())
+		}
+
+		ms.Truef(verrs.HasAny(), "CreateQuote(%s) validation didn't catch the annotation", tt.test)
+		ms.Equalf(uuid.Nil, quote.ID, "CreateQuote(%s) saved the quote anyway", tt.test)
 	}
 }
 
-func (ms *ModelSuite) Test_UpdateQuoteWithNewAnnotation() {
-	authors, _, conversations := loadFixtureData(ms)
+func (ms *ModelSuite) Test_UpdateQuoteReplacesAnnotations() {
+	authors, conversations := loadFixtureData(ms)
 	ms.LoadFixture(("test quotes"))
 
-	quotes := []Quote{}
+	repo := NewPopRepository(ms.DB)
 
-	err := ms.DB.All(&quotes)
+	quote, err := repo.Quotes.Find(uuid.FromStringOrNil(annotatedQuote))
 
 	if err != nil {
-		ms.Fail("Update fetch all quotes failed", err.Error())
+		ms.FailNow("Update fetch quote failed", err.Error())
 	}
 
-	quote := &quotes[0]
+	ms.Len(quote.Annotations, 2)
+
 	quote.AuthorID = authors[0].ID
+	quote.Annotations = Annotations{{Kind: AnnotationExplanation, Note: "The name changed twice that year."}}
 
 	verrs, err := quote.Update(ms.DB, conversations[0].ID)
 
@@ -218,4 +246,9 @@ func (ms *ModelSuite) Test_UpdateQuoteWithNewAnnotation() {
 	if verrs.HasAny() {
 		ms.Fail("UpdateQuote validation fail", verrs.String())
 	}
+
+	annotations, err := repo.Annotations.ForQuote(quote.ID)
+	ms.NoError(err)
+	ms.Len(annotations, 1)
+	ms.Equal(AnnotationExplanation, annotations[0].Kind)
 }
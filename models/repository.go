@@ -0,0 +1,92 @@
+package models
+
+import (
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is what a repository returns when there's no such record
+var ErrNotFound = errors.New("record not found")
+
+// ConversationRepository stores conversations along with their quotes
+type ConversationRepository interface {
+	// Find loads the conversation with its quotes, their authors and
+	// annotations, in order
+	Find(id uuid.UUID) (*Conversation, error)
+	// Create saves the conversation and all its quotes, or none of it
+	Create(c *Conversation) (*validate.Errors, error)
+	// Update saves the conversation, creating any quotes that are new
+	Update(c *Conversation) (*validate.Errors, error)
+	// Destroy removes the conversation and its quotes
+	Destroy(c *Conversation) error
+}
+
+// QuoteRepository stores the quotes in a conversation
+type QuoteRepository interface {
+	Find(id uuid.UUID) (*Quote, error)
+	// ForConversation loads a conversation's quotes in order
+	ForConversation(conversationID uuid.UUID) (Quotes, error)
+	// Create saves the quote into the conversation along with its
+	// annotations
+	Create(q *Quote, conversationID uuid.UUID) (*validate.Errors, error)
+	// Update saves the quote, its annotations replace the ones it had
+	Update(q *Quote, conversationID uuid.UUID) (*validate.Errors, error)
+	Destroy(q *Quote) error
+}
+
+// AuthorRepository stores the people who said things
+type AuthorRepository interface {
+	Find(id uuid.UUID) (*Author, error)
+	// FindByName finds the author with exactly this name, ignoring case
+	FindByName(name string) (*Author, error)
+	// All loads every author by name
+	All() (Authors, error)
+	Create(a *Author) (*validate.Errors, error)
+	Update(a *Author) (*validate.Errors, error)
+}
+
+// AnnotationRepository stores the notes attached to quotes
+type AnnotationRepository interface {
+	Find(id uuid.UUID) (*Annotation, error)
+	// ForQuote loads a quote's annotations in order, with their authors
+	ForQuote(quoteID uuid.UUID) (Annotations, error)
+	Create(a *Annotation) (*validate.Errors, error)
+}
+
+// Repository is everywhere the archive is kept.  Actions get one built on
+// the request's transaction, tests can use the in-memory one.
+type Repository struct {
+	Conversations ConversationRepository
+	Quotes        QuoteRepository
+	Authors       AuthorRepository
+	Annotations   AnnotationRepository
+}
+
+// validationFailed rolls back a save that didn't pass validation, the
+// errors themselves go back to the caller
+var validationFailed = errors.New("validation failed")
+
+// atomically runs fn so either everything it saves sticks or none of it
+// does.  Inside a transaction already, like the one around each request,
+// pop would commit it on the way out, so a savepoint stands in.
+func atomically(db *pop.Connection, fn func(tx *pop.Connection) error) error {
+	if db.TX == nil {
+		return db.Transaction(fn)
+	}
+
+	if err := db.RawQuery("SAVEPOINT repository").Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := fn(db); err != nil {
+		if rerr := db.RawQuery("ROLLBACK TO SAVEPOINT repository").Exec(); rerr != nil {
+			return errors.Wrap(rerr, err.Error())
+		}
+
+		return err
+	}
+
+	return errors.WithStack(db.RawQuery("RELEASE SAVEPOINT repository").Exec())
+}
@@ -0,0 +1,538 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+)
+
+// NewMemoryRepository keeps the archive in memory, for tests that don't
+// need a database.  It runs the same validations pop would.
+func NewMemoryRepository() *Repository {
+	m := &memoryStore{
+		conversations: map[uuid.UUID]Conversation{},
+		quotes:        map[uuid.UUID]Quote{},
+		authors:       map[uuid.UUID]Author{},
+		annotations:   map[uuid.UUID]Annotation{},
+	}
+
+	return &Repository{
+		Conversations: memoryConversations{m},
+		Quotes:        memoryQuotes{m},
+		Authors:       memoryAuthors{m},
+		Annotations:   memoryAnnotations{m},
+	}
+}
+
+// memoryStore holds copies of the records, never what callers passed in
+type memoryStore struct {
+	sync.Mutex
+	conversations map[uuid.UUID]Conversation
+	quotes        map[uuid.UUID]Quote
+	authors       map[uuid.UUID]Author
+	annotations   map[uuid.UUID]Annotation
+}
+
+// validator is what every model has for pop's ValidateAnd* methods
+type validator interface {
+	Validate(*pop.Connection) (*validate.Errors, error)
+}
+
+// check runs the model's validations, none of them need the connection
+func check(v validator) (*validate.Errors, error) {
+	return v.Validate(nil)
+}
+
+// stamp fills in what pop would on a create
+func stamp(id *uuid.UUID, created, updated *time.Time) error {
+	if *id == uuid.Nil {
+		u, err := uuid.NewV4()
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		*id = u
+	}
+
+	now := time.Now()
+
+	if created.IsZero() {
+		*created = now
+	}
+
+	*updated = now
+
+	return nil
+}
+
+// nameTaken says if another author already has a's name, m must be locked
+func (m *memoryStore) nameTaken(a *Author) bool {
+	for id, saved := range m.authors {
+		if id != a.ID && strings.EqualFold(saved.Name, a.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authorKnown says if an annotation's optional author is saved, m must be
+// locked
+func (m *memoryStore) authorKnown(id *uuid.UUID) bool {
+	if id == nil {
+		return true
+	}
+
+	_, ok := m.authors[*id]
+
+	return ok
+}
+
+// quotesOf gathers a conversation's quotes in order, with their authors
+// and annotations, m must be locked
+func (m *memoryStore) quotesOf(conversationID uuid.UUID) Quotes {
+	quotes := Quotes{}
+
+	for _, q := range m.quotes {
+		if q.ConversationID == conversationID {
+			quotes = append(quotes, m.filled(q))
+		}
+	}
+
+	sort.Slice(quotes, func(i, j int) bool {
+		return quotes[i].Sequence < quotes[j].Sequence
+	})
+
+	return quotes
+}
+
+// filled loads a quote's author and annotations, m must be locked
+func (m *memoryStore) filled(q Quote) Quote {
+	q.Author = m.authors[q.AuthorID]
+	q.Annotations = m.annotationsOf(q.ID)
+
+	return q
+}
+
+// annotationsOf gathers a quote's annotations in order, with their
+// authors, m must be locked
+func (m *memoryStore) annotationsOf(quoteID uuid.UUID) Annotations {
+	annotations := Annotations{}
+
+	for _, a := range m.annotations {
+		if a.QuoteID != quoteID {
+			continue
+		}
+
+		if a.AuthorID != nil {
+			if author, ok := m.authors[*a.AuthorID]; ok {
+				a.Author = &author
+			}
+		}
+
+		annotations = append(annotations, a)
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		return annotations[i].Sequence < annotations[j].Sequence
+	})
+
+	return annotations
+}
+
+// unannotate drops all of a quote's annotations, m must be locked
+func (m *memoryStore) unannotate(quoteID uuid.UUID) {
+	for id, a := range m.annotations {
+		if a.QuoteID == quoteID {
+			delete(m.annotations, id)
+		}
+	}
+}
+
+type memoryConversations struct {
+	m *memoryStore
+}
+
+func (r memoryConversations) Find(id uuid.UUID) (*Conversation, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	c, ok := r.m.conversations[id]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c.Quotes = r.m.quotesOf(id)
+
+	return &c, nil
+}
+
+func (r memoryConversations) Create(c *Conversation) (*validate.Errors, error) {
+	return r.save(c, false)
+}
+
+func (r memoryConversations) Update(c *Conversation) (*validate.Errors, error) {
+	return r.save(c, true)
+}
+
+// save validates everything before storing anything, so a bad quote
+// leaves the store as it was, like the rolled back transaction would
+func (r memoryConversations) save(c *Conversation, update bool) (*validate.Errors, error) {
+	verrs, err := check(c)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	for i := range c.Quotes {
+		c.Quotes[i].Sequence = i
+		c.Quotes[i].claimAnnotations()
+
+		for j := range c.Quotes[i].Annotations {
+			if verrs, err = check(&c.Quotes[i].Annotations[j]); err != nil || verrs.HasAny() {
+				return verrs, err
+			}
+		}
+
+		if verrs, err = check(&c.Quotes[i]); err != nil || verrs.HasAny() {
+			return verrs, err
+		}
+	}
+
+	r.m.Lock()
+	if _, ok := r.m.conversations[c.ID]; update && !ok {
+		r.m.Unlock()
+		return verrs, ErrNotFound
+	}
+
+	for i := range c.Quotes {
+		if _, ok := r.m.authors[c.Quotes[i].AuthorID]; !ok {
+			r.m.Unlock()
+			return verrs, ErrNotFound
+		}
+	}
+
+	if err := stamp(&c.ID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		r.m.Unlock()
+		return verrs, err
+	}
+
+	stored := *c
+	stored.Quotes = nil
+	r.m.conversations[c.ID] = stored
+	r.m.Unlock()
+
+	quotes := memoryQuotes{r.m}
+
+	for i := range c.Quotes {
+		if verrs, err = quotes.put(&c.Quotes[i], c.ID); err != nil {
+			return verrs, err
+		}
+	}
+
+	return verrs, nil
+}
+
+func (r memoryConversations) Destroy(c *Conversation) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if _, ok := r.m.conversations[c.ID]; !ok {
+		return ErrNotFound
+	}
+
+	for id, q := range r.m.quotes {
+		if q.ConversationID == c.ID {
+			r.m.unannotate(id)
+			delete(r.m.quotes, id)
+		}
+	}
+
+	delete(r.m.conversations, c.ID)
+
+	return nil
+}
+
+type memoryQuotes struct {
+	m *memoryStore
+}
+
+func (r memoryQuotes) Find(id uuid.UUID) (*Quote, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	q, ok := r.m.quotes[id]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	q = r.m.filled(q)
+
+	return &q, nil
+}
+
+func (r memoryQuotes) ForConversation(conversationID uuid.UUID) (Quotes, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.m.quotesOf(conversationID), nil
+}
+
+func (r memoryQuotes) Create(q *Quote, conversationID uuid.UUID) (*validate.Errors, error) {
+	return r.put(q, conversationID)
+}
+
+func (r memoryQuotes) Update(q *Quote, conversationID uuid.UUID) (*validate.Errors, error) {
+	r.m.Lock()
+	_, ok := r.m.quotes[q.ID]
+	r.m.Unlock()
+
+	if !ok {
+		return validate.NewErrors(), ErrNotFound
+	}
+
+	return r.put(q, conversationID)
+}
+
+// put saves the quote, then swaps its annotations for the ones it has now
+func (r memoryQuotes) put(q *Quote, conversationID uuid.UUID) (*validate.Errors, error) {
+	q.ConversationID = conversationID
+	q.claimAnnotations()
+
+	for i := range q.Annotations {
+		if verrs, err := check(&q.Annotations[i]); err != nil || verrs.HasAny() {
+			return verrs, err
+		}
+	}
+
+	verrs, err := check(q)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	author, ok := r.m.authors[q.AuthorID]
+
+	if !ok {
+		return verrs, ErrNotFound
+	}
+
+	for _, a := range q.Annotations {
+		if !r.m.authorKnown(a.AuthorID) {
+			return verrs, ErrNotFound
+		}
+	}
+
+	q.Author = author
+
+	if err := stamp(&q.ID, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		return verrs, err
+	}
+
+	stored := *q
+	stored.Author = Author{}
+	stored.Annotations = nil
+	stored.Conversation = Conversation{}
+	r.m.quotes[q.ID] = stored
+
+	r.m.unannotate(q.ID)
+	q.claimAnnotations()
+
+	for i := range q.Annotations {
+		a := &q.Annotations[i]
+		a.ID = uuid.Nil
+
+		if err := stamp(&a.ID, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return verrs, err
+		}
+
+		saved := *a
+		saved.Author = nil
+		r.m.annotations[a.ID] = saved
+	}
+
+	return verrs, nil
+}
+
+func (r memoryQuotes) Destroy(q *Quote) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if _, ok := r.m.quotes[q.ID]; !ok {
+		return ErrNotFound
+	}
+
+	r.m.unannotate(q.ID)
+	delete(r.m.quotes, q.ID)
+
+	return nil
+}
+
+type memoryAuthors struct {
+	m *memoryStore
+}
+
+func (r memoryAuthors) Find(id uuid.UUID) (*Author, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	a, ok := r.m.authors[id]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &a, nil
+}
+
+func (r memoryAuthors) FindByName(name string) (*Author, error) {
+	name = strings.TrimSpace(name)
+
+	if len(name) == 0 {
+		return nil, errors.New("author name can't be blank")
+	}
+
+	all, _ := r.All()
+
+	for _, a := range all {
+		if strings.EqualFold(a.Name, name) {
+			return &a, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r memoryAuthors) All() (Authors, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	authors := Authors{}
+
+	for _, a := range r.m.authors {
+		authors = append(authors, a)
+	}
+
+	sort.Slice(authors, func(i, j int) bool {
+		return authors[i].Name < authors[j].Name
+	})
+
+	return authors, nil
+}
+
+func (r memoryAuthors) Create(a *Author) (*validate.Errors, error) {
+	verrs, err := check(a)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.m.nameTaken(a) {
+		verrs.Add("name", fmt.Sprintf("%s is already taken", a.Name))
+		return verrs, nil
+	}
+
+	if err := stamp(&a.ID, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return verrs, err
+	}
+
+	r.m.authors[a.ID] = *a
+
+	return verrs, nil
+}
+
+func (r memoryAuthors) Update(a *Author) (*validate.Errors, error) {
+	verrs, err := check(a)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if _, ok := r.m.authors[a.ID]; !ok {
+		return verrs, ErrNotFound
+	}
+
+	if r.m.nameTaken(a) {
+		verrs.Add("name", fmt.Sprintf("%s is already taken", a.Name))
+		return verrs, nil
+	}
+
+	if err := stamp(&a.ID, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return verrs, err
+	}
+
+	r.m.authors[a.ID] = *a
+
+	return verrs, nil
+}
+
+type memoryAnnotations struct {
+	m *memoryStore
+}
+
+func (r memoryAnnotations) Find(id uuid.UUID) (*Annotation, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	a, ok := r.m.annotations[id]
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &a, nil
+}
+
+func (r memoryAnnotations) ForQuote(quoteID uuid.UUID) (Annotations, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.m.annotationsOf(quoteID), nil
+}
+
+func (r memoryAnnotations) Create(a *Annotation) (*validate.Errors, error) {
+	verrs, err := check(a)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	// the foreign keys are checked like the database would
+	if _, ok := r.m.quotes[a.QuoteID]; !ok {
+		return verrs, ErrNotFound
+	}
+
+	if !r.m.authorKnown(a.AuthorID) {
+		return verrs, ErrNotFound
+	}
+
+	if err := stamp(&a.ID, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return verrs, err
+	}
+
+	r.m.annotations[a.ID] = *a
+
+	return verrs, nil
+}
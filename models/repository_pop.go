@@ -0,0 +1,302 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/pkg/errors"
+)
+
+// NewPopRepository keeps the archive in the database through db, which
+// for actions is the request's transaction
+func NewPopRepository(db *pop.Connection) *Repository {
+	return &Repository{
+		Conversations: popConversations{db},
+		Quotes:        popQuotes{db},
+		Authors:       popAuthors{db},
+		Annotations:   popAnnotations{db},
+	}
+}
+
+// notFound turns pop's no rows into ErrNotFound
+func notFound(err error) error {
+	if errors.Cause(err) == sql.ErrNoRows {
+		return ErrNotFound
+	}
+
+	return errors.WithStack(err)
+}
+
+type popConversations struct {
+	db *pop.Connection
+}
+
+func (r popConversations) Find(id uuid.UUID) (*Conversation, error) {
+	c := &Conversation{}
+
+	if err := r.db.Eager("Quotes.Conversation").Eager("Quotes").Eager("Quotes.Author").Eager("Quotes.Annotations").Eager("Quotes.Annotations.Author").Find(c, id); err != nil {
+		return nil, notFound(err)
+	}
+
+	return c, nil
+}
+
+func (r popConversations) Create(c *Conversation) (*validate.Errors, error) {
+	return r.save(c, false)
+}
+
+func (r popConversations) Update(c *Conversation) (*validate.Errors, error) {
+	return r.save(c, true)
+}
+
+// save writes the conversation and its quotes as one, the first thing
+// that fails validation undoes the rest
+func (r popConversations) save(c *Conversation, update bool) (*validate.Errors, error) {
+	var verrs *validate.Errors
+
+	err := atomically(r.db, func(tx *pop.Connection) error {
+		var err error
+
+		if update {
+			verrs, err = tx.ValidateAndUpdate(c)
+		} else {
+			verrs, err = tx.ValidateAndCreate(c)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if verrs.HasAny() {
+			return validationFailed
+		}
+
+		quotes := popQuotes{tx}
+
+		for i := range c.Quotes {
+			q := &c.Quotes[i]
+			q.Sequence = i
+
+			if q.ID == uuid.Nil {
+				verrs, err = quotes.Create(q, c.ID)
+			} else {
+				verrs, err = quotes.Update(q, c.ID)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if verrs.HasAny() {
+				return validationFailed
+			}
+		}
+
+		return nil
+	})
+
+	if err == validationFailed {
+		return verrs, nil
+	}
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return verrs, nil
+}
+
+func (r popConversations) Destroy(c *Conversation) error {
+	return atomically(r.db, func(tx *pop.Connection) error {
+		if err := tx.RawQuery("DELETE FROM quotes WHERE conversation_id = ?", c.ID).Exec(); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return errors.WithStack(tx.Destroy(c))
+	})
+}
+
+type popQuotes struct {
+	db *pop.Connection
+}
+
+func (r popQuotes) Find(id uuid.UUID) (*Quote, error) {
+	q := &Quote{}
+
+	if err := r.db.Eager("Author").Eager("Annotations").Eager("Annotations.Author").Find(q, id); err != nil {
+		return nil, notFound(err)
+	}
+
+	return q, nil
+}
+
+func (r popQuotes) ForConversation(conversationID uuid.UUID) (Quotes, error) {
+	quotes := Quotes{}
+
+	err := r.db.Eager("Author").Eager("Annotations").Eager("Annotations.Author").Where("conversation_id = ?", conversationID).Order("sequence").All(&quotes)
+
+	return quotes, errors.WithStack(err)
+}
+
+func (r popQuotes) Create(q *Quote, conversationID uuid.UUID) (*validate.Errors, error) {
+	verrs, err := r.checkAnnotations(q)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	// a missing author is left for validation to report
+	if q.AuthorID != uuid.Nil {
+		author, err := popAuthors{r.db}.Find(q.AuthorID)
+
+		if err != nil {
+			return verrs, err
+		}
+
+		q.Author = *author
+	}
+
+	q.ConversationID = conversationID
+
+	verrs, err = r.db.ValidateAndCreate(q)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	return r.annotate(q)
+}
+
+func (r popQuotes) Update(q *Quote, conversationID uuid.UUID) (*validate.Errors, error) {
+	verrs, err := r.checkAnnotations(q)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	q.ConversationID = conversationID
+
+	verrs, err = r.db.ValidateAndUpdate(q)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	return r.annotate(q)
+}
+
+// checkAnnotations validates the quote's annotations before anything
+// gets saved
+func (r popQuotes) checkAnnotations(q *Quote) (*validate.Errors, error) {
+	q.claimAnnotations()
+
+	for i := range q.Annotations {
+		verrs, err := q.Annotations[i].Validate(r.db)
+
+		if err != nil || verrs.HasAny() {
+			return verrs, err
+		}
+	}
+
+	return validate.NewErrors(), nil
+}
+
+// annotate swaps whatever annotations the quote had for the ones it has
+// now
+func (r popQuotes) annotate(q *Quote) (*validate.Errors, error) {
+	if err := r.db.RawQuery("DELETE FROM annotations WHERE quote_id = ?", q.ID).Exec(); err != nil {
+		return validate.NewErrors(), errors.WithStack(err)
+	}
+
+	q.claimAnnotations()
+	annotations := popAnnotations{r.db}
+
+	for i := range q.Annotations {
+		q.Annotations[i].ID = uuid.Nil
+
+		verrs, err := annotations.Create(&q.Annotations[i])
+
+		if err != nil || verrs.HasAny() {
+			return verrs, err
+		}
+	}
+
+	return validate.NewErrors(), nil
+}
+
+func (r popQuotes) Destroy(q *Quote) error {
+	return errors.WithStack(r.db.Destroy(q))
+}
+
+type popAuthors struct {
+	db *pop.Connection
+}
+
+func (r popAuthors) Find(id uuid.UUID) (*Author, error) {
+	a := &Author{}
+
+	if err := r.db.Find(a, id); err != nil {
+		return nil, notFound(err)
+	}
+
+	return a, nil
+}
+
+func (r popAuthors) FindByName(name string) (*Author, error) {
+	name = strings.TrimSpace(name)
+
+	if len(name) == 0 {
+		return nil, errors.New("author name can't be blank")
+	}
+
+	a := &Author{}
+
+	if err := r.db.Where("LOWER(name) = LOWER(?)", name).First(a); err != nil {
+		return nil, notFound(err)
+	}
+
+	return a, nil
+}
+
+func (r popAuthors) All() (Authors, error) {
+	authors := Authors{}
+
+	return authors, errors.WithStack(r.db.Order("name").All(&authors))
+}
+
+func (r popAuthors) Create(a *Author) (*validate.Errors, error) {
+	return r.db.ValidateAndCreate(a)
+}
+
+func (r popAuthors) Update(a *Author) (*validate.Errors, error) {
+	return r.db.ValidateAndUpdate(a)
+}
+
+type popAnnotations struct {
+	db *pop.Connection
+}
+
+func (r popAnnotations) Find(id uuid.UUID) (*Annotation, error) {
+	a := &Annotation{}
+
+	if err := r.db.Find(a, id); err != nil {
+		return nil, notFound(err)
+	}
+
+	return a, nil
+}
+
+func (r popAnnotations) ForQuote(quoteID uuid.UUID) (Annotations, error) {
+	annotations := Annotations{}
+
+	err := r.db.Eager("Author").Where("quote_id = ?", quoteID).Order("sequence").All(&annotations)
+
+	return annotations, errors.WithStack(err)
+}
+
+func (r popAnnotations) Create(a *Annotation) (*validate.Errors, error) {
+	return r.db.ValidateAndCreate(a)
+}
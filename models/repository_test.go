@@ -0,0 +1,212 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// checkRepository puts a repository through what every implementation
+// has to do the same way
+func checkRepository(r *require.Assertions, repo *Repository) {
+	bob := &Author{Name: "Bob J. McGowan"}
+	verrs, err := repo.Authors.Create(bob)
+	r.NoError(err)
+	r.False(verrs.HasAny(), verrs.String())
+	r.NotEqual(uuid.Nil, bob.ID)
+
+	verrs, err = repo.Authors.Create(&Author{})
+	r.NoError(err)
+	r.True(verrs.HasAny(), "blank author name got through")
+
+	found, err := repo.Authors.FindByName(" bob j. mcgowan")
+	r.NoError(err)
+	r.Equal(bob.ID, found.ID)
+
+	_, err = repo.Authors.FindByName("Bob McGowan")
+	r.Equal(ErrNotFound, err)
+
+	_, err = repo.Authors.FindByName("Nobody Atall")
+	r.Equal(ErrNotFound, err)
+
+	verrs, err = repo.Authors.Create(&Author{Name: "BOB J. MCGOWAN"})
+	r.NoError(err)
+	r.True(verrs.HasAny(), "second Bob got through")
+
+	obrien := &Author{Name: "Pat O'Brien"}
+	_, err = repo.Authors.Create(obrien)
+	r.NoError(err)
+
+	found, err = repo.Authors.FindByName("pat o'brien")
+	r.NoError(err)
+	r.Equal(obrien.ID, found.ID)
+
+	_, err = repo.Authors.FindByName(" ")
+	r.Error(err)
+
+	alice := &Author{Name: "Alice Archer"}
+	_, err = repo.Authors.Create(alice)
+	r.NoError(err)
+
+	all, err := repo.Authors.All()
+	r.NoError(err)
+	r.True(len(all) >= 2)
+	for i := 1; i < len(all); i++ {
+		r.True(all[i-1].Name <= all[i].Name, "authors out of order")
+	}
+
+	alice.Name = "Alice B. Archer"
+	verrs, err = repo.Authors.Update(alice)
+	r.NoError(err)
+	r.False(verrs.HasAny(), verrs.String())
+
+	got, err := repo.Authors.Find(alice.ID)
+	r.NoError(err)
+	r.Equal("Alice B. Archer", got.Name)
+
+	_, err = repo.Authors.Find(uuid.Must(uuid.NewV4()))
+	r.Equal(ErrNotFound, err)
+
+	said := time.Now().Add(-time.Hour)
+
+	conv := &Conversation{OccurredOn: said, Publish: true, Quotes: Quotes{
+		{SaidOn: said, Phrase: "first", Publish: true, AuthorID: bob.ID},
+		{SaidOn: said, Phrase: "second", Publish: true, AuthorID: alice.ID, Annotations: Annotations{
+			{Note: "after a long pause"},
+			{Kind: AnnotationAside, Note: "she was joking", AuthorID: &bob.ID},
+		}},
+	}, Meeting: "Standup", Location: "Room 4", Topic: "Deadlines"}
+
+	verrs, err = repo.Conversations.Create(conv)
+	r.NoError(err)
+	r.False(verrs.HasAny(), verrs.String())
+	r.NotEqual(uuid.Nil, conv.ID)
+
+	loaded, err := repo.Conversations.Find(conv.ID)
+	r.NoError(err)
+	r.Len(loaded.Quotes, 2)
+	r.Equal("first", loaded.Quotes[0].Phrase)
+	r.Equal(bob.Name, loaded.Quotes[0].Author.Name)
+	r.Equal("Standup · Room 4 · Deadlines", loaded.Context())
+	r.Len(loaded.Quotes[0].Annotations, 0)
+	r.Equal(1, loaded.Quotes[1].Sequence)
+	r.Len(loaded.Quotes[1].Annotations, 2)
+
+	context := loaded.Quotes[1].Annotations[0]
+	r.Equal("after a long pause", context.Note)
+	r.Equal(AnnotationContext, context.Kind)
+	r.Nil(context.Author)
+
+	aside := loaded.Quotes[1].Annotations[1]
+	r.Equal(AnnotationAside, aside.Kind)
+	r.NotNil(aside.Author)
+	r.Equal(bob.Name, aside.Author.Name)
+
+	note, err := repo.Annotations.Find(context.ID)
+	r.NoError(err)
+	r.Equal(loaded.Quotes[1].ID, note.QuoteID)
+
+	// the same words can annotate more than one quote
+	verrs, err = repo.Annotations.Create(&Annotation{QuoteID: loaded.Quotes[0].ID, Kind: AnnotationContext, Note: "After A Long Pause"})
+	r.NoError(err)
+	r.False(verrs.HasAny(), verrs.String())
+
+	verrs, err = repo.Annotations.Create(&Annotation{QuoteID: loaded.Quotes[0].ID, Kind: "gossip", Note: "heard it"})
+	r.NoError(err)
+	r.True(verrs.HasAny(), "unknown kind got through")
+
+	notes, err := repo.Annotations.ForQuote(loaded.Quotes[0].ID)
+	r.NoError(err)
+	r.Len(notes, 1)
+
+	_, err = repo.Annotations.Find(uuid.Must(uuid.NewV4()))
+	r.Equal(ErrNotFound, err)
+
+	// one bad quote and none of the conversation gets saved
+	bad := &Conversation{OccurredOn: said, Quotes: Quotes{
+		{SaidOn: said, Phrase: "fine", AuthorID: bob.ID},
+		{SaidOn: said, Phrase: "", AuthorID: bob.ID},
+	}}
+
+	verrs, err = repo.Conversations.Create(bad)
+	r.NoError(err)
+	r.True(verrs.HasAny(), "blank phrase got through")
+
+	if bad.ID != uuid.Nil {
+		_, err = repo.Conversations.Find(bad.ID)
+		r.Equal(ErrNotFound, err)
+	}
+
+	// an update replaces the annotations a quote had
+	loaded.Quotes = append(loaded.Quotes, Quote{SaidOn: said, Phrase: "third", AuthorID: bob.ID, Annotations: Annotations{{Kind: AnnotationExplanation, Note: "AFTER a long pause"}}})
+	loaded.Quotes[0].Phrase = "first, again"
+	loaded.Quotes[1].Annotations = loaded.Quotes[1].Annotations[1:]
+	verrs, err = repo.Conversations.Update(loaded)
+	r.NoError(err)
+	r.False(verrs.HasAny(), verrs.String())
+
+	quotes, err := repo.Quotes.ForConversation(conv.ID)
+	r.NoError(err)
+	r.Len(quotes, 3)
+	r.Equal("first, again", quotes[0].Phrase)
+	r.Equal("third", quotes[2].Phrase)
+	r.Len(quotes[1].Annotations, 1)
+	r.Equal("she was joking", quotes[1].Annotations[0].Note)
+	r.Len(quotes[2].Annotations, 1)
+	r.Equal(AnnotationExplanation, quotes[2].Annotations[0].Kind)
+
+	q, err := repo.Quotes.Find(quotes[2].ID)
+	r.NoError(err)
+	r.Equal(bob.ID, q.Author.ID)
+
+	r.NoError(repo.Quotes.Destroy(q))
+	_, err = repo.Quotes.Find(q.ID)
+	r.Equal(ErrNotFound, err)
+
+	notes, err = repo.Annotations.ForQuote(q.ID)
+	r.NoError(err)
+	r.Len(notes, 0)
+
+	r.NoError(repo.Conversations.Destroy(loaded))
+	_, err = repo.Conversations.Find(conv.ID)
+	r.Equal(ErrNotFound, err)
+
+	quotes, err = repo.Quotes.ForConversation(conv.ID)
+	r.NoError(err)
+	r.Len(quotes, 0)
+
+	notes, err = repo.Annotations.ForQuote(aside.QuoteID)
+	r.NoError(err)
+	r.Len(notes, 0)
+}
+
+func Test_MemoryRepository(t *testing.T) {
+	checkRepository(require.New(t), NewMemoryRepository())
+}
+
+func (ms *ModelSuite) Test_PopRepository() {
+	checkRepository(ms.Require(), NewPopRepository(ms.DB))
+}
+
+func (ms *ModelSuite) Test_PopRepository_InTransaction() {
+	// a failed save inside the request's transaction mustn't end it
+	ms.NoError(ms.DB.Transaction(func(tx *pop.Connection) error {
+		repo := NewPopRepository(tx)
+
+		verrs, err := repo.Authors.Create(&Author{Name: "Kept Around"})
+		ms.NoError(err)
+		ms.False(verrs.HasAny())
+
+		verrs, err = repo.Conversations.Create(&Conversation{OccurredOn: time.Now(), Quotes: Quotes{{SaidOn: time.Now()}}})
+		ms.NoError(err)
+		ms.True(verrs.HasAny())
+
+		_, err = repo.Authors.FindByName("Kept Around")
+		ms.NoError(err)
+
+		return nil
+	}))
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/pkg/errors"
+)
+
+// Stats is everything the admin stats page shows
+type Stats struct {
+	Since         time.Time           `json:"since"`
+	Requests      []RequestDay        `json:"requests"`
+	Displays      []DisplayCount      `json:"displays"`
+	Conversations []ConversationCount `json:"conversations"`
+	Archive       []ArchiveMonth      `json:"archive"`
+}
+
+// RequestDay is the traffic for one day out of the requests tables
+type RequestDay struct {
+	Day    time.Time `json:"day" db:"day"`
+	Count  int64     `json:"count" db:"count"`
+	ExecMS int64     `json:"exec_ms" db:"exec_ms"`
+	AvgMS  int64     `json:"avg_ms" db:"-"`
+}
+
+// DisplayCount is how many conversations a display showed
+type DisplayCount struct {
+	Display string `json:"display" db:"display"`
+	Shows   int64  `json:"shows" db:"shows"`
+}
+
+// ConversationCount is how many times a conversation was shown, with its
+// first phrase so a person can tell which one it is
+type ConversationCount struct {
+	ConversationID uuid.UUID `json:"conversation_id" db:"conversation_id"`
+	Phrase         string    `json:"phrase" db:"phrase"`
+	Shows          int64     `json:"shows" db:"shows"`
+}
+
+// ArchiveMonth is what got added to the archive in a month, and how big
+// it was at the end of it
+type ArchiveMonth struct {
+	Month              time.Time `json:"month" db:"month"`
+	Conversations      int64     `json:"conversations" db:"conversations"`
+	Quotes             int64     `json:"quotes" db:"quotes"`
+	TotalConversations int64     `json:"total_conversations" db:"-"`
+	TotalQuotes        int64     `json:"total_quotes" db:"-"`
+}
+
+// LoadStats gathers the stats from since on, top limits how many
+// displays and conversations get ranked
+func LoadStats(db *pop.Connection, since time.Time, top int) (*Stats, error) {
+	st := &Stats{
+		Since:         since,
+		Displays:      []DisplayCount{},
+		Conversations: []ConversationCount{},
+		Archive:       []ArchiveMonth{},
+	}
+	var err error
+
+	if st.Requests, err = RequestDays(db, since); err != nil {
+		return nil, err
+	}
+
+	if err = db.RawQuery(`SELECT display, SUM(count) AS shows FROM conversation_shows
+		WHERE day >= ? GROUP BY display ORDER BY shows DESC, display LIMIT ?`,
+		since.Format("2006-01-02"), top).All(&st.Displays); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = db.RawQuery(`SELECT s.conversation_id, SUM(s.count) AS shows,
+		COALESCE((SELECT q.phrase FROM quotes q WHERE q.conversation_id = s.conversation_id ORDER BY q.sequence LIMIT 1), '') AS phrase
+		FROM conversation_shows s WHERE s.day >= ? GROUP BY s.conversation_id ORDER BY shows DESC LIMIT ?`,
+		since.Format("2006-01-02"), top).All(&st.Conversations); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	month := "date_trunc('month', c.created_at)"
+	if IsSQLite(db) {
+		month = "strftime('%Y-%m-01', c.created_at)"
+	}
+
+	months := []struct {
+		Month         dbTime `db:"month"`
+		Conversations int64  `db:"conversations"`
+		Quotes        int64  `db:"quotes"`
+	}{}
+
+	if err = db.RawQuery(`SELECT ` + month + ` AS month,
+		COUNT(DISTINCT c.id) AS conversations, COUNT(q.id) AS quotes
+		FROM conversations c LEFT JOIN quotes q ON q.conversation_id = c.id
+		GROUP BY 1 ORDER BY 1`).All(&months); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, m := range months {
+		st.Archive = append(st.Archive, ArchiveMonth{Month: m.Month.Time, Conversations: m.Conversations, Quotes: m.Quotes})
+	}
+
+	runningTotals(st.Archive)
+
+	return st, nil
+}
+
+// RequestTables lists the requests table and every one that
+// RotateRequests has moved aside
+func RequestTables(db *pop.Connection) ([]string, error) {
+	tables := []struct {
+		Name string `db:"tablename"`
+	}{}
+
+	q := `SELECT tablename FROM pg_tables
+		WHERE schemaname = 'public' AND tablename LIKE 'requests%' ORDER BY tablename`
+	if IsSQLite(db) {
+		q = `SELECT name AS tablename FROM sqlite_master
+			WHERE type = 'table' AND name LIKE 'requests%' ORDER BY name`
+	}
+
+	err := db.RawQuery(q).All(&tables)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	names := []string{}
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+
+	return names, nil
+}
+
+// RequestDays adds up the requests per day from since on, across the
+// current and rotated requests tables
+func RequestDays(db *pop.Connection, since time.Time) ([]RequestDay, error) {
+	tables, err := RequestTables(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	day := "created_at::date"
+	if IsSQLite(db) {
+		day = "date(created_at)"
+	}
+
+	all := []RequestDay{}
+
+	for _, t := range tables {
+		days := []struct {
+			Day    dbTime `db:"day"`
+			Count  int64  `db:"count"`
+			ExecMS int64  `db:"exec_ms"`
+		}{}
+
+		// a table name can't be a parameter, and the rotated ones have
+		// spaces and colons in them
+		err := db.RawQuery(`SELECT `+day+` AS day, SUM(count) AS count, SUM(exec_ms) AS exec_ms
+			FROM `+quoteIdent(t)+` WHERE created_at >= ? GROUP BY 1`, since).All(&days)
+
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, d := range days {
+			all = append(all, RequestDay{Day: d.Day.Time, Count: d.Count, ExecMS: d.ExecMS})
+		}
+	}
+
+	return mergeRequestDays(all), nil
+}
+
+// mergeRequestDays combines days that show up in more than one table,
+// which happens on the day a table gets rotated
+func mergeRequestDays(days []RequestDay) []RequestDay {
+	byDay := map[string]int{}
+	merged := []RequestDay{}
+
+	for _, d := range days {
+		key := d.Day.Format("2006-01-02")
+
+		if i, ok := byDay[key]; ok {
+			merged[i].Count += d.Count
+			merged[i].ExecMS += d.ExecMS
+			continue
+		}
+
+		byDay[key] = len(merged)
+		merged = append(merged, d)
+	}
+
+	for i := range merged {
+		if merged[i].Count > 0 {
+			merged[i].AvgMS = merged[i].ExecMS / merged[i].Count
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Day.Before(merged[j].Day)
+	})
+
+	return merged
+}
+
+// runningTotals fills in how big the archive was at the end of each month
+func runningTotals(months []ArchiveMonth) {
+	var convs, quotes int64
+
+	for i := range months {
+		convs += months[i].Conversations
+		quotes += months[i].Quotes
+		months[i].TotalConversations = convs
+		months[i].TotalQuotes = quotes
+	}
+}
+
+// quoteIdent quotes a name for use as an identifier in SQL
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_mergeRequestDays(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	got := mergeRequestDays([]RequestDay{
+		{Day: day(9), Count: 2, ExecMS: 40},
+		{Day: day(8), Count: 1, ExecMS: 10},
+		{Day: day(9), Count: 2, ExecMS: 20},
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("mergeRequestDays got %d days, wanted 2", len(got))
+	}
+
+	if !got[0].Day.Equal(day(8)) || got[0].AvgMS != 10 {
+		t.Errorf("mergeRequestDays first day got %v", got[0])
+	}
+
+	if got[1].Count != 4 || got[1].ExecMS != 60 || got[1].AvgMS != 15 {
+		t.Errorf("mergeRequestDays didn't add up the rotated day, got %v", got[1])
+	}
+}
+
+func Test_runningTotals(t *testing.T) {
+	months := []ArchiveMonth{{Conversations: 2, Quotes: 5}, {Conversations: 1, Quotes: 1}}
+	runningTotals(months)
+
+	if months[1].TotalConversations != 3 || months[1].TotalQuotes != 6 {
+		t.Errorf("runningTotals got %v", months[1])
+	}
+}
+
+func Test_quoteIdent(t *testing.T) {
+	tests := map[string]string{
+		"requests":                      `"requests"`,
+		"requests2020-10-08 to 2020-11": `"requests2020-10-08 to 2020-11"`,
+		`bad"; DROP TABLE users; --`:    `"bad""; DROP TABLE users; --"`,
+	}
+
+	for name, want := range tests {
+		if got := quoteIdent(name); got != want {
+			t.Errorf("quoteIdent(%q) got %s, wanted %s", name, got, want)
+		}
+	}
+}
+
+func (ms *ModelSuite) Test_LoadStats() {
+	loadFixtureData(ms)
+	ms.LoadFixture("test quotes")
+
+	quote := &Quote{}
+	ms.NoError(ms.DB.First(quote))
+	conv := &Conversation{ID: quote.ConversationID}
+
+	now := time.Now()
+	ms.NoError(RecordShow(ms.DB, "lobby", conv.ID, now))
+	ms.NoError(RecordShow(ms.DB, "lobby", conv.ID, now))
+	ms.NoError(RecordShow(ms.DB, "kitchen", conv.ID, now))
+
+	st, err := LoadStats(ms.DB, now.AddDate(0, 0, -1), 10)
+	ms.NoError(err)
+
+	ms.Len(st.Displays, 2)
+	ms.Equal("lobby", st.Displays[0].Display)
+	ms.Equal(int64(2), st.Displays[0].Shows)
+
+	ms.Len(st.Conversations, 1)
+	ms.Equal(int64(3), st.Conversations[0].Shows)
+	ms.NotEmpty(st.Conversations[0].Phrase)
+
+	ms.NotEmpty(st.Archive)
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time
+const RecoveryCodeCount = 10
+
+// totpPeriod is the standard 30 seconds every authenticator app uses
+const totpPeriod = 30
+
+// one step either side is allowed for phones with a drifting clock
+var totpOpts = totp.ValidateOpts{Period: totpPeriod, Skew: 1, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// RecoveryCode is a one time code for signing in when the phone with the
+// authenticator is lost.  Only a hash is kept.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	UsedAt    nulls.Time `json:"used_at" db:"used_at"`
+}
+
+// String is not required by pop and may be deleted
+func (r RecoveryCode) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// RecoveryCodes is not required by pop and may be deleted
+type RecoveryCodes []RecoveryCode
+
+// String is not required by pop and may be deleted
+func (r RecoveryCodes) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (r *RecoveryCode) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: r.UserID, Name: "UserID"},
+		&validators.StringIsPresent{Field: r.CodeHash, Name: "CodeHash"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (r *RecoveryCode) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (r *RecoveryCode) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// NewTOTPKey makes a secret for the user to load into his authenticator.
+// Nothing is saved until EnableTOTP proves it was loaded.
+func NewTOTPKey(u *User, issuer string) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{Issuer: issuer, AccountName: u.Email, Period: totpPeriod})
+}
+
+// TwoFactorEnabled says if signing in takes a code as well as a password
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt.Valid && len(u.TOTPSecret) > 0
+}
+
+// TwoFactorRequired says if one of the user's roles calls for two-factor
+func (u *User) TwoFactorRequired(tx *pop.Connection, roles []string) (bool, error) {
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	args := make([]interface{}, len(roles))
+	for i, r := range roles {
+		args[i] = r
+	}
+
+	// pop spreads the args over the (?) itself
+	return tx.Where("user_id = ?", u.ID).Where("name IN (?)", args...).Exists(&Permission{})
+}
+
+// EnableTOTP turns on two-factor with the secret, once code shows the
+// authenticator has it
+func (u *User) EnableTOTP(tx *pop.Connection, secret, code string, now time.Time) (bool, error) {
+	step, ok := totpStep(secret, code, now)
+
+	if !ok {
+		return false, nil
+	}
+
+	u.TOTPSecret = secret
+	u.TOTPEnabledAt = nulls.NewTime(now)
+	u.TOTPLastStep = step
+
+	return true, tx.UpdateColumns(u, "totp_secret", "totp_enabled_at", "totp_last_step", "updated_at")
+}
+
+// DisableTOTP turns two-factor off and throws away the recovery codes
+func (u *User) DisableTOTP(tx *pop.Connection) error {
+	u.TOTPSecret = ""
+	u.TOTPEnabledAt = nulls.Time{}
+	u.TOTPLastStep = 0
+
+	if err := tx.RawQuery("DELETE FROM recovery_codes WHERE user_id = ?", u.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return tx.UpdateColumns(u, "totp_secret", "totp_enabled_at", "totp_last_step", "updated_at")
+}
+
+// CheckTOTP checks a code from the authenticator.  Each code only works
+// once, so one read over a shoulder can't be replayed.
+func (u *User) CheckTOTP(tx *pop.Connection, code string, now time.Time) (bool, error) {
+	if !u.TwoFactorEnabled() {
+		return false, nil
+	}
+
+	step, ok := totpStep(u.TOTPSecret, code, now)
+
+	if !ok || step <= u.TOTPLastStep {
+		return false, nil
+	}
+
+	u.TOTPLastStep = step
+
+	return true, tx.UpdateColumns(u, "totp_last_step", "updated_at")
+}
+
+// totpStep finds which time step the code is for, within the skew
+func totpStep(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+
+	if len(code) != totpOpts.Digits.Length() {
+		return 0, false
+	}
+
+	step := now.Unix() / totpPeriod
+
+	for i := -int64(totpOpts.Skew); i <= int64(totpOpts.Skew); i++ {
+		at := time.Unix((step+i)*totpPeriod, 0)
+
+		want, err := totp.GenerateCodeCustom(secret, at, totpOpts)
+
+		if err == nil && want == code {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+// NewRecoveryCodes replaces the user's recovery codes and returns the new
+// ones, this is the only time they can be seen
+func (u *User) NewRecoveryCodes(tx *pop.Connection) ([]string, error) {
+	if err := tx.RawQuery("DELETE FROM recovery_codes WHERE user_id = ?", u.ID).Exec(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	codes := []string{}
+
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		code = code[:4] + "-" + code[4:]
+
+		if err := tx.Create(&RecoveryCode{UserID: u.ID, CodeHash: hashRecoveryCode(code)}); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// UseRecoveryCode signs off one of the user's recovery codes
+func (u *User) UseRecoveryCode(tx *pop.Connection, code string, now time.Time) (bool, error) {
+	rc := &RecoveryCode{}
+
+	if err := tx.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", u.ID, hashRecoveryCode(code)).First(rc); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.WithStack(err)
+	}
+
+	rc.UsedAt = nulls.NewTime(now)
+
+	return true, tx.UpdateColumns(rc, "used_at", "updated_at")
+}
+
+// RecoveryCodesLeft counts the user's unused recovery codes
+func (u *User) RecoveryCodesLeft(tx *pop.Connection) (int, error) {
+	return tx.Where("user_id = ? AND used_at IS NULL", u.ID).Count(&RecoveryCode{})
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so a code typed in
+// from paper still matches
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func Test_totpStep(t *testing.T) {
+	key, err := NewTOTPKey(&User{Email: "mark@example.com"}, "Quote Wall")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1600000000, 0)
+	code, err := totp.GenerateCodeCustom(key.Secret(), now, totpOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := totpStep(key.Secret(), code, now)
+	if !ok || step != now.Unix()/totpPeriod {
+		t.Errorf("totpStep got %d %t, wanted %d", step, ok, now.Unix()/totpPeriod)
+	}
+
+	// a step either side is allowed for a slow clock
+	if _, ok := totpStep(key.Secret(), code, now.Add(totpPeriod*time.Second)); !ok {
+		t.Error("totpStep didn't allow for skew")
+	}
+
+	if _, ok := totpStep(key.Secret(), code, now.Add(3*totpPeriod*time.Second)); ok {
+		t.Error("totpStep took an old code")
+	}
+
+	if _, ok := totpStep(key.Secret(), "12345", now); ok {
+		t.Error("totpStep took a short code")
+	}
+}
+
+func Test_hashRecoveryCode(t *testing.T) {
+	want := hashRecoveryCode("abcd-efgh")
+
+	for _, code := range []string{"ABCD-EFGH", "abcdefgh", " abcd efgh "} {
+		if got := hashRecoveryCode(code); got != want {
+			t.Errorf("hashRecoveryCode(%q) didn't match", code)
+		}
+	}
+
+	if hashRecoveryCode("abcd-efgi") == want {
+		t.Error("hashRecoveryCode matched a different code")
+	}
+}
+
+func (ms *ModelSuite) Test_TwoFactor() {
+	u := &User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	key, err := NewTOTPKey(u, "Quote Wall")
+	ms.NoError(err)
+
+	now := time.Now()
+	code, err := totp.GenerateCodeCustom(key.Secret(), now, totpOpts)
+	ms.NoError(err)
+
+	on, err := u.EnableTOTP(ms.DB, key.Secret(), "000000", now)
+	ms.NoError(err)
+	ms.False(on)
+	ms.False(u.TwoFactorEnabled())
+
+	on, err = u.EnableTOTP(ms.DB, key.Secret(), code, now)
+	ms.NoError(err)
+	ms.True(on)
+	ms.True(u.TwoFactorEnabled())
+
+	// the code used to turn it on can't be used again
+	passed, err := u.CheckTOTP(ms.DB, code, now)
+	ms.NoError(err)
+	ms.False(passed)
+
+	next := now.Add(totpPeriod * time.Second)
+	code, err = totp.GenerateCodeCustom(key.Secret(), next, totpOpts)
+	ms.NoError(err)
+
+	passed, err = u.CheckTOTP(ms.DB, code, next)
+	ms.NoError(err)
+	ms.True(passed)
+
+	codes, err := u.NewRecoveryCodes(ms.DB)
+	ms.NoError(err)
+	ms.Len(codes, RecoveryCodeCount)
+
+	used, err := u.UseRecoveryCode(ms.DB, codes[0], now)
+	ms.NoError(err)
+	ms.True(used)
+
+	used, err = u.UseRecoveryCode(ms.DB, codes[0], now)
+	ms.NoError(err)
+	ms.False(used)
+
+	left, err := u.RecoveryCodesLeft(ms.DB)
+	ms.NoError(err)
+	ms.Equal(RecoveryCodeCount-1, left)
+
+	required, err := u.TwoFactorRequired(ms.DB, []string{PermissionAdmin})
+	ms.NoError(err)
+	ms.False(required)
+
+	ms.NoError(u.Grant(ms.DB, PermissionAdmin))
+
+	required, err = u.TwoFactorRequired(ms.DB, []string{PermissionAdmin})
+	ms.NoError(err)
+	ms.True(required)
+
+	ms.NoError(u.DisableTOTP(ms.DB))
+	ms.False(u.TwoFactorEnabled())
+
+	left, err = u.RecoveryCodesLeft(ms.DB)
+	ms.NoError(err)
+	ms.Equal(0, left)
+}
@@ -1,10 +1,13 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"strings"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -13,6 +16,13 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// how often a digest goes out
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
 //User is a generated model from buffalo-auth, it serves as the base for username/password authentication.
 type User struct {
 	ID           uuid.UUID `json:"id" db:"id"`
@@ -21,6 +31,23 @@ type User struct {
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"password_hash" db:"password_hash"`
 
+	// how often the user gets the digest email, and the token that
+	// lets the unsubscribe link work without logging in
+	Digest       string     `json:"digest" db:"digest"`
+	DigestToken  string     `json:"-" db:"digest_token"`
+	DigestSentAt nulls.Time `json:"digest_sent_at" db:"digest_sent_at"`
+
+	// who the user is at the single sign-on provider, empty for users
+	// that only have a local password
+	OIDCIssuer  string `json:"oidc_issuer" db:"oidc_issuer"`
+	OIDCSubject string `json:"oidc_subject" db:"oidc_subject"`
+
+	// the authenticator secret once two-factor is on, and the last time
+	// step a code was used for so it can't be used again
+	TOTPSecret    string     `json:"-" db:"totp_secret"`
+	TOTPEnabledAt nulls.Time `json:"totp_enabled_at" db:"totp_enabled_at"`
+	TOTPLastStep  int64      `json:"-" db:"totp_last_step"`
+
 	Password             string `json:"-" db:"-"`
 	PasswordConfirmation string `json:"-" db:"-"`
 }
@@ -28,7 +55,7 @@ type User struct {
 // Create wraps up the pattern of encrypting the password and
 // running validations. Useful when writing tests.
 func (u *User) Create(tx *pop.Connection) (*validate.Errors, error) {
-	u.Email = strings.ToLower(u.Email)
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
 	ph, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return validate.NewErrors(), errors.WithStack(err)
@@ -59,6 +86,7 @@ func (u *User) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.StringIsPresent{Field: u.Email, Name: "Email"},
 		&validators.StringIsPresent{Field: u.PasswordHash, Name: "PasswordHash"},
+		&validators.StringInclusion{Field: u.digest(), Name: "Digest", List: []string{DigestOff, DigestDaily, DigestWeekly}},
 		// check to see if the email address is already taken:
 		&validators.FuncValidator{
 			Field:   u.Email,
@@ -95,3 +123,199 @@ func (u *User) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
 func (u *User) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
+
+// digest treats a user that never picked as not subscribed
+func (u *User) digest() string {
+	if len(u.Digest) == 0 {
+		return DigestOff
+	}
+	return u.Digest
+}
+
+// SubscribeDigest signs the user up for the digest every day or week.
+// A token for the unsubscribe link is made the first time.
+func (u *User) SubscribeDigest(tx *pop.Connection, every string) error {
+	if every != DigestDaily && every != DigestWeekly {
+		return errors.Errorf("digest can't be sent %s", every)
+	}
+
+	if len(u.DigestToken) == 0 {
+		b := make([]byte, 20)
+
+		if _, err := rand.Read(b); err != nil {
+			return errors.WithStack(err)
+		}
+
+		u.DigestToken = hex.EncodeToString(b)
+	}
+
+	u.Digest = every
+
+	return tx.UpdateColumns(u, "digest", "digest_token", "updated_at")
+}
+
+// UnsubscribeDigest stops the digest.  The token is kept so an old
+// unsubscribe link still finds the user.
+func (u *User) UnsubscribeDigest(tx *pop.Connection) error {
+	u.Digest = DigestOff
+
+	return tx.UpdateColumns(u, "digest", "updated_at")
+}
+
+// DigestSent records when the last digest went out
+func (u *User) DigestSent(tx *pop.Connection, at time.Time) error {
+	u.DigestSentAt = nulls.NewTime(at)
+
+	return tx.UpdateColumns(u, "digest_sent_at", "updated_at")
+}
+
+// DigestDue checks if the user is owed a digest.  The periods are a
+// little short of a full day or week so a run that starts a few minutes
+// early doesn't skip anyone.
+func (u *User) DigestDue(now time.Time) bool {
+	if !u.DigestSentAt.Valid {
+		return u.digest() != DigestOff
+	}
+
+	since := now.Sub(u.DigestSentAt.Time)
+
+	switch u.digest() {
+	case DigestDaily:
+		return since >= 20*time.Hour
+	case DigestWeekly:
+		return since >= 6*24*time.Hour
+	}
+
+	return false
+}
+
+// DigestSince is where the user's digest picks up, the last one sent or
+// one period back for the very first
+func (u *User) DigestSince(now time.Time) time.Time {
+	if u.DigestSentAt.Valid {
+		return u.DigestSentAt.Time
+	}
+
+	if u.digest() == DigestWeekly {
+		return now.AddDate(0, 0, -7)
+	}
+
+	return now.AddDate(0, 0, -1)
+}
+
+// FindByDigestToken loads the user an unsubscribe link belongs to
+func (u *User) FindByDigestToken(tx *pop.Connection, token string) error {
+	if len(token) == 0 {
+		return errors.New("digest token can't be blank")
+	}
+
+	return tx.Where("digest_token = ?", token).First(u)
+}
+
+// DigestSubscribers loads every user getting a digest
+func (u *Users) DigestSubscribers(tx *pop.Connection) error {
+	return tx.Where("digest IN (?, ?)", DigestDaily, DigestWeekly).Order("email").All(u)
+}
+
+// MinPasswordLength is the shortest password a user can pick
+const MinPasswordLength = 8
+
+// FindByEmail loads the user with the email address, ignoring case
+func (u *User) FindByEmail(tx *pop.Connection, email string) error {
+	return tx.Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(u)
+}
+
+// CheckPassword compares a password to the stored hash
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// ChangePassword checks the new password and its confirmation, then
+// stores the new hash
+func (u *User) ChangePassword(tx *pop.Connection, password, confirmation string) (*validate.Errors, error) {
+	verrs := validate.Validate(
+		&validators.StringLengthInRange{Field: password, Name: "Password", Min: MinPasswordLength, Max: 72, Message: "Password must be 8 to 72 characters"},
+		&validators.StringsMatch{Name: "Password", Field: password, Field2: confirmation, Message: "Password does not match confirmation"},
+	)
+
+	if verrs.HasAny() {
+		return verrs, nil
+	}
+
+	ph, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return verrs, errors.WithStack(err)
+	}
+
+	u.PasswordHash = string(ph)
+
+	return verrs, tx.UpdateColumns(u, "password_hash", "updated_at")
+}
+
+// CheckEmail checks a new address for the user without saving it
+func (u *User) CheckEmail(tx *pop.Connection, email string) (*validate.Errors, error) {
+	check := *u
+	check.Email = strings.ToLower(strings.TrimSpace(email))
+
+	verrs, err := check.Validate(tx)
+
+	if err != nil {
+		return verrs, err
+	}
+
+	verrs.Append(validate.Validate(&validators.EmailIsPresent{Field: check.Email, Name: "Email"}))
+
+	return verrs, nil
+}
+
+// ChangeEmail moves the user to a new address, as long as nobody else
+// is using it
+func (u *User) ChangeEmail(tx *pop.Connection, email string) (*validate.Errors, error) {
+	verrs, err := u.CheckEmail(tx, email)
+
+	if err != nil || verrs.HasAny() {
+		return verrs, err
+	}
+
+	u.Email = strings.ToLower(strings.TrimSpace(email))
+
+	return verrs, tx.UpdateColumns(u, "email", "updated_at")
+}
+
+// Invite makes an account for someone who hasn't picked a password yet.
+// He gets a random one nobody knows until the invite link is used.
+func (u *User) Invite(tx *pop.Connection) (*validate.Errors, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return validate.NewErrors(), errors.WithStack(err)
+	}
+
+	u.Password = hex.EncodeToString(b)
+	u.PasswordConfirmation = u.Password
+
+	return u.Create(tx)
+}
+
+// Destroy removes the user along with his roles
+func (u *User) Destroy(tx *pop.Connection) error {
+	if err := tx.RawQuery("DELETE FROM permissions WHERE user_id = ?", u.ID).Exec(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return tx.Destroy(u)
+}
+
+// FindByOIDC loads the user linked to the subject at the provider
+func (u *User) FindByOIDC(tx *pop.Connection, issuer, subject string) error {
+	return tx.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).First(u)
+}
+
+// LinkOIDC ties the user to a subject at the provider, so later sign ins
+// find him even if his email changes there
+func (u *User) LinkOIDC(tx *pop.Connection, issuer, subject string) error {
+	u.OIDCIssuer, u.OIDCSubject = issuer, subject
+
+	return tx.UpdateColumns(u, "oidc_issuer", "oidc_subject", "updated_at")
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+)
+
+func Test_User_DigestDue(t *testing.T) {
+	now := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		digest string
+		sent   nulls.Time
+		want   bool
+	}{
+		{DigestOff, nulls.Time{}, false},
+		{"", nulls.Time{}, false},
+		{DigestDaily, nulls.Time{}, true},
+		{DigestDaily, nulls.NewTime(now.Add(-24 * time.Hour).Add(5 * time.Minute)), true},
+		{DigestDaily, nulls.NewTime(now.Add(-2 * time.Hour)), false},
+		{DigestWeekly, nulls.NewTime(now.AddDate(0, 0, -7)), true},
+		{DigestWeekly, nulls.NewTime(now.AddDate(0, 0, -3)), false},
+		{DigestOff, nulls.NewTime(now.AddDate(0, 0, -30)), false},
+	}
+
+	for _, tt := range tests {
+		u := &User{Digest: tt.digest, DigestSentAt: tt.sent}
+		if got := u.DigestDue(now); got != tt.want {
+			t.Errorf("DigestDue(%s, %v) got %v, wanted %v", tt.digest, tt.sent, got, tt.want)
+		}
+	}
+}
+
+func Test_User_DigestSince(t *testing.T) {
+	now := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+	last := now.AddDate(0, 0, -2)
+
+	u := &User{Digest: DigestWeekly}
+	if got := u.DigestSince(now); !got.Equal(now.AddDate(0, 0, -7)) {
+		t.Errorf("first weekly digest starts %s", got)
+	}
+
+	u.Digest = DigestDaily
+	if got := u.DigestSince(now); !got.Equal(now.AddDate(0, 0, -1)) {
+		t.Errorf("first daily digest starts %s", got)
+	}
+
+	u.DigestSentAt = nulls.NewTime(last)
+	if got := u.DigestSince(now); !got.Equal(last) {
+		t.Errorf("digest should pick up from %s, got %s", last, got)
+	}
+}
+
+func (ms *ModelSuite) Test_User_SubscribeDigest() {
+	u := &User{Email: "reader@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	ms.Error(u.SubscribeDigest(ms.DB, "hourly"))
+	ms.NoError(u.SubscribeDigest(ms.DB, DigestWeekly))
+	ms.NotEmpty(u.DigestToken)
+
+	found := &User{}
+	ms.NoError(found.FindByDigestToken(ms.DB, u.DigestToken))
+	ms.Equal(DigestWeekly, found.Digest)
+
+	subs := Users{}
+	ms.NoError(subs.DigestSubscribers(ms.DB))
+	ms.Len(subs, 1)
+
+	token := u.DigestToken
+	ms.NoError(u.UnsubscribeDigest(ms.DB))
+	ms.NoError(found.FindByDigestToken(ms.DB, token))
+	ms.Equal(DigestOff, found.Digest)
+
+	subs = Users{}
+	ms.NoError(subs.DigestSubscribers(ms.DB))
+	ms.Len(subs, 0)
+
+	ms.Error(found.FindByDigestToken(ms.DB, ""))
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// how often last_seen_at gets written, so every page view isn't an update
+const sessionTouchEvery = time.Minute
+
+// UserSession is one signed in browser.  The cookie only holds the ID,
+// so deleting the row signs that browser out.
+type UserSession struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	RemoteAddr string    `json:"remote_addr" db:"remote_addr"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// String is not required by pop and may be deleted
+func (s UserSession) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// UserSessions is not required by pop and may be deleted
+type UserSessions []UserSession
+
+// String is not required by pop and may be deleted
+func (s UserSessions) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (s *UserSession) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: s.UserID, Name: "UserID"},
+		&validators.TimeIsPresent{Field: s.ExpiresAt, Name: "ExpiresAt"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (s *UserSession) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (s *UserSession) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// NewUserSession records a sign in that lasts for lifetime
+func NewUserSession(tx *pop.Connection, u *User, remote, agent string, lifetime time.Duration) (*UserSession, error) {
+	now := time.Now()
+
+	s := &UserSession{
+		UserID:     u.ID,
+		RemoteAddr: remote,
+		UserAgent:  agent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(lifetime),
+	}
+
+	verrs, err := tx.ValidateAndCreate(s)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return nil, errors.New(verrs.String())
+	}
+
+	return s, nil
+}
+
+// FindActive loads the session if it belongs to the user and hasn't
+// expired or been revoked
+func (s *UserSession) FindActive(tx *pop.Connection, id, userID string, now time.Time) error {
+	return tx.Where("id = ? AND user_id = ? AND expires_at > ?", id, userID, now).First(s)
+}
+
+// Touch notes the session was just used, at most once a minute
+func (s *UserSession) Touch(tx *pop.Connection, now time.Time) error {
+	if now.Sub(s.LastSeenAt) < sessionTouchEvery {
+		return nil
+	}
+
+	s.LastSeenAt = now
+
+	return tx.UpdateColumns(s, "last_seen_at", "updated_at")
+}
+
+// ForUser loads the user's live sessions, most recently used first
+func (s *UserSessions) ForUser(tx *pop.Connection, userID uuid.UUID, now time.Time) error {
+	return tx.Where("user_id = ? AND expires_at > ?", userID, now).Order("last_seen_at DESC").All(s)
+}
+
+// RevokeSession signs out one of the user's sessions
+func (u *User) RevokeSession(tx *pop.Connection, id string) error {
+	return tx.RawQuery("DELETE FROM user_sessions WHERE id = ? AND user_id = ?", id, u.ID).Exec()
+}
+
+// RevokeSessions signs out all of the user's sessions except keep,
+// which can be the zero UUID to sign out everywhere
+func (u *User) RevokeSessions(tx *pop.Connection, keep uuid.UUID) error {
+	return tx.RawQuery("DELETE FROM user_sessions WHERE user_id = ? AND id <> ?", u.ID, keep).Exec()
+}
@@ -31,6 +31,24 @@ func (ms *modelSuite) Test_User_Create() {
 	ms.Equal(1, count)
 }
 
+func (ms *modelSuite) Test_User_Create_Email() {
+	u := &models.User{
+		Email:                " Mark@Example.com ",
+		Password:             "password",
+		PasswordConfirmation: "password",
+	}
+
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+	ms.Equal("mark@example.com", u.Email)
+
+	// stored the same way it gets looked up
+	found := &models.User{}
+	ms.NoError(found.FindByEmail(ms.DB, " Mark@Example.com "))
+	ms.Equal(u.ID, found.ID)
+}
+
 func (ms *modelSuite) Test_User_Create_ValidationErrors() {
 	count, err := ms.DB.Count("users")
 	ms.NoError(err)
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// what a token can be used for
+const (
+	TokenPasswordReset = "password_reset"
+	TokenEmailChange   = "email_change"
+	TokenInvite        = "invite"
+)
+
+// ErrTokenInvalid covers unknown, used and expired tokens alike, so a
+// guesser can't tell which one he hit
+var ErrTokenInvalid = errors.New("that link is invalid or has expired")
+
+// UserToken is a one time link mailed to a user.  Only a hash of the
+// token is kept, so a copy of the database can't be used to take over
+// accounts.
+type UserToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Purpose   string     `json:"purpose" db:"purpose"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	Email     string     `json:"email" db:"email"` // the new address for an email change
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    nulls.Time `json:"used_at" db:"used_at"`
+}
+
+// String is not required by pop and may be deleted
+func (t UserToken) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// UserTokens is not required by pop and may be deleted
+type UserTokens []UserToken
+
+// String is not required by pop and may be deleted
+func (t UserTokens) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (t *UserToken) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: t.UserID, Name: "UserID"},
+		&validators.StringInclusion{Field: t.Purpose, Name: "Purpose", List: []string{TokenPasswordReset, TokenEmailChange, TokenInvite}},
+		&validators.StringIsPresent{Field: t.TokenHash, Name: "TokenHash"},
+		&validators.TimeIsPresent{Field: t.ExpiresAt, Name: "ExpiresAt"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (t *UserToken) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (t *UserToken) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// NewUserToken makes a token for the user and returns the raw value to
+// put in the link.  Any earlier tokens for the same purpose stop working,
+// so only the newest email does anything.
+func NewUserToken(tx *pop.Connection, u *User, purpose string, ttl time.Duration, email string) (string, *UserToken, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	raw := hex.EncodeToString(b)
+
+	err := tx.RawQuery("DELETE FROM user_tokens WHERE user_id = ? AND purpose = ? AND used_at IS NULL", u.ID, purpose).Exec()
+
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	t := &UserToken{
+		UserID:    u.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(raw),
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	verrs, err := tx.ValidateAndCreate(t)
+
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	if verrs.HasAny() {
+		return "", nil, errors.New(verrs.String())
+	}
+
+	return raw, t, nil
+}
+
+// Lookup loads the token for a link, as long as it is still good
+func (t *UserToken) Lookup(tx *pop.Connection, purpose, raw string, now time.Time) error {
+	if len(raw) == 0 {
+		return ErrTokenInvalid
+	}
+
+	tokens := UserTokens{}
+
+	if err := tx.Where("token_hash = ? AND purpose = ?", hashToken(raw), purpose).All(&tokens); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if len(tokens) == 0 || tokens[0].UsedAt.Valid || now.After(tokens[0].ExpiresAt) {
+		return ErrTokenInvalid
+	}
+
+	*t = tokens[0]
+
+	return nil
+}
+
+// Redeem looks up the token and uses it up
+func (t *UserToken) Redeem(tx *pop.Connection, purpose, raw string, now time.Time) error {
+	if err := t.Lookup(tx, purpose, raw, now); err != nil {
+		return err
+	}
+
+	t.UsedAt = nulls.NewTime(now)
+
+	return tx.UpdateColumns(t, "used_at", "updated_at")
+}
+
+// hashToken is what gets stored for a raw token
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+func (ms *ModelSuite) Test_UserToken_Redeem() {
+	u := &User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	first, _, err := NewUserToken(ms.DB, u, TokenEmailChange, time.Hour, "new@example.com")
+	ms.NoError(err)
+
+	raw, tok, err := NewUserToken(ms.DB, u, TokenEmailChange, time.Hour, "newer@example.com")
+	ms.NoError(err)
+	ms.NotEqual(raw, tok.TokenHash)
+
+	now := time.Now()
+	t := &UserToken{}
+
+	// only the newest link works
+	ms.Equal(ErrTokenInvalid, t.Lookup(ms.DB, TokenEmailChange, first, now))
+
+	// and only for what it was made for
+	ms.Equal(ErrTokenInvalid, t.Lookup(ms.DB, TokenPasswordReset, raw, now))
+
+	ms.Equal(ErrTokenInvalid, t.Lookup(ms.DB, TokenEmailChange, raw, now.Add(2*time.Hour)))
+
+	ms.NoError(t.Redeem(ms.DB, TokenEmailChange, raw, now))
+	ms.Equal("newer@example.com", t.Email)
+
+	ms.Equal(ErrTokenInvalid, t.Redeem(ms.DB, TokenEmailChange, raw, now))
+}
+
+func (ms *ModelSuite) Test_User_Permissions() {
+	u := &User{Email: "mark@example.com", Password: "password", PasswordConfirmation: "password"}
+	verrs, err := u.Create(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	admin, err := u.HasPermission(ms.DB, PermissionAdmin)
+	ms.NoError(err)
+	ms.False(admin)
+
+	ms.NoError(u.Grant(ms.DB, PermissionAdmin))
+	ms.NoError(u.Grant(ms.DB, PermissionAdmin))
+
+	names, err := u.PermissionNames(ms.DB)
+	ms.NoError(err)
+	ms.Equal([]string{PermissionAdmin}, names)
+
+	ms.NoError(u.Revoke(ms.DB, PermissionAdmin))
+
+	admin, err = u.HasPermission(ms.DB, PermissionAdmin)
+	ms.NoError(err)
+	ms.False(admin)
+}
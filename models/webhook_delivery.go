@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// where a delivery is at
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// WebhookDelivery is one event sent, or being sent, to a subscription.
+// The payload is saved so every retry sends exactly the same body.
+type WebhookDelivery struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"-" db:"updated_at"`
+	Event       string     `json:"event" db:"event"`
+	Payload     string     `json:"payload" db:"payload"`
+	Status      string     `json:"status" db:"status"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	StatusCode  int        `json:"status_code" db:"status_code"`
+	LastError   string     `json:"last_error" db:"last_error"`
+	DeliveredAt nulls.Time `json:"delivered_at" db:"delivered_at"`
+
+	// Relationships
+	Subscription WebhookSubscription `json:"-" belongs_to:"webhook_subscription" fk_id:"SubscriptionID" db:"-"`
+
+	// Foreign keys
+	SubscriptionID uuid.UUID `json:"subscription_id" db:"subscription_id"`
+}
+
+// String is not required by pop and may be deleted
+func (w WebhookDelivery) String() string {
+	jw, _ := json.Marshal(w)
+	return string(jw)
+}
+
+// WebhookDeliveries is not required by pop and may be deleted
+type WebhookDeliveries []WebhookDelivery
+
+// String is not required by pop and may be deleted
+func (w WebhookDeliveries) String() string {
+	jw, _ := json.Marshal(w)
+	return string(jw)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (w *WebhookDelivery) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: w.Event, Name: "Event"},
+		&validators.StringIsPresent{Field: w.Payload, Name: "Payload"},
+		&validators.UUIDIsPresent{Field: w.SubscriptionID, Name: "SubscriptionID"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (w *WebhookDelivery) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (w *WebhookDelivery) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Recent loads the newest deliveries for a subscription
+func (w *WebhookDeliveries) Recent(db *pop.Connection, subscription uuid.UUID, limit int) error {
+	return db.Where("subscription_id = ?", subscription).Order("created_at DESC").Limit(limit).All(w)
+}
+
+// Pending loads the deliveries still waiting to go out to a subscription
+// that is turned on, oldest first
+func (w *WebhookDeliveries) Pending(db *pop.Connection) error {
+	return db.Where("status = ? AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE active = TRUE)", DeliveryPending).Order("created_at").All(w)
+}
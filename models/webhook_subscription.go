@@ -0,0 +1,210 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/uuid"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// the events a webhook can subscribe to
+const (
+	EventConversationCreated   = "conversation.created"
+	EventConversationUpdated   = "conversation.updated"
+	EventConversationPublished = "conversation.published"
+	EventConversationDeleted   = "conversation.deleted"
+	EventAuthorCreated         = "author.created"
+	EventAuthorUpdated         = "author.updated"
+)
+
+// WebhookEvents lists every event in the order they show up on the admin pages
+var WebhookEvents = []string{
+	EventConversationCreated,
+	EventConversationUpdated,
+	EventConversationPublished,
+	EventConversationDeleted,
+	EventAuthorCreated,
+	EventAuthorUpdated,
+}
+
+// WebhookSubscription is another tool that wants to hear about changes
+// to the archive.  Every delivery is signed with his secret.
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+	URL       string    `json:"url" db:"url" form:"URL"`
+	Secret    string    `json:"-" db:"secret" form:"Secret"`
+	Events    string    `json:"events" db:"events" form:"Events"` // comma separated
+	Active    bool      `json:"active" db:"active" form:"Active"`
+
+	// Relationships
+	Deliveries WebhookDeliveries `json:"-" has_many:"webhook_deliveries" order_by:"created_at desc" db:"-"`
+}
+
+// NewWebhookSubscription returns an active subscription with a freshly
+// generated secret
+func NewWebhookSubscription() (*WebhookSubscription, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return &WebhookSubscription{Secret: hex.EncodeToString(b), Active: true}, nil
+}
+
+// String is not required by pop and may be deleted
+func (w WebhookSubscription) String() string {
+	jw, _ := json.Marshal(w)
+	return string(jw)
+}
+
+// WebhookSubscriptions is not required by pop and may be deleted
+type WebhookSubscriptions []WebhookSubscription
+
+// String is not required by pop and may be deleted
+func (w WebhookSubscriptions) String() string {
+	jw, _ := json.Marshal(w)
+	return string(jw)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (w *WebhookSubscription) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.StringIsPresent{Field: w.URL, Name: "URL"},
+		&validators.StringLengthInRange{Field: w.Secret, Name: "Secret", Min: 16, Max: 255, Message: "secret must be at least 16 characters"},
+		&validators.FuncValidator{
+			Field:   w.URL,
+			Name:    "URL",
+			Message: "%s must be an http or https address",
+			Fn: func() bool {
+				u, err := url.Parse(w.URL)
+				return err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0
+			},
+		},
+		&validators.FuncValidator{
+			Field:   w.Events,
+			Name:    "Events",
+			Message: "%s must be a list of known events",
+			Fn: func() bool {
+				events := w.EventList()
+				if len(events) == 0 {
+					return false
+				}
+				for _, e := range events {
+					if !knownEvent(e) {
+						return false
+					}
+				}
+				return true
+			},
+		},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+// This method is not required and may be deleted.
+func (w *WebhookSubscription) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+// This method is not required and may be deleted.
+func (w *WebhookSubscription) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// EventList splits the subscribed events into a list
+func (w *WebhookSubscription) EventList() []string {
+	var events []string
+
+	for _, e := range strings.Split(w.Events, ",") {
+		if e = strings.TrimSpace(e); len(e) > 0 {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+// Wants checks if the subscription is listening for event
+func (w *WebhookSubscription) Wants(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ForEvent loads the active subscriptions listening for event
+func (w *WebhookSubscriptions) ForEvent(db *pop.Connection, event string) error {
+	all := WebhookSubscriptions{}
+
+	if err := db.Where("active = TRUE").All(&all); err != nil {
+		return err
+	}
+
+	for _, s := range all {
+		if s.Wants(event) {
+			*w = append(*w, s)
+		}
+	}
+
+	return nil
+}
+
+func knownEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the body of every delivery
+type WebhookPayload struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// NewDelivery queues up event for the subscription and saves it to the
+// delivery log
+func (w *WebhookSubscription) NewDelivery(db *pop.Connection, event string, data interface{}) (*WebhookDelivery, error) {
+	body, err := json.Marshal(WebhookPayload{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &WebhookDelivery{
+		SubscriptionID: w.ID,
+		Event:          event,
+		Payload:        string(body),
+		Status:         DeliveryPending,
+	}
+
+	verrs, err := db.ValidateAndCreate(d)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if verrs.HasAny() {
+		return nil, fmt.Errorf("webhook delivery: %s", verrs.String())
+	}
+
+	return d, nil
+}
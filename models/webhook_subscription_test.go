@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+)
+
+func Test_WebhookSubscription_Wants(t *testing.T) {
+	w := &WebhookSubscription{Events: " conversation.published, author.created ,"}
+
+	if got := w.EventList(); len(got) != 2 {
+		t.Errorf("EventList got %v", got)
+	}
+
+	if !w.Wants(EventConversationPublished) || !w.Wants(EventAuthorCreated) {
+		t.Errorf("subscription should want its own events")
+	}
+
+	if w.Wants(EventConversationCreated) {
+		t.Errorf("subscription shouldn't want %s", EventConversationCreated)
+	}
+}
+
+func Test_NewWebhookSubscription(t *testing.T) {
+	a, err := NewWebhookSubscription()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := NewWebhookSubscription()
+
+	if len(a.Secret) != 64 || a.Secret == b.Secret {
+		t.Errorf("secrets %s and %s aren't random", a.Secret, b.Secret)
+	}
+
+	if !a.Active {
+		t.Errorf("new subscriptions should start active")
+	}
+}
+
+func (ms *ModelSuite) Test_WebhookSubscription_Validate() {
+	w, err := NewWebhookSubscription()
+	ms.NoError(err)
+	w.URL = "https://chat.example.com/hooks"
+	w.Events = EventConversationPublished
+
+	verrs, err := w.Validate(ms.DB)
+	ms.NoError(err)
+	ms.False(verrs.HasAny())
+
+	w.URL = "chat.example.com"
+	w.Events = "conversation.eaten"
+
+	verrs, err = w.Validate(ms.DB)
+	ms.NoError(err)
+	ms.True(verrs.HasAny())
+	ms.NotEmpty(verrs.Get("url"))
+	ms.NotEmpty(verrs.Get("events"))
+}
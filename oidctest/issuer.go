@@ -0,0 +1,296 @@
+// Package oidctest is a small OpenID Connect provider for trying out
+// single sign-on without a real one.  It signs everybody in as User
+// straight away, but otherwise checks the authorization code flow the
+// way a real provider would, PKCE included.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// User is who the issuer says signed in
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// Issuer is the mock provider.  It is an http.Handler, URL has to be
+// set to wherever it is being served.
+type Issuer struct {
+	URL      string
+	ClientID string
+	User     User
+
+	// how long the ID tokens it hands out last
+	TokenLifetime time.Duration
+
+	key   *rsa.PrivateKey
+	keyID string
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// grant is an authorization code waiting to be swapped for tokens
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        User
+	expires     time.Time
+}
+
+// NewIssuer makes an issuer with a fresh signing key
+func NewIssuer(clientID string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	return &Issuer{
+		ClientID:      clientID,
+		User:          User{Subject: "mock-user", Email: "mock@example.com", EmailVerified: true},
+		TokenLifetime: 5 * time.Minute,
+		key:           key,
+		keyID:         hex.EncodeToString(kid),
+		grants:        map[string]grant{},
+	}, nil
+}
+
+// NewServer starts an issuer on a local test server.  Close the server
+// when done.
+func NewServer(clientID string) (*Issuer, *httptest.Server, error) {
+	iss, err := NewIssuer(clientID)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv := httptest.NewServer(iss)
+	iss.URL = srv.URL
+
+	return iss, srv, nil
+}
+
+// ServeHTTP answers the discovery, key, authorize and token endpoints
+func (iss *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		iss.discovery(w)
+	case "/keys":
+		iss.keys(w)
+	case "/authorize":
+		iss.authorize(w, r)
+	case "/token":
+		iss.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (iss *Issuer) discovery(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                iss.URL,
+		"authorization_endpoint":                iss.URL + "/authorize",
+		"token_endpoint":                        iss.URL + "/token",
+		"jwks_uri":                              iss.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (iss *Issuer) keys(w http.ResponseWriter) {
+	pub := iss.key.PublicKey
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": iss.keyID,
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize signs the current User in without asking and sends the
+// browser back with a code
+func (iss *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+
+	if err != nil || len(redirect.Host) == 0 {
+		http.Error(w, "redirect_uri is required", http.StatusBadRequest)
+		return
+	}
+
+	back := func(params url.Values) {
+		params.Set("state", q.Get("state"))
+		redirect.RawQuery = params.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	}
+
+	switch {
+	case q.Get("response_type") != "code":
+		back(url.Values{"error": {"unsupported_response_type"}})
+		return
+	case q.Get("client_id") != iss.ClientID:
+		back(url.Values{"error": {"unauthorized_client"}})
+		return
+	case q.Get("code_challenge_method") != "S256" || len(q.Get("code_challenge")) == 0:
+		back(url.Values{"error": {"invalid_request"}, "error_description": {"PKCE with S256 is required"}})
+		return
+	}
+
+	code := make([]byte, 16)
+	rand.Read(code)
+
+	iss.mu.Lock()
+	iss.grants[hex.EncodeToString(code)] = grant{
+		redirectURI: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		user:        iss.User,
+		expires:     time.Now().Add(time.Minute),
+	}
+	iss.mu.Unlock()
+
+	back(url.Values{"code": {hex.EncodeToString(code)}})
+}
+
+// token swaps a code for an ID token, once, after checking the PKCE
+// verifier matches the challenge
+func (iss *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	iss.mu.Lock()
+	g, ok := iss.grants[r.PostForm.Get("code")]
+	delete(iss.grants, r.PostForm.Get("code"))
+	iss.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code":
+		tokenError(w, "unsupported_grant_type")
+		return
+	case !ok || time.Now().After(g.expires) || g.redirectURI != r.PostForm.Get("redirect_uri"):
+		tokenError(w, "invalid_grant")
+		return
+	case r.PostForm.Get("client_id") != iss.ClientID:
+		tokenError(w, "invalid_client")
+		return
+	case Challenge(r.PostForm.Get("code_verifier")) != g.challenge:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	idToken, err := iss.IDToken(g.user, g.nonce)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": hex.EncodeToString([]byte(g.user.Subject)),
+		"token_type":   "Bearer",
+		"expires_in":   int(iss.TokenLifetime.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// IDToken makes a signed ID token for the user
+func (iss *Issuer) IDToken(u User, nonce string) (string, error) {
+	now := time.Now()
+
+	claims := map[string]interface{}{
+		"iss":            iss.URL,
+		"sub":            u.Subject,
+		"aud":            iss.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(iss.TokenLifetime).Unix(),
+		"email":          u.Email,
+		"email_verified": u.EmailVerified,
+		"groups":         u.Groups,
+	}
+
+	if len(nonce) > 0 {
+		claims["nonce"] = nonce
+	}
+
+	return iss.Sign(claims)
+}
+
+// Sign makes an RS256 JWT out of any claims, for testing how bad tokens
+// get handled
+func (iss *Issuer) Sign(claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": iss.keyID})
+	payload, err := json.Marshal(claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	signed := b64(header) + "." + b64(payload)
+	sum := sha256.Sum256([]byte(signed))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, iss.key, crypto.SHA256, sum[:])
+
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + b64(sig), nil
+}
+
+// Challenge is the S256 PKCE challenge for a verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return b64(sum[:])
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}